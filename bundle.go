@@ -0,0 +1,216 @@
+package gitconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// bundleVersion is the format version written by ExportBundle.
+const bundleVersion = 1
+
+// redacted replaces sensitive values in bundles.
+const redacted = "REDACTED"
+
+// defaultSensitiveKeyNames are redacted in bundles unless
+// Configs.SensitiveKeyNames is set.
+var defaultSensitiveKeyNames = []string{"password", "passwd", "passphrase", "secret", "token", "apikey", "extraheader"}
+
+// DefaultSensitiveKeyNames returns the key names whose values are redacted
+// in bundles if Configs.SensitiveKeyNames is not set. A key is sensitive if
+// its name (the part after the last dot) contains one of these, ignoring
+// case. Passwords in URLs are always redacted.
+//
+// Example:
+//
+//	cfg.SensitiveKeyNames = append(gitconfig.DefaultSensitiveKeyNames(), "cookie")
+func DefaultSensitiveKeyNames() []string {
+	return slices.Clone(defaultSensitiveKeyNames)
+}
+
+// Bundle is a snapshot of all loaded scopes. It is meant to be attached to
+// bug reports so that maintainers can reproduce how a configuration is
+// resolved. Sensitive values are redacted.
+//
+// Fields:
+// - Version: Format version of the bundle
+// - Name, Workdir, EnvPrefix: The settings of the exported Configs
+// - Scopes: One entry for every non-empty scope, in priority order
+type Bundle struct {
+	Version   int           `json:"version"`
+	Name      string        `json:"name"`
+	Workdir   string        `json:"workdir,omitempty"`
+	EnvPrefix string        `json:"env_prefix,omitempty"`
+	Scopes    []BundleScope `json:"scopes"`
+}
+
+// BundleScope is a single scope in a Bundle.
+//
+// Fields:
+// - Scope: Name of the scope
+// - Path: The file the scope was loaded from, if any
+// - Raw: The content of that file (without includes)
+// - Vars: All values of the scope, including those from included files
+// - ReadOnly: If changes to this scope can not be persisted
+type BundleScope struct {
+	Scope    string              `json:"scope"`
+	Path     string              `json:"path,omitempty"`
+	Raw      string              `json:"raw,omitempty"`
+	Vars     map[string][]string `json:"vars"`
+	ReadOnly bool                `json:"readonly,omitempty"`
+}
+
+// ExportBundle returns a JSON snapshot of all loaded scopes with sensitive
+// values redacted (see Configs.SensitiveKeyNames). Use LoadBundle to reconstruct
+// the configuration.
+//
+// Example:
+//
+//	buf, err := cfg.ExportBundle()
+//	_ = os.WriteFile("gitconfig-bundle.json", buf, 0o600)
+func (cs *Configs) ExportBundle() ([]byte, error) {
+	b := Bundle{
+		Version:   bundleVersion,
+		Name:      cs.Name,
+		Workdir:   cs.workdir,
+		EnvPrefix: cs.EnvPrefix,
+		Scopes:    make([]BundleScope, 0, 6),
+	}
+
+	names := cs.SensitiveKeyNames
+	if names == nil {
+		names = defaultSensitiveKeyNames
+	}
+
+	for _, sc := range cs.namedScopes() {
+		if sc.cfg == nil || (len(sc.cfg.vars) == 0 && sc.cfg.raw.Len() == 0) {
+			continue
+		}
+
+		bs := BundleScope{
+			Scope:    sc.name,
+			Path:     sc.cfg.path,
+			Raw:      redactRaw(sc.cfg.raw.String(), sc.cfg.unescapeValues(), names),
+			Vars:     make(map[string][]string, len(sc.cfg.vars)),
+			ReadOnly: sc.cfg.readonly || sc.cfg.noWrites,
+		}
+		for k, vs := range sc.cfg.vars {
+			out := make([]string, 0, len(vs))
+			for _, v := range vs {
+				out = append(out, redactValue(k, v, names))
+			}
+			bs.Vars[k] = out
+		}
+		b.Scopes = append(b.Scopes, bs)
+	}
+
+	return json.MarshalIndent(b, "", "  ")
+}
+
+// LoadBundle reconstructs a Configs from a bundle created by ExportBundle.
+// All scopes are read-only and nothing is ever written to disk.
+func LoadBundle(data []byte) (*Configs, error) {
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("%w: invalid bundle: %w", ErrInvalidValue, err)
+	}
+	if b.Version != bundleVersion {
+		return nil, fmt.Errorf("%w: unsupported bundle version %d", ErrInvalidValue, b.Version)
+	}
+
+	cs := New()
+	cs.Name = b.Name
+	cs.EnvPrefix = b.EnvPrefix
+	cs.workdir = b.Workdir
+	cs.NoWrites = true
+
+	for _, bs := range b.Scopes {
+		c := &Config{
+			path:     bs.Path,
+			readonly: true,
+			noWrites: true,
+			vars:     make(map[string][]string, len(bs.Vars)),
+		}
+		c.raw.WriteString(bs.Raw)
+		for k, vs := range bs.Vars {
+			c.vars[k] = slices.Clone(vs)
+		}
+
+		switch bs.Scope {
+		case ScopeOverlay:
+			cs.overlay = c
+		case ScopeEnv:
+			cs.env = c
+		case ScopeWorktree:
+			cs.worktree = c
+		case ScopeLocal:
+			cs.local = c
+		case ScopeGlobal:
+			cs.global = c
+		case ScopeSystem:
+			cs.system = c
+		case ScopePreset:
+			cs.Preset = c
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownScope, bs.Scope)
+		}
+	}
+
+	return cs, nil
+}
+
+// isSensitiveKey returns true if the name of the key contains one of names,
+// i.e. its values must not be exported.
+func isSensitiveKey(key string, names []string) bool {
+	_, _, name := splitKey(key)
+	name = strings.ToLower(name)
+
+	return slices.ContainsFunc(names, func(s string) bool {
+		return strings.Contains(name, strings.ToLower(s))
+	})
+}
+
+// redactValue redacts the value if the key is sensitive or the value is a URL
+// with a password.
+func redactValue(key, value string, names []string) string {
+	if isSensitiveKey(key, names) && value != "" {
+		return redacted
+	}
+
+	if !strings.Contains(value, "://") {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	if _, hasPassword := u.User.Password(); !hasPassword {
+		return value
+	}
+	u.User = url.UserPassword(u.User.Username(), redacted)
+
+	return u.String()
+}
+
+// redactRaw redacts all sensitive values in the raw config text.
+func redactRaw(raw string, unescape bool, names []string) string {
+	if raw == "" {
+		return ""
+	}
+
+	d := parseDocument(strings.NewReader(raw), unescape, KeyRules{})
+	for i, l := range d.lines {
+		if l.kind != lineKeyValue {
+			continue
+		}
+		if rv := redactValue(l.key, l.value, names); rv != l.value {
+			l.value = rv
+			l.bare = false
+			d.lines[i].text = l.format(unescape)
+		}
+	}
+
+	return d.String()
+}
@@ -0,0 +1,88 @@
+package gitconfig
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// typeDuration identifies durations in the coercion cache. Durations are
+// not a git type, so there is no exported ValueType for them.
+const typeDuration ValueType = "duration"
+
+// coercionKey identifies a coerced value in the cache of a Config.
+type coercionKey struct {
+	key string
+	typ ValueType
+}
+
+// coercion is a cached result of parsing a raw value into a typed one.
+// The raw value and the compatibility options are kept to detect stale entries.
+type coercion struct {
+	raw    string
+	compat CompatOptions
+	value  any
+	err    error
+}
+
+// coerce parses raw with parse, re-using a previous result for the same key
+// and type if the raw value didn't change since. This keeps repeated typed
+// lookups in hot paths as cheap as a plain Get. The cache is dropped
+// whenever the config is modified.
+func coerce[T any](c *Config, key string, typ ValueType, raw string, parse func(string) (T, error)) (T, error) {
+	ck := coercionKey{key: key, typ: typ}
+
+	c.coercionMu.RLock()
+	ce, found := c.coercions[ck]
+	c.coercionMu.RUnlock()
+
+	if found && ce.raw == raw && ce.compat == c.compatOptions() {
+		v, _ := ce.value.(T)
+
+		return v, ce.err
+	}
+
+	v, err := parse(raw)
+
+	c.coercionMu.Lock()
+	if c.coercions == nil {
+		c.coercions = make(map[coercionKey]coercion, 8)
+	}
+	c.coercions[ck] = coercion{raw: raw, compat: c.compatOptions(), value: v, err: err}
+	c.coercionMu.Unlock()
+
+	return v, err
+}
+
+// resetCoercions drops all cached typed values. It must be called whenever
+// the values of the config change.
+func (c *Config) resetCoercions() {
+	c.coercionMu.Lock()
+	c.coercions = nil
+	c.coercionMu.Unlock()
+}
+
+// memo caches values computed from strings, like compiled patterns, for the
+// lifetime of the process. It is safe for concurrent use and is cleared
+// once it holds more than max entries, so configs with many different
+// patterns can not grow it without bounds.
+type memo[V any] struct {
+	max  int64
+	m    sync.Map // string -> V
+	size atomic.Int64
+}
+
+// get returns the value for key, computing it with fn if it is not cached.
+func (m *memo[V]) get(key string, fn func(string) V) V {
+	if v, found := m.m.Load(key); found {
+		return v.(V) //nolint:forcetypeassert
+	}
+
+	v := fn(key)
+	if m.size.Add(1) > m.max {
+		m.m.Clear()
+		m.size.Store(1)
+	}
+	m.m.Store(key, v)
+
+	return v
+}
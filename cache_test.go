@@ -0,0 +1,108 @@
+package gitconfig
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoercionCache(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader("[core]\n\tbigfilethreshold = 1k\n\tbare = yes\n"))
+	c.noWrites = true
+
+	calls := 0
+	parse := func(v string) (int64, error) {
+		calls++
+
+		return parseInt(v)
+	}
+
+	for range 3 {
+		n, err := coerce(c, "core.bigFileThreshold", TypeInt, "1k", parse)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1024), n)
+	}
+	assert.Equal(t, 1, calls)
+
+	// a different raw value is never served from the cache
+	n, err := coerce(c, "core.bigfilethreshold", TypeInt, "2k", parse)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2048), n)
+	assert.Equal(t, 2, calls)
+
+	// Set and Unset drop the cache
+	require.NoError(t, c.Set("core.bigfilethreshold", "3k"))
+	v, found, err := c.GetInt("core.bigfilethreshold")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(3072), v)
+
+	b, ok := c.GetBool("core.bare")
+	assert.True(t, ok)
+	assert.True(t, b)
+	require.NoError(t, c.Unset("core.bare"))
+	_, ok = c.GetBool("core.bare")
+	assert.False(t, ok)
+
+	_, found = c.coercions[coercionKey{key: "core.bare", typ: TypeBool}]
+	assert.False(t, found)
+}
+
+func TestCoercionCacheConfigs(t *testing.T) {
+	t.Parallel()
+
+	cs := New()
+	cs.env = ParseConfig(strings.NewReader("[core]\n\ttimeout = 30\n\tbroken = x\n"))
+	cs.env.noWrites = true
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 100 {
+				d, found, err := cs.GetDuration("core.timeout")
+				assert.NoError(t, err)
+				assert.True(t, found)
+				assert.Equal(t, "30s", d.String())
+
+				_, _, err = cs.GetInt("core.broken")
+				assert.ErrorIs(t, err, ErrInvalidValue)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.NoError(t, cs.SetEnv("core.timeout", "1m"))
+	d, _, err := cs.GetDuration("core.timeout")
+	require.NoError(t, err)
+	assert.Equal(t, "1m0s", d.String())
+}
+
+func TestMemo(t *testing.T) {
+	t.Parallel()
+
+	m := memo[int]{max: 2}
+	calls := 0
+	fn := func(s string) int {
+		calls++
+
+		return len(s)
+	}
+
+	assert.Equal(t, 1, m.get("a", fn))
+	assert.Equal(t, 1, m.get("a", fn))
+	assert.Equal(t, 1, calls)
+
+	// the cache is cleared once it exceeds its limit
+	assert.Equal(t, 2, m.get("bb", fn))
+	assert.Equal(t, 3, m.get("ccc", fn))
+	assert.Equal(t, 1, m.get("a", fn))
+	assert.Equal(t, 4, calls)
+}
@@ -0,0 +1,154 @@
+package gitconfig
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueType is a value type as understood by git config --type.
+type ValueType string
+
+const (
+	// TypeBool canonicalizes to "true" or "false".
+	TypeBool ValueType = "bool"
+	// TypeInt canonicalizes to a decimal integer, applying unit suffixes.
+	TypeInt ValueType = "int"
+	// TypeBoolOrInt canonicalizes booleans to "true" or "false" and integers to decimal.
+	TypeBoolOrInt ValueType = "bool-or-int"
+	// TypePath canonicalizes to a path with a leading tilde expanded.
+	TypePath ValueType = "path"
+	// TypeExpiryDate canonicalizes to a unix timestamp.
+	TypeExpiryDate ValueType = "expiry-date"
+	// TypeColor canonicalizes to an ANSI escape sequence.
+	TypeColor ValueType = "color"
+)
+
+// timeNow is used to resolve relative dates. It can be replaced in tests.
+var timeNow = time.Now
+
+// Canonicalize normalizes a value like git config --type=<typ> does when
+// reading it. This can be used to normalize values before storing or
+// displaying them. The returned error wraps ErrInvalidValue if the value
+// is not valid for the given type.
+//
+// Example:
+//
+//	v, err := Canonicalize("10k", TypeInt) // "10240"
+//	v, err = Canonicalize("yes", TypeBool) // "true"
+func Canonicalize(value string, typ ValueType) (string, error) {
+	switch typ {
+	case TypeBool:
+		b, err := parseBool(value, legacyOptions.EmptyValueIsTrue)
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.FormatBool(b), nil
+	case TypeInt:
+		n, err := parseInt(value)
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.FormatInt(n, 10), nil
+	case TypeBoolOrInt:
+		if n, err := parseInt(value); err == nil {
+			return strconv.FormatInt(n, 10), nil
+		}
+		b, err := parseBool(value, legacyOptions.EmptyValueIsTrue)
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.FormatBool(b), nil
+	case TypePath:
+		return expandPath(value)
+	case TypeExpiryDate:
+		ts, err := parseExpiry(value)
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.FormatInt(ts, 10), nil
+	case TypeColor:
+		c, err := ParseColor(value)
+		if err != nil {
+			return "", err
+		}
+
+		return c.ANSI(), nil
+	default:
+		return "", fmt.Errorf("%w: unknown type %q", ErrInvalidValue, typ)
+	}
+}
+
+// expiryUnits maps the units accepted in relative expiry dates to their length.
+var expiryUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+	"month":  30 * 24 * time.Hour,
+	"year":   365 * 24 * time.Hour,
+}
+
+// expiryLayouts are the absolute date formats accepted in expiry dates.
+var expiryLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseExpiry parses an expiry date like git config --type=expiry-date does
+// and returns it as a unix timestamp. Supported are the special values
+// "never" and "false" (0), "now" and "all" (the maximum timestamp),
+// relative dates like "2.weeks.ago" or "3 days ago", absolute dates in
+// ISO 8601 or RFC 2822 format and unix timestamps prefixed with "@".
+//
+// This is a subset of git's approxidate.
+func parseExpiry(value string) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+
+	switch s {
+	case "never", "false":
+		return 0, nil
+	case "now", "all":
+		return math.MaxInt64, nil
+	}
+
+	if ts, found := strings.CutPrefix(s, "@"); found {
+		n, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: invalid timestamp %q", ErrInvalidValue, value)
+		}
+
+		return n, nil
+	}
+
+	for _, layout := range expiryLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(value), time.Local); err == nil {
+			return t.Unix(), nil
+		}
+	}
+
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '.' || r == ' '
+	})
+	if len(fields) == 3 && fields[2] == "ago" {
+		n, err := strconv.ParseInt(fields[0], 10, 64)
+		unit, ok := expiryUnits[strings.TrimSuffix(fields[1], "s")]
+		if err == nil && ok && n >= 0 && n <= math.MaxInt64/int64(unit) {
+			return timeNow().Add(-time.Duration(n) * unit).Unix(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: invalid expiry date %q", ErrInvalidValue, value)
+}
@@ -0,0 +1,88 @@
+package gitconfig
+
+import (
+	"math"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalize(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	for _, tc := range []struct {
+		value string
+		typ   ValueType
+		want  string
+	}{
+		{"yes", TypeBool, "true"},
+		{"Off", TypeBool, "false"},
+		{"2", TypeBool, "true"},
+		{"10k", TypeInt, "10240"},
+		{" 7 ", TypeInt, "7"},
+		{"on", TypeBoolOrInt, "true"},
+		{"1", TypeBoolOrInt, "1"},
+		{"1m", TypeBoolOrInt, "1048576"},
+		{"~/foo", TypePath, filepath.Join(td, "foo")},
+		{"rel/path", TypePath, "rel/path"},
+		{"never", TypeExpiryDate, "0"},
+		{"@1700000000", TypeExpiryDate, "1700000000"},
+		{"bold red", TypeColor, "\x1b[1;31m"},
+	} {
+		got, err := Canonicalize(tc.value, tc.typ)
+		require.NoError(t, err, "%s as %s", tc.value, tc.typ)
+		assert.Equal(t, tc.want, got, "%s as %s", tc.value, tc.typ)
+	}
+
+	for _, tc := range []struct {
+		value string
+		typ   ValueType
+	}{
+		{"maybe", TypeBool},
+		{"1x", TypeInt},
+		{"maybe", TypeBoolOrInt},
+		{"~nosuchuser12345/foo", TypePath},
+		{"someday", TypeExpiryDate},
+		{"purple", TypeColor},
+		{"foo", ValueType("unknown")},
+	} {
+		_, err := Canonicalize(tc.value, tc.typ)
+		require.ErrorIs(t, err, ErrInvalidValue, "%s as %s", tc.value, tc.typ)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = time.Now })
+
+	for in, want := range map[string]int64{
+		"never":                0,
+		"false":                0,
+		"now":                  math.MaxInt64,
+		"all":                  math.MaxInt64,
+		"2.weeks.ago":          now.Add(-14 * 24 * time.Hour).Unix(),
+		"3 days ago":           now.Add(-3 * 24 * time.Hour).Unix(),
+		"1.hour.ago":           now.Add(-time.Hour).Unix(),
+		"@1234":                1234,
+		"2024-01-02T03:04:05Z": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Unix(),
+	} {
+		got, err := parseExpiry(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	d, err := parseExpiry("2024-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local).Unix(), d)
+
+	for _, in := range []string{"", "soon", "2.fortnights.ago", "x.days.ago", "@abc", "-1.days.ago", strconv.Itoa(math.MaxInt32) + "0000000000.years.ago"} {
+		_, err := parseExpiry(in)
+		require.ErrorIs(t, err, ErrInvalidValue, in)
+	}
+}
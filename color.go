@@ -0,0 +1,288 @@
+package gitconfig
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ColorKind describes how a ColorValue is specified.
+type ColorKind int
+
+const (
+	// ColorNone means no color was given (or "normal"), i.e. the color is left unchanged.
+	ColorNone ColorKind = iota
+	// ColorDefault is the terminal's default color.
+	ColorDefault
+	// ColorANSI is one of the eight basic ANSI colors (Index 0-7).
+	ColorANSI
+	// ColorBright is the bright variant of one of the eight basic ANSI colors (Index 0-7).
+	ColorBright
+	// Color256 is a color from the 256 color palette (Index 0-255).
+	Color256
+	// ColorRGB is a 24-bit color.
+	ColorRGB
+)
+
+// ColorValue is a single foreground or background color.
+type ColorValue struct {
+	Kind  ColorKind
+	Index uint8
+	R     uint8
+	G     uint8
+	B     uint8
+}
+
+// Color is a parsed git color specification, e.g. "bold red" or
+// "#ff0000 ul". See the "color" section in git-config(1) for the syntax.
+type Color struct {
+	Foreground ColorValue
+	Background ColorValue
+	// Attributes are the normalized attribute names in the order given,
+	// e.g. "bold" or "nobold".
+	Attributes []string
+	// Reset indicates that all attributes and colors are reset first.
+	Reset bool
+}
+
+// colorNames are the basic ANSI color names in palette order.
+var colorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// colorAttributes maps the supported attributes to their SGR codes.
+// The negated variants use the "no" prefix.
+var colorAttributes = map[string]int{
+	"bold":      1,
+	"dim":       2,
+	"italic":    3,
+	"ul":        4,
+	"blink":     5,
+	"reverse":   7,
+	"strike":    9,
+	"nobold":    22,
+	"nodim":     22,
+	"noitalic":  23,
+	"noul":      24,
+	"noblink":   25,
+	"noreverse": 27,
+	"nostrike":  29,
+}
+
+// GetColor returns the value of the key parsed as a git color specification.
+// If the key is not set defaultValue is parsed instead.
+//
+// Example:
+//
+//	c, err := cfg.GetColor("color.diff.meta", "bold")
+//	fmt.Print(c.ANSI() + "meta" + "\x1b[m")
+func (c *Config) GetColor(key, defaultValue string) (Color, error) {
+	v, found := c.Get(key)
+	if !found {
+		v = defaultValue
+	}
+
+	col, err := ParseColor(v)
+	if err != nil {
+		return Color{}, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return col, nil
+}
+
+// GetColor returns the value for the given key from the first scope that
+// contains it, parsed as a git color specification. If the key is not set
+// in any scope defaultValue is parsed instead.
+func (cs *Configs) GetColor(key, defaultValue string) (Color, error) {
+	v, found := cs.lookup(key)
+	if !found {
+		v = defaultValue
+	}
+
+	col, err := ParseColor(v)
+	if err != nil {
+		return Color{}, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return col, nil
+}
+
+// GetColorBool returns whether color output should be used according to
+// the given key (e.g. "color.ui" or "color.diff"). The values "always" and
+// "never" as well as any boolean are honored. "auto" (the default if the key
+// is not set) enables color only if isTerminal is true.
+func (cs *Configs) GetColorBool(key string, isTerminal bool) bool {
+	cfg, v, found := cs.lookupConfig(key)
+	if !found {
+		return isTerminal
+	}
+
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "always":
+		return true
+	case "never":
+		return false
+	case "auto":
+		return isTerminal
+	}
+
+	b, err := cfg.parseBool(v)
+	if err != nil {
+		return isTerminal
+	}
+
+	// like git, "true" means "auto"
+	return b && isTerminal
+}
+
+// ParseColor parses a git color specification. It consists of up to two
+// colors (foreground and background) and any number of attributes,
+// separated by whitespace. Colors can be given as names (e.g. "red",
+// "brightred", "normal", "default"), as numbers from the 256 color
+// palette or as 24-bit hex values (e.g. "#ff0000" or "#f00").
+// Attributes are bold, dim, italic, ul, blink, reverse and strike, each of
+// which can be negated with a "no" or "no-" prefix, and reset.
+func ParseColor(spec string) (Color, error) {
+	var col Color
+	var colors int
+
+	for _, word := range strings.Fields(spec) {
+		if cv, ok := parseColorValue(word); ok {
+			switch colors {
+			case 0:
+				col.Foreground = cv
+			case 1:
+				col.Background = cv
+			default:
+				return Color{}, fmt.Errorf("%w: too many colors in %q", ErrInvalidValue, spec)
+			}
+			colors++
+
+			continue
+		}
+
+		attr := strings.ToLower(word)
+		if attr == "reset" {
+			col.Reset = true
+
+			continue
+		}
+		attr = strings.Replace(attr, "no-", "no", 1)
+		if _, ok := colorAttributes[attr]; !ok {
+			return Color{}, fmt.Errorf("%w: invalid color %q", ErrInvalidValue, spec)
+		}
+		col.Attributes = append(col.Attributes, attr)
+	}
+
+	return col, nil
+}
+
+// parseColorValue parses a single color word.
+func parseColorValue(word string) (ColorValue, bool) {
+	w := strings.ToLower(word)
+
+	switch w {
+	case "normal", "-1":
+		return ColorValue{Kind: ColorNone}, true
+	case "default":
+		return ColorValue{Kind: ColorDefault}, true
+	}
+
+	if i := slices.Index(colorNames, w); i >= 0 {
+		return ColorValue{Kind: ColorANSI, Index: uint8(i)}, true
+	}
+	if name, found := strings.CutPrefix(w, "bright"); found {
+		if i := slices.Index(colorNames, name); i >= 0 {
+			return ColorValue{Kind: ColorBright, Index: uint8(i)}, true
+		}
+	}
+
+	if hex, found := strings.CutPrefix(w, "#"); found {
+		return parseHexColor(hex)
+	}
+
+	n, err := strconv.ParseUint(w, 10, 8)
+	if err != nil {
+		return ColorValue{}, false
+	}
+
+	// like git, rewrite 0-15 as the more portable basic and bright colors
+	switch {
+	case n < 8:
+		return ColorValue{Kind: ColorANSI, Index: uint8(n)}, true
+	case n < 16:
+		return ColorValue{Kind: ColorBright, Index: uint8(n - 8)}, true
+	default:
+		return ColorValue{Kind: Color256, Index: uint8(n)}, true
+	}
+}
+
+// parseHexColor parses a RRGGBB or RGB hex color.
+func parseHexColor(hex string) (ColorValue, bool) {
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return ColorValue{}, false
+	}
+
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return ColorValue{}, false
+	}
+
+	return ColorValue{Kind: ColorRGB, R: uint8(n >> 16), G: uint8(n >> 8), B: uint8(n)}, true
+}
+
+// ANSI renders the color as an ANSI escape sequence, like
+// git config --type=color does. It returns an empty string
+// if the color doesn't change anything.
+func (c Color) ANSI() string {
+	codes := make([]string, 0, 8)
+
+	if c.Reset {
+		codes = append(codes, "0")
+	}
+
+	attrs := make([]int, 0, len(c.Attributes))
+	for _, a := range c.Attributes {
+		if code, ok := colorAttributes[a]; ok && !slices.Contains(attrs, code) {
+			attrs = append(attrs, code)
+		}
+	}
+	slices.Sort(attrs)
+	for _, code := range attrs {
+		codes = append(codes, strconv.Itoa(code))
+	}
+
+	if fg := c.Foreground.sgr(30); fg != "" {
+		codes = append(codes, fg)
+	}
+	if bg := c.Background.sgr(40); bg != "" {
+		codes = append(codes, bg)
+	}
+
+	if len(codes) == 0 {
+		return ""
+	}
+
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
+// sgr returns the SGR parameters for this color. base is 30 for
+// foreground and 40 for background colors.
+func (cv ColorValue) sgr(base int) string {
+	switch cv.Kind {
+	case ColorDefault:
+		return strconv.Itoa(base + 9)
+	case ColorANSI:
+		return strconv.Itoa(base + int(cv.Index))
+	case ColorBright:
+		return strconv.Itoa(base + 60 + int(cv.Index))
+	case Color256:
+		return fmt.Sprintf("%d;5;%d", base+8, cv.Index)
+	case ColorRGB:
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, cv.R, cv.G, cv.B)
+	default:
+		return ""
+	}
+}
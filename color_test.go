@@ -0,0 +1,94 @@
+package gitconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColor(t *testing.T) {
+	t.Parallel()
+
+	for spec, want := range map[string]string{
+		"":                  "",
+		"normal":            "",
+		"red":               "\x1b[31m",
+		"bold red":          "\x1b[1;31m",
+		"red bold":          "\x1b[1;31m",
+		"red blue":          "\x1b[31;44m",
+		"brightred":         "\x1b[91m",
+		"normal brightblue": "\x1b[104m",
+		"default":           "\x1b[39m",
+		"reset":             "\x1b[0m",
+		"ul no-bold":        "\x1b[4;22m",
+		"nobold bold":       "\x1b[1;22m",
+		"5":                 "\x1b[35m",
+		"12":                "\x1b[94m",
+		"208":               "\x1b[38;5;208m",
+		"#ff0000":           "\x1b[38;2;255;0;0m",
+		"#0f0 #FFFFFF":      "\x1b[38;2;0;255;0;48;2;255;255;255m",
+		"-1 green":          "\x1b[42m",
+	} {
+		c, err := ParseColor(spec)
+		require.NoError(t, err, spec)
+		assert.Equal(t, want, c.ANSI(), spec)
+	}
+
+	for _, spec := range []string{"red blue green", "purple", "#ff00", "256", "blod"} {
+		_, err := ParseColor(spec)
+		require.ErrorIs(t, err, ErrInvalidValue, spec)
+	}
+
+	c, err := ParseColor("bold italic #112233 blue")
+	require.NoError(t, err)
+	assert.Equal(t, Color{
+		Foreground: ColorValue{Kind: ColorRGB, R: 0x11, G: 0x22, B: 0x33},
+		Background: ColorValue{Kind: ColorANSI, Index: 4},
+		Attributes: []string{"bold", "italic"},
+	}, c)
+}
+
+func TestGetColor(t *testing.T) {
+	t.Parallel()
+
+	cfg := ParseConfig(strings.NewReader(`[color "diff"]
+	meta = yellow bold
+	broken = rainbow
+[color]
+	ui = auto
+	diff = always
+	branch = never
+	status = false
+`))
+
+	c, err := cfg.GetColor("color.diff.meta", "normal")
+	require.NoError(t, err)
+	assert.Equal(t, "\x1b[1;33m", c.ANSI())
+
+	c, err = cfg.GetColor("color.diff.frag", "cyan")
+	require.NoError(t, err)
+	assert.Equal(t, "\x1b[36m", c.ANSI())
+
+	_, err = cfg.GetColor("color.diff.broken", "")
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	cs := New()
+	cs.local = cfg
+
+	c, err = cs.GetColor("color.diff.meta", "")
+	require.NoError(t, err)
+	assert.Equal(t, "\x1b[1;33m", c.ANSI())
+
+	c, err = cs.GetColor("color.diff.missing", "blue")
+	require.NoError(t, err)
+	assert.Equal(t, "\x1b[34m", c.ANSI())
+
+	assert.True(t, cs.GetColorBool("color.ui", true))
+	assert.False(t, cs.GetColorBool("color.ui", false))
+	assert.True(t, cs.GetColorBool("color.diff", false))
+	assert.False(t, cs.GetColorBool("color.branch", true))
+	assert.False(t, cs.GetColorBool("color.status", true))
+	assert.True(t, cs.GetColorBool("color.missing", true))
+}
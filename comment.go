@@ -0,0 +1,87 @@
+package gitconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultCommentPrefix starts generated comments, see SetCommentPrefix.
+const defaultCommentPrefix = "# "
+
+// SetCommentPrefix sets how comments generated by FormatComment and
+// AddComment start. The prefix must be "#" or ";", optionally followed by
+// spaces or tabs. The default is "# ".
+//
+// Example:
+//
+//	if err := cfg.SetCommentPrefix(";"); err != nil { ... }
+//	err := cfg.AddComment("core", "", "managed by migrate-config")
+func (c *Config) SetCommentPrefix(prefix string) error {
+	if prefix == "" || (prefix[0] != '#' && prefix[0] != ';') || strings.Trim(prefix[1:], " \t") != "" {
+		return fmt.Errorf("%w: invalid comment prefix %q", ErrInvalidValue, prefix)
+	}
+
+	c.commentPrefix = prefix
+
+	return nil
+}
+
+// CommentPrefix returns the prefix of generated comments, see
+// SetCommentPrefix.
+func (c *Config) CommentPrefix() string {
+	if c == nil || c.commentPrefix == "" {
+		return defaultCommentPrefix
+	}
+
+	return c.commentPrefix
+}
+
+// FormatComment turns text into comment lines, one for every line of the
+// text, joined by "\n". Empty lines become a bare comment character.
+//
+// Example:
+//
+//	fmt.Println(cfg.FormatComment("managed by migrate-config\ndo not edit"))
+//	// # managed by migrate-config
+//	// # do not edit
+func (c *Config) FormatComment(text string) string {
+	prefix := c.CommentPrefix()
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(prefix+l, " \t")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// AddComment inserts text as comment lines right below the first header of
+// the section (see FormatComment). The lines are indented like the keys of
+// the section.
+//
+// Example:
+//
+//	err := cfg.AddComment("remote", "origin", "added by the setup wizard")
+func (c *Config) AddComment(section, subsection, text string) error {
+	if err := c.checkEditable(); err != nil {
+		return err
+	}
+	if strings.ContainsAny(text, "\r\x00") {
+		return fmt.Errorf("%w: comment %q contains a carriage return or NUL", ErrInvalidValue, text)
+	}
+
+	return c.editLines(func(d *document) error {
+		i, err := sectionIndex(d, section, subsection)
+		if err != nil {
+			return err
+		}
+
+		indent := d.style(strings.ToLower(section), subsection, "").indent
+		var comments []docLine
+		for _, line := range strings.Split(c.FormatComment(text), "\n") {
+			comments = append(comments, docLine{kind: lineOther, text: indent + line})
+		}
+		d.lines = append(d.lines[:i+1], append(comments, d.lines[i+1:]...)...)
+
+		return nil
+	})
+}
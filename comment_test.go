@@ -0,0 +1,49 @@
+package gitconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentPrefix(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader(""))
+	assert.Equal(t, "# ", c.CommentPrefix())
+	assert.Equal(t, "# managed\n#\n# do not edit", c.FormatComment("managed\n\ndo not edit"))
+
+	require.NoError(t, c.SetCommentPrefix(";"))
+	assert.Equal(t, ";managed", c.FormatComment("managed"))
+	require.NoError(t, c.SetCommentPrefix("#\t"))
+	assert.Equal(t, "#\tmanaged", c.FormatComment("managed"))
+
+	for _, prefix := range []string{"", "//", "# x", " #"} {
+		require.ErrorIs(t, c.SetCommentPrefix(prefix), ErrInvalidValue, prefix)
+	}
+	assert.Equal(t, "#\t", c.CommentPrefix())
+
+	var nilCfg *Config
+	assert.Equal(t, "# ", nilCfg.CommentPrefix())
+}
+
+func TestAddComment(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader("[core]\n    editor = vim\n[remote \"origin\"]\n\turl = x\n"))
+	c.noWrites = true
+
+	require.NoError(t, c.AddComment("Core", "", "managed by a tool\ndo not edit"))
+	require.NoError(t, c.SetCommentPrefix("; "))
+	require.NoError(t, c.AddComment("remote", "origin", "added by the wizard"))
+
+	assert.Equal(t, "[core]\n    # managed by a tool\n    # do not edit\n    editor = vim\n"+
+		"[remote \"origin\"]\n\t; added by the wizard\n\turl = x\n", c.raw.String())
+	assert.Equal(t, []Origin{{Line: 4}}, c.Origins("core.editor"))
+
+	require.ErrorIs(t, c.AddComment("missing", "", "x"), ErrInvalidKey)
+	require.ErrorIs(t, c.AddComment("core", "", "a\rb"), ErrInvalidValue)
+	require.ErrorIs(t, NewFromMap(nil).AddComment("core", "", "x"), ErrReadonly)
+}
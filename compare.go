@@ -0,0 +1,69 @@
+package gitconfig
+
+import "strings"
+
+// ValueComparison controls how Set decides whether a new value is equal to
+// the existing one. If both are considered equal the config is not rewritten.
+type ValueComparison int
+
+const (
+	// CompareExact treats values as equal only if they are byte-for-byte identical.
+	CompareExact ValueComparison = iota
+	// CompareNormalized ignores leading and trailing whitespace, which git
+	// discards for unquoted values anyway.
+	CompareNormalized
+	// CompareSemantic additionally treats values as equal if they represent
+	// the same boolean (e.g. "yes" and "true") or the same integer
+	// (e.g. "1k" and "1024"). This avoids needless rewrites for callers that
+	// repeatedly converge a config to a desired state.
+	CompareSemantic
+)
+
+// String implements fmt.Stringer.
+func (vc ValueComparison) String() string {
+	switch vc {
+	case CompareExact:
+		return "exact"
+	case CompareNormalized:
+		return "normalized"
+	case CompareSemantic:
+		return "semantic"
+	default:
+		return "unknown"
+	}
+}
+
+// equal reports whether the existing value and the new value are considered
+// equal under this comparison mode.
+func (vc ValueComparison) equal(existing, value string) bool {
+	switch vc {
+	case CompareNormalized:
+		return strings.TrimSpace(existing) == strings.TrimSpace(value)
+	case CompareSemantic:
+		return semanticEqual(existing, value)
+	default:
+		return existing == value
+	}
+}
+
+// semanticEqual reports whether two values are equal after normalization,
+// either as integers or as booleans. Integers take precedence so that e.g.
+// "10" and "1" are never considered equal just because both are truthy.
+func semanticEqual(a, b string) bool {
+	a = strings.TrimSpace(a)
+	b = strings.TrimSpace(b)
+	if a == b {
+		return true
+	}
+
+	ia, errA := parseInt(a)
+	ib, errB := parseInt(b)
+	if errA == nil && errB == nil {
+		return ia == ib
+	}
+
+	ba, okA := parseBoolText(a)
+	bb, okB := parseBoolText(b)
+
+	return okA && okB && ba == bb
+}
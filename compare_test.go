@@ -0,0 +1,101 @@
+package gitconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueComparisonEqual(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		mode     ValueComparison
+		existing string
+		value    string
+		equal    bool
+	}{
+		{CompareExact, "foo", "foo", true},
+		{CompareExact, "foo", " foo", false},
+		{CompareExact, "foo", "Foo", false},
+		{CompareNormalized, "foo", " foo ", true},
+		{CompareNormalized, "foo", "Foo", false},
+		{CompareNormalized, "yes", "true", false},
+		{CompareSemantic, "yes", "true", true},
+		{CompareSemantic, "off", "0", true},
+		{CompareSemantic, "on", "false", false},
+		{CompareSemantic, "1k", "1024", true},
+		{CompareSemantic, "10", "1", false},
+		{CompareSemantic, "10", "true", false},
+		{CompareSemantic, "foo", " foo", true},
+	} {
+		assert.Equal(t, tc.equal, tc.mode.equal(tc.existing, tc.value), "%s: %q == %q", tc.mode, tc.existing, tc.value)
+	}
+}
+
+func TestSetComparesReplacedValue(t *testing.T) {
+	t.Parallel()
+
+	in := `[core]
+	multi = first
+	multi = second
+`
+	c := ParseConfig(strings.NewReader(in))
+	c.noWrites = true
+
+	// "second" is present, but only the first value would be replaced,
+	// so this must update the config.
+	require.NoError(t, c.Set("core.multi", "second"))
+	vs, ok := c.GetAll("core.multi")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"second", "second"}, vs)
+	assert.Equal(t, `[core]
+	multi = second
+	multi = second
+`, c.raw.String())
+}
+
+func TestSetNormalizedComparison(t *testing.T) {
+	t.Parallel()
+
+	in := `[core]
+	editor = vim
+`
+	c := ParseConfig(strings.NewReader(in))
+	c.noWrites = true
+
+	require.NoError(t, c.Set("core.editor", "vim "))
+	v, _ := c.Get("core.editor")
+	assert.Equal(t, "vim ", v)
+
+	c = ParseConfig(strings.NewReader(in))
+	c.noWrites = true
+	c.SetValueComparison(CompareNormalized)
+
+	require.NoError(t, c.Set("core.editor", "vim "))
+	v, _ = c.Get("core.editor")
+	assert.Equal(t, "vim", v)
+	assert.Equal(t, in, c.raw.String())
+}
+
+func TestSetSemanticComparison(t *testing.T) {
+	t.Parallel()
+
+	in := `[core]
+	autocrlf = yes
+	bigFileThreshold = 512m
+`
+	c := ParseConfig(strings.NewReader(in))
+	c.noWrites = true
+	c.SetValueComparison(CompareSemantic)
+
+	require.NoError(t, c.Set("core.autocrlf", "true"))
+	require.NoError(t, c.Set("core.bigfilethreshold", "536870912"))
+	assert.Equal(t, in, c.raw.String())
+
+	require.NoError(t, c.Set("core.autocrlf", "false"))
+	v, _ := c.Get("core.autocrlf")
+	assert.Equal(t, "false", v)
+}
@@ -0,0 +1,267 @@
+package gitconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompatLevel bundles the behaviors where this package and git differ.
+// It allows embedders to either keep the behavior of earlier releases
+// or opt into matching git as closely as possible.
+type CompatLevel int
+
+const (
+	// CompatLegacy keeps the behavior of earlier releases of this package.
+	// This is the default.
+	CompatLegacy CompatLevel = iota
+	// CompatGitExact matches git's behavior, even where it is surprising.
+	CompatGitExact
+	// CompatCustom uses the options set with SetCompatOptions.
+	CompatCustom
+)
+
+// String implements fmt.Stringer.
+func (l CompatLevel) String() string {
+	switch l {
+	case CompatLegacy:
+		return "legacy"
+	case CompatGitExact:
+		return "git-exact"
+	case CompatCustom:
+		return "custom"
+	default:
+		return fmt.Sprintf("CompatLevel(%d)", int(l))
+	}
+}
+
+// CompatOptions are the individual behaviors selected by a CompatLevel.
+//
+// Fields:
+//   - LastValueWins: Get returns the last value of a multivar and Set updates
+//     it, like git does. Otherwise the first value is used.
+//   - UnescapeValues: Escape sequences in values (e.g. \n, \t, \") are
+//     processed. Compatibility mode (see Config.SetCompatMode) disables this
+//     regardless of the level.
+//   - EmptyValueIsTrue: An explicitly empty value ("key =") is a true
+//     boolean. Git treats it as false. A bare key (without "=") is always
+//     true.
+//
+// The order of included values is not covered. Git inserts the values of
+// an include at the position of the include directive, this package always
+// adds them after the values of the including file, see CONFIG_FORMAT.md.
+type CompatOptions struct {
+	LastValueWins    bool
+	UnescapeValues   bool
+	EmptyValueIsTrue bool
+}
+
+// Options returns the options of a predefined level. CompatCustom has no
+// predefined options, it returns the options of CompatLegacy.
+func (l CompatLevel) Options() CompatOptions {
+	switch l {
+	case CompatGitExact:
+		return CompatOptions{
+			LastValueWins:    true,
+			UnescapeValues:   true,
+			EmptyValueIsTrue: false,
+		}
+	default:
+		return legacyOptions
+	}
+}
+
+// legacyOptions are the options of CompatLegacy.
+var legacyOptions = CompatOptions{
+	LastValueWins:    false,
+	UnescapeValues:   true,
+	EmptyValueIsTrue: true,
+}
+
+// compatState is a compatibility setting, see Config.SetCompatLevel. It is
+// never modified, so it can be shared by configs.
+type compatState struct {
+	level   CompatLevel
+	options CompatOptions
+}
+
+// newCompatState returns the setting for the level l. CompatCustom keeps
+// the options of prev.
+func newCompatState(l CompatLevel, prev CompatOptions) *compatState {
+	if l == CompatCustom {
+		return &compatState{level: CompatCustom, options: prev}
+	}
+
+	return &compatState{level: l, options: l.Options()}
+}
+
+// compatOptions returns the compatibility options of this config, see
+// SetCompatLevel.
+func (c *Config) compatOptions() CompatOptions {
+	if c == nil || c.level == nil {
+		return legacyOptions
+	}
+
+	return c.level.options
+}
+
+// valueIndex returns the index of the value that Get returns for a key
+// with n values.
+func (c *Config) valueIndex(n int) int {
+	if c.compatOptions().LastValueWins {
+		return n - 1
+	}
+
+	return 0
+}
+
+// unescapeValues returns true if escape sequences in the values of this
+// config are processed.
+func (c *Config) unescapeValues() bool {
+	if c == nil || c.compat == nil {
+		return c.compatOptions().UnescapeValues && !CompatMode
+	}
+
+	return c.compatOptions().UnescapeValues && !*c.compat
+}
+
+// parseBool parses a boolean value of this config, see parseBool.
+func (c *Config) parseBool(value string) (bool, error) {
+	return parseBool(value, c.compatOptions().EmptyValueIsTrue)
+}
+
+// SetCompatLevel selects a compatibility level for this config. The default
+// is CompatLegacy. CompatCustom keeps the options of the config, use
+// SetCompatOptions to change them. Like SetCompatMode the values are
+// re-read from the text of the config if the escape handling changes.
+// Values of included files keep the level they were loaded with, use
+// Configs.SetCompatLevel to apply a level before loading.
+//
+// Example:
+//
+//	c := gitconfig.ParseConfig(r)
+//	c.SetCompatLevel(gitconfig.CompatGitExact)
+//	v, _ := c.Get("core.multi") // the last value
+func (c *Config) SetCompatLevel(l CompatLevel) {
+	c.setCompat(newCompatState(l, c.compatOptions()))
+}
+
+// SetCompatOptions configures the compatibility behaviors of this config
+// individually and switches it to CompatCustom.
+func (c *Config) SetCompatOptions(o CompatOptions) {
+	c.setCompat(&compatState{level: CompatCustom, options: o})
+}
+
+// Compat returns the compatibility level of this config and its options.
+func (c *Config) Compat() (CompatLevel, CompatOptions) {
+	if c == nil || c.level == nil {
+		return CompatLegacy, legacyOptions
+	}
+
+	return c.level.level, c.level.options
+}
+
+// setCompat replaces the compatibility setting of the config. The setting
+// is kept for Reload.
+func (c *Config) setCompat(s *compatState) {
+	before := c.unescapeValues()
+	c.level = s
+	if c.loadedWith != nil {
+		c.loadedWith.opts.level = s
+	}
+	c.resetCoercions()
+	if c.unescapeValues() == before || c.raw.Len() == 0 {
+		return
+	}
+
+	c.reloadVars(parseDocument(strings.NewReader(c.raw.String()), c.unescapeValues(), c.keys))
+}
+
+// SetCompatMode enables or disables compatibility mode for this config only,
+// overriding the package-wide CompatMode. In compatibility mode values are
+// read and written as is, without processing quotes and escape sequences.
+// The values of the config are re-read from its text, so they reflect the
+// new mode. Values of included files keep the mode they were loaded with,
+// use Configs.SetCompatMode to apply a mode before loading.
+//
+// Example:
+//
+//	c := gitconfig.ParseConfig(r)
+//	c.SetCompatMode(true)
+//	v, _ := c.Get("alias.quoted") // quotes are kept
+func (c *Config) SetCompatMode(enabled bool) {
+	before := c.unescapeValues()
+	c.compat = &enabled
+	if c.unescapeValues() == before || c.raw.Len() == 0 {
+		return
+	}
+
+	c.reloadVars(parseDocument(strings.NewReader(c.raw.String()), c.unescapeValues(), c.keys))
+}
+
+// SetCompatMode enables or disables compatibility mode for all scopes,
+// overriding the package-wide CompatMode. It applies to the scopes that are
+// already loaded and to everything loaded by LoadAll and Reload later on,
+// including includes.
+//
+// Example:
+//
+//	cfg := gitconfig.New()
+//	cfg.SetCompatMode(true)
+//	cfg.LoadAll(".")
+func (cs *Configs) SetCompatMode(enabled bool) {
+	cs.compatMode = &enabled
+	cs.applyCompatMode()
+}
+
+// SetCompatLevel selects a compatibility level for all scopes, see
+// Config.SetCompatLevel. It applies to the scopes that are already loaded
+// and to everything loaded by LoadAll and Reload later on, including
+// includes.
+//
+// Example:
+//
+//	cfg := gitconfig.New()
+//	cfg.SetCompatLevel(gitconfig.CompatGitExact)
+//	cfg.LoadAll(".")
+func (cs *Configs) SetCompatLevel(l CompatLevel) {
+	_, o := cs.Compat()
+	cs.compatLevel = newCompatState(l, o)
+	cs.applyCompatMode()
+}
+
+// SetCompatOptions configures the compatibility behaviors of all scopes
+// individually and switches them to CompatCustom, see SetCompatLevel.
+func (cs *Configs) SetCompatOptions(o CompatOptions) {
+	cs.compatLevel = &compatState{level: CompatCustom, options: o}
+	cs.applyCompatMode()
+}
+
+// Compat returns the compatibility level of the scopes and its options.
+func (cs *Configs) Compat() (CompatLevel, CompatOptions) {
+	if cs.compatLevel == nil {
+		return CompatLegacy, legacyOptions
+	}
+
+	return cs.compatLevel.level, cs.compatLevel.options
+}
+
+// applyCompatMode passes the CompatMode and CompatLevel settings of cs on
+// to all scopes.
+func (cs *Configs) applyCompatMode() {
+	for _, sc := range cs.namedScopes() {
+		if sc.cfg == nil {
+			continue
+		}
+		cs.applyCompat(sc.cfg)
+	}
+}
+
+// applyCompat applies the compatibility settings of cs to c.
+func (cs *Configs) applyCompat(c *Config) {
+	if cs.compatMode != nil {
+		c.SetCompatMode(*cs.compatMode)
+	}
+	if cs.compatLevel != nil {
+		c.setCompat(cs.compatLevel)
+	}
+}
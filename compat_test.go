@@ -0,0 +1,327 @@
+package gitconfig
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const compatTestConfig = `[core]
+	multi = first
+	multi = last
+	escaped = "a\tb"
+	empty =
+	bare
+`
+
+func TestCompatLevels(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		level   CompatLevel
+		multi   string
+		escaped string
+		empty   bool
+		updated string
+	}{
+		{
+			level:   CompatLegacy,
+			multi:   "first",
+			escaped: "a\tb",
+			empty:   true,
+			updated: "\tmulti = new\n\tmulti = last",
+		},
+		{
+			level:   CompatGitExact,
+			multi:   "last",
+			escaped: "a\tb",
+			empty:   false,
+			updated: "\tmulti = first\n\tmulti = new",
+		},
+	} {
+		t.Run(tc.level.String(), func(t *testing.T) {
+			t.Parallel()
+
+			c := ParseConfig(strings.NewReader(compatTestConfig))
+			c.noWrites = true
+			c.SetCompatLevel(tc.level)
+
+			v, _ := c.Get("core.multi")
+			assert.Equal(t, tc.multi, v)
+			v, _ = c.Get("core.escaped")
+			assert.Equal(t, tc.escaped, v)
+			b, ok := c.GetBool("core.empty")
+			assert.True(t, ok)
+			assert.Equal(t, tc.empty, b)
+			b, ok = c.GetBool("core.bare")
+			assert.True(t, ok)
+			assert.True(t, b)
+
+			require.NoError(t, c.Set("core.multi", "new"))
+			v, _ = c.Get("core.multi")
+			assert.Equal(t, "new", v)
+			assert.Contains(t, c.raw.String(), tc.updated)
+		})
+	}
+}
+
+func TestCompatCustom(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader(compatTestConfig))
+	l, o := c.Compat()
+	assert.Equal(t, CompatLegacy, l)
+	assert.Equal(t, CompatLegacy.Options(), o)
+
+	c.SetCompatOptions(CompatOptions{LastValueWins: true})
+	l, o = c.Compat()
+	assert.Equal(t, CompatCustom, l)
+	assert.Equal(t, CompatOptions{LastValueWins: true}, o)
+
+	v, _ := c.Get("core.multi")
+	assert.Equal(t, "last", v)
+	v, _ = c.Get("core.escaped")
+	assert.Equal(t, `a\tb`, v)
+
+	// switching to custom keeps the current options
+	c.SetCompatLevel(CompatGitExact)
+	c.SetCompatLevel(CompatCustom)
+	l, o = c.Compat()
+	assert.Equal(t, CompatCustom, l)
+	assert.Equal(t, CompatGitExact.Options(), o)
+
+	// custom has no predefined options
+	assert.Equal(t, CompatLegacy.Options(), CompatCustom.Options())
+	assert.Equal(t, "CompatLevel(42)", CompatLevel(42).String())
+}
+
+func TestConfigCompatLevel(t *testing.T) {
+	t.Parallel()
+
+	legacy := ParseConfig(strings.NewReader(compatTestConfig))
+	exact := ParseConfig(strings.NewReader(compatTestConfig))
+	exact.SetCompatLevel(CompatGitExact)
+
+	l, o := exact.Compat()
+	assert.Equal(t, CompatGitExact, l)
+	assert.Equal(t, CompatGitExact.Options(), o)
+
+	// the level only applies to the config it is set on
+	v, _ := legacy.Get("core.multi")
+	assert.Equal(t, "first", v)
+	v, _ = exact.Get("core.multi")
+	assert.Equal(t, "last", v)
+
+	// cached typed values follow the level
+	b, ok := legacy.GetBool("core.empty")
+	assert.True(t, ok)
+	assert.True(t, b)
+	legacy.SetCompatOptions(CompatOptions{UnescapeValues: true})
+	b, ok = legacy.GetBool("core.empty")
+	assert.True(t, ok)
+	assert.False(t, b)
+
+	// values are re-read if the escape handling changes
+	legacy.SetCompatOptions(CompatOptions{})
+	v, _ = legacy.Get("core.escaped")
+	assert.Equal(t, `a\tb`, v)
+	legacy.SetCompatLevel(CompatCustom)
+	l, o = legacy.Compat()
+	assert.Equal(t, CompatCustom, l)
+	assert.Equal(t, CompatOptions{}, o)
+
+	var s struct {
+		Empty bool `gitconfig:"core.empty"`
+	}
+	require.NoError(t, legacy.Unmarshal(&s))
+	assert.False(t, s.Empty)
+}
+
+// TestCompatGitExactConformance checks CompatGitExact against the git binary.
+func TestCompatGitExactConformance(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	t.Parallel()
+
+	fn := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(fn, []byte(compatTestConfig), 0o600))
+
+	c, err := LoadConfig(fn)
+	require.NoError(t, err)
+	c.SetCompatLevel(CompatGitExact)
+
+	for _, tc := range []struct {
+		key  string
+		args []string
+		ours func() string
+	}{
+		{"core.multi", nil, func() string { v, _ := c.Get("core.multi"); return v }},
+		{"core.escaped", nil, func() string { v, _ := c.Get("core.escaped"); return v }},
+		{"core.empty", []string{"--type=bool"}, func() string { v, _ := c.GetBool("core.empty"); return boolString(v) }},
+		{"core.bare", nil, func() string { v, _ := c.Get("core.bare"); return v }},
+		{"core.bare", []string{"--type=bool"}, func() string { v, _ := c.GetBool("core.bare"); return boolString(v) }},
+	} {
+		args := append([]string{"config", "--file", fn}, tc.args...)
+		out, err := exec.Command("git", append(args, "--get", tc.key)...).Output()
+		require.NoError(t, err, tc.key)
+		assert.Equal(t, strings.TrimSuffix(string(out), "\n"), tc.ours(), tc.key)
+	}
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+
+	return "false"
+}
+
+// TestQuoteValueGitConformance checks that values written by Set are read
+// back unchanged by git and that values written by git are read unchanged.
+func TestQuoteValueGitConformance(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	t.Parallel()
+
+	values := []string{
+		"plain",
+		" leading and trailing ",
+		"with # hash",
+		"semi;colon",
+		`back\slash`,
+		`"quoted"`,
+		"tab\there",
+		"new\nline",
+	}
+
+	fn := filepath.Join(t.TempDir(), "config")
+	c := &Config{path: fn}
+	c.SetCompatLevel(CompatGitExact)
+	for i, v := range values {
+		require.NoError(t, c.Set(fmt.Sprintf("ours.key%d", i), v))
+	}
+
+	for i, v := range values {
+		key := fmt.Sprintf("ours.key%d", i)
+		out, err := exec.Command("git", "config", "--file", fn, "--get", key).Output()
+		require.NoError(t, err, key)
+		assert.Equal(t, v, strings.TrimSuffix(string(out), "\n"), key)
+
+		require.NoError(t, exec.Command("git", "config", "--file", fn, fmt.Sprintf("theirs.key%d", i), v).Run())
+	}
+
+	c, err := LoadConfig(fn)
+	require.NoError(t, err)
+	c.SetCompatLevel(CompatGitExact)
+	for i, v := range values {
+		got, _ := c.Get(fmt.Sprintf("theirs.key%d", i))
+		assert.Equal(t, v, got, i)
+	}
+}
+
+func TestConfigSetCompatMode(t *testing.T) {
+	t.Parallel()
+
+	in := "[alias]\n\tq = \"a\\tb\"\n"
+
+	strict := ParseConfig(strings.NewReader(in))
+	strict.noWrites = true
+	compatible := ParseConfig(strings.NewReader(in))
+	compatible.noWrites = true
+	compatible.SetCompatMode(true)
+
+	v, _ := strict.Get("alias.q")
+	assert.Equal(t, "a\tb", v)
+	v, _ = compatible.Get("alias.q")
+	assert.Equal(t, `a\tb`, v)
+
+	// the mode also applies to writes
+	require.NoError(t, strict.Set("alias.n", "x\ny"))
+	require.ErrorIs(t, compatible.Set("alias.n", "x\ny"), ErrInvalidValue)
+	require.NoError(t, compatible.Set("alias.n", `"raw"`))
+	assert.Contains(t, compatible.raw.String(), "\tn = \"raw\"\n")
+
+	compatible.SetCompatMode(false)
+	v, _ = compatible.Get("alias.q")
+	assert.Equal(t, "a\tb", v)
+}
+
+func TestConfigsSetCompatMode(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	require.NoError(t, os.WriteFile(filepath.Join(td, "local"), []byte("[include]\n\tpath = included\n[local]\n\tkey = \"a\\tb\"\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "included"), []byte("[inc]\n\tkey = \"c\\td\"\n"), 0o600))
+
+	c := New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_COMPAT_CONFIG"
+	c.NoWrites = true
+
+	c.LoadAll(td)
+	assert.Equal(t, "a\tb", c.Get("local.key"))
+	assert.Equal(t, "c\td", c.Get("inc.key"))
+
+	c.SetCompatMode(true)
+	c.LoadAll(td)
+	assert.Equal(t, `a\tb`, c.Get("local.key"))
+	assert.Equal(t, `c\td`, c.Get("inc.key"))
+	assert.False(t, CompatMode)
+}
+
+func TestConfigsSetCompatLevel(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	require.NoError(t, os.WriteFile(filepath.Join(td, "local"), []byte("[include]\n\tpath = included\n[local]\n\tkey = first\n\tkey = last\n\tempty =\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "included"), []byte("[inc]\n\tkey = first\n\tkey = last\n"), 0o600))
+
+	c := New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_COMPAT_CONFIG"
+	c.NoWrites = true
+
+	c.LoadAll(td)
+	assert.Equal(t, "first", c.Get("local.key"))
+	assert.Equal(t, "first", c.Get("inc.key"))
+
+	// applies to the loaded scopes and to reloads, including includes
+	c.SetCompatLevel(CompatGitExact)
+	l, o := c.Compat()
+	assert.Equal(t, CompatGitExact, l)
+	assert.Equal(t, CompatGitExact.Options(), o)
+	assert.Equal(t, "last", c.Get("local.key"))
+	b, ok := c.GetBool("local.empty")
+	assert.True(t, ok)
+	assert.False(t, b)
+
+	c.LoadAll(td)
+	assert.Equal(t, "last", c.Get("inc.key"))
+	_, o = c.scopeConfig(ScopeLocal).Compat()
+	assert.Equal(t, CompatGitExact.Options(), o)
+
+	// other instances are not affected
+	other := New()
+	other.SystemConfig = filepath.Join(td, "system")
+	other.LocalConfig = "local"
+	other.EnvPrefix = "GPTEST_COMPAT_CONFIG"
+	other.LoadAll(td)
+	assert.Equal(t, "first", other.Get("inc.key"))
+
+	c.SetCompatOptions(CompatOptions{UnescapeValues: true})
+	l, _ = c.Compat()
+	assert.Equal(t, CompatCustom, l)
+	assert.Equal(t, "first", c.Get("local.key"))
+}
@@ -0,0 +1,57 @@
+package gitconfig
+
+import "strings"
+
+// conditionKind is the type of an includeIf condition.
+type conditionKind int
+
+const (
+	conditionUnsupported conditionKind = iota
+	conditionGitdir
+	conditionOnbranch
+)
+
+// includeCondition is a parsed includeIf condition like "gitdir/i:~/work/".
+type includeCondition struct {
+	kind    conditionKind
+	pattern string // the pattern after the colon, as written
+	fold    bool   // gitdir/i: matches case-insensitively
+
+	// branch is the compiled pattern of an onbranch condition, err is set
+	// if it is invalid
+	branch pattern
+	err    error
+}
+
+// conditionCache holds the parsed conditions keyed by their raw text, so
+// reloading unchanged configs doesn't parse and compile them again.
+var conditionCache = memo[*includeCondition]{max: maxCachedPatterns}
+
+// parseCondition parses the subsection of an includeIf section, e.g.
+// "gitdir:~/work/" or "onbranch:feat/*". Conditions of unknown kinds or
+// without a colon are unsupported. The result is cached and must not be
+// modified.
+func parseCondition(raw string) *includeCondition {
+	return conditionCache.get(raw, func(raw string) *includeCondition {
+		kind, p, found := strings.Cut(raw, ":")
+		if !found {
+			return &includeCondition{}
+		}
+
+		switch kind {
+		case "gitdir", "gitdir/i":
+			return &includeCondition{kind: conditionGitdir, pattern: p, fold: kind == "gitdir/i"}
+		case "onbranch":
+			// like for gitdir, a trailing slash matches everything below
+			pat := p
+			if strings.HasSuffix(pat, "/") {
+				pat += "**"
+			}
+			branch, err := compilePattern(pat)
+
+			return &includeCondition{kind: conditionOnbranch, pattern: p, branch: branch, err: err}
+		default:
+			return &includeCondition{}
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package gitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCondition(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		raw     string
+		kind    conditionKind
+		pattern string
+		fold    bool
+		invalid bool
+	}{
+		{raw: "gitdir:~/work/", kind: conditionGitdir, pattern: "~/work/"},
+		{raw: "gitdir/i:C:/projects/", kind: conditionGitdir, pattern: "C:/projects/", fold: true},
+		{raw: "onbranch:feat/*", kind: conditionOnbranch, pattern: "feat/*"},
+		{raw: "onbranch:[z-a]", kind: conditionOnbranch, pattern: "[z-a]", invalid: true},
+		{raw: "hasconfig:remote.*.url:https://example.com/**", kind: conditionUnsupported},
+		{raw: "gitdirx:/src/", kind: conditionUnsupported},
+		{raw: "gitdir", kind: conditionUnsupported},
+	} {
+		cond := parseCondition(tc.raw)
+		assert.Equal(t, tc.kind, cond.kind, tc.raw)
+		if tc.kind == conditionUnsupported {
+			continue
+		}
+		assert.Equal(t, tc.pattern, cond.pattern, tc.raw)
+		assert.Equal(t, tc.fold, cond.fold, tc.raw)
+		assert.Equal(t, tc.invalid, cond.err != nil, tc.raw)
+
+		// repeated loads re-use the parsed condition
+		assert.Same(t, cond, parseCondition(tc.raw), tc.raw)
+	}
+
+	assert.True(t, parseCondition("onbranch:feat/*").branch.Match("feat/x"))
+	assert.True(t, parseCondition("onbranch:feat/").branch.Match("feat/x/y"))
+}
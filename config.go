@@ -1,9 +1,10 @@
 package gitconfig
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"maps"
 	"os"
 	"path"
@@ -12,18 +13,17 @@ import (
 	"slices"
 	"strconv"
 	"strings"
-
-	"github.com/gopasspw/gopass/pkg/debug"
+	"sync"
+	"time"
 )
 
 var (
-	keyValueTpl     = "\t%s = %s%s"
-	keyTpl          = "\t%s%s"
 	reQuotedComment = regexp.MustCompile(`"[^"]*[#;][^"]*"`)
-	// "The variable names are case-insensitive, allow only alphanumeric characters and -, and must start with an alphabetic character."".
-	reValidKey = regexp.MustCompile(`^[a-z]+[a-z0-9-]*$`)
 
 	// CompatMode enables compatibility mode, which disables certain features like value unescaping.
+	// It is only the default for configs that don't have their own setting.
+	//
+	// Deprecated: Use Config.SetCompatMode or Configs.SetCompatMode instead.
 	CompatMode bool
 )
 
@@ -39,6 +39,7 @@ var (
 // - raw: Maintains the raw text representation for round-trip fidelity
 // - vars: Map of normalized keys to their values (may be multiple values per key)
 // - branch: Current git branch name (for onbranch conditionals)
+// - compare: How Set decides if a value is unchanged (see ValueComparison)
 //
 // Note: Config is not thread-safe. Concurrent access from multiple goroutines
 // is not supported. Callers must provide synchronization if needed.
@@ -56,6 +57,29 @@ type Config struct {
 	raw      strings.Builder
 	vars     map[string][]string
 	branch   string
+	gitDir   string // GIT_DIR for gitdir conditions, see Configs.GitEnv
+	compare  ValueComparison
+	issues   []parseIssue             // lines ignored while parsing
+	includes []string                 // paths of included files
+	origins  map[string][]valueOrigin // where each value was defined, parallel to vars
+	compat   *bool                    // per-instance CompatMode, nil to use the package default
+	level    *compatState             // see SetCompatLevel, nil for CompatLegacy
+	keys     KeyRules                 // how keys are canonicalized, see KeyRules
+	format   fileFormat               // line endings and BOM of the file, raw always uses "\n" without BOM
+
+	includeLimitReached bool               // some includes were skipped because of the include limit
+	skippedIncludes     []SkippedInclude   // includes that could not be read, see IncludeErrorPolicy
+	includeGraph        []IncludeNode      // the include directives found while loading, see Includes
+	loadedWith          *loadParams        // how the config was loaded, nil if it can not be reloaded
+	topLevelWrites      bool               // Set never writes to included files, see SetWriteToTopLevel
+	pendingIncludes     map[string]*Config // included files changed within a transaction, written when it succeeds
+
+	commentPrefix string               // starts generated comments, see SetCommentPrefix
+	loadedAt      time.Time            // when the file was loaded, see Configs.LastLoaded
+	stamps        map[string]fileStamp // the files as seen by Configs.LoadAll, see Configs.ChangedSinceLoad
+
+	coercionMu sync.RWMutex
+	coercions  map[coercionKey]coercion // cached typed values, see coerce
 }
 
 // IsEmpty returns true if the config is empty (no configuration loaded).
@@ -63,7 +87,8 @@ type Config struct {
 // An empty config is one that:
 // - Is nil
 // - Has no variables loaded
-// - Has no raw content (not just missing path reference)
+// - Has no raw content (not just missing path reference) and no values,
+// configs loaded without their raw text (see LoadConfigMapped) only have values
 //
 // This is used to distinguish between "not yet loaded" and "loaded but empty file".
 func (c *Config) IsEmpty() bool {
@@ -71,7 +96,7 @@ func (c *Config) IsEmpty() bool {
 		return true
 	}
 
-	if c.raw.Len() > 0 {
+	if c.raw.Len() > 0 || len(c.vars) > 0 {
 		return false
 	}
 
@@ -103,7 +128,7 @@ func (c *Config) Unset(key string) error {
 		return fmt.Errorf("%w: %s", ErrInvalidKey, key)
 	}
 
-	key = canonicalizeKey(key)
+	key = c.canonicalKey(key)
 
 	_, present := c.vars[key]
 	if !present {
@@ -111,9 +136,11 @@ func (c *Config) Unset(key string) error {
 	}
 
 	delete(c.vars, key)
+	delete(c.origins, key)
+	c.resetCoercions()
 
-	return c.rewriteRaw(key, "", func(fKey, key, value, comment, _ string) (string, bool) {
-		return "", true
+	return c.edit(func(d *document) {
+		d.remove(key)
 	})
 }
 
@@ -134,13 +161,59 @@ func (c *Config) Unset(key string) error {
 //	  fmt.Printf("Editor: %s\n", v)
 //	}
 func (c *Config) Get(key string) (string, bool) {
-	key = canonicalizeKey(key)
+	key = c.canonicalKey(key)
+	vs, found := c.vars[key]
+	if !found || len(vs) < 1 {
+		return "", false
+	}
+
+	return vs[c.valueIndex(len(vs))], true
+}
+
+// GetRaw returns the value Get would return exactly as it was written in
+// the file: quotes and escape sequences are not processed and a trailing
+// comment is kept. This is useful for values with many backslashes, like
+// Windows paths or regular expressions. Values that were not read from a
+// file (e.g. from the environment) are returned as is.
+//
+// Example:
+//
+//	// [core]
+//	//	pattern = "^a\\d+$" # digits
+//	v, _ := cfg.GetRaw("core.pattern") // "^a\\d+$" # digits
+func (c *Config) GetRaw(key string) (string, bool) {
+	key = c.canonicalKey(key)
+	vs, found := c.vars[key]
+	if !found || len(vs) < 1 {
+		return "", false
+	}
+
+	i := c.valueIndex(len(vs))
+	if raw := c.origin(key, i).raw; raw != "" {
+		return raw, true
+	}
+
+	return vs[i], true
+}
+
+// GetComment returns the inline comment of the line that defines the value
+// Get returns, without its delimiter. Set keeps the comment when it changes
+// the value.
+//
+// Example:
+//
+//	// editor = vim # until everyone knows emacs
+//	comment, _ := cfg.GetComment("core.editor") // "until everyone knows emacs"
+func (c *Config) GetComment(key string) (string, bool) {
+	key = c.canonicalKey(key)
 	vs, found := c.vars[key]
 	if !found || len(vs) < 1 {
 		return "", false
 	}
 
-	return vs[0], true
+	comment := c.origin(key, c.valueIndex(len(vs))).comment
+
+	return comment, comment != ""
 }
 
 // GetAll returns all values of the key.
@@ -162,7 +235,7 @@ func (c *Config) Get(key string) (string, bool) {
 //	  }
 //	}
 func (c *Config) GetAll(key string) ([]string, bool) {
-	key = canonicalizeKey(key)
+	key = c.canonicalKey(key)
 	vs, found := c.vars[key]
 	if !found {
 		return nil, false
@@ -171,6 +244,51 @@ func (c *Config) GetAll(key string) ([]string, bool) {
 	return vs, true
 }
 
+// GetAllRange returns up to limit values of the key, starting at offset.
+//
+// This is useful for keys with a large number of values (e.g. generated
+// insteadOf lists) where callers want to paginate instead of handling the
+// full slice. A negative limit returns all values after offset.
+//
+// Returns (values, true) if the key is found, (nil, false) otherwise.
+// If the offset is beyond the last value the returned slice is empty.
+//
+// Example:
+//
+//	page, ok := cfg.GetAllRange("url.git@example.com:.insteadof", 100, 50)
+func (c *Config) GetAllRange(key string, offset, limit int) ([]string, bool) {
+	vs, found := c.GetAll(key)
+	if !found {
+		return nil, false
+	}
+
+	return pageValues(vs, offset, limit), true
+}
+
+// CountValues returns the number of values of the key. It returns 0
+// if the key is not set.
+func (c *Config) CountValues(key string) int {
+	key = c.canonicalKey(key)
+
+	return len(c.vars[key])
+}
+
+// pageValues returns the window [offset, offset+limit) of vs. The result is
+// clipped so appending to it never modifies vs.
+func pageValues(vs []string, offset, limit int) []string {
+	offset = max(offset, 0)
+	if offset >= len(vs) {
+		return []string{}
+	}
+
+	end := len(vs)
+	if limit >= 0 && offset+limit < end {
+		end = offset + limit
+	}
+
+	return vs[offset:end:end]
+}
+
 // IsSet returns true if the key was set in this config.
 //
 // Returns true even if the value is empty string (unlike checking Get with ok).
@@ -181,7 +299,7 @@ func (c *Config) GetAll(key string) ([]string, bool) {
 //	  fmt.Println("Editor is configured")
 //	}
 func (c *Config) IsSet(key string) bool {
-	key = canonicalizeKey(key)
+	key = c.canonicalKey(key)
 	_, present := c.vars[key]
 
 	return present
@@ -190,13 +308,15 @@ func (c *Config) IsSet(key string) bool {
 // Set updates or adds a key in the config.
 //
 // Behavior:
-// - If the key exists, the first value is updated
+// - If the key exists, the first value is updated (the last with CompatOptions.LastValueWins)
 // - If the key doesn't exist, it's added to an existing section or a new section
+// - If the value is defined in an included file, that file is updated (see SetWriteToTopLevel)
 // - If possible, the underlying config file is written to disk
 // - Original formatting (comments, whitespace) is preserved where possible
 //
 // Errors:
 // - Returns error if readonly or key is invalid (missing section or key name)
+// - Returns error wrapping ErrInvalidValue if the value contains a line break or NUL
 // - Returns error if file write fails (but in-memory value may be set)
 //
 // This method normalizes the key (lowercase sections and key names) but preserves
@@ -208,9 +328,31 @@ func (c *Config) IsSet(key string) bool {
 //	  log.Fatal(err)
 //	}
 func (c *Config) Set(key, value string) error {
-	section, _, subkey := splitKey(key)
-	if section == "" || subkey == "" {
-		return fmt.Errorf("%w: %s", ErrInvalidKey, key)
+	return c.set(key, value, false)
+}
+
+// SetBare sets the key to a bare boolean, i.e. the key is written without
+// "=" and a value. Git reads such a key as true while an empty value
+// (as written by Set(key, "")) is false.
+//
+// Example:
+//
+//	// [core]
+//	//	bare
+//	err := cfg.SetBare("core.bare")
+func (c *Config) SetBare(key string) error {
+	return c.set(key, "", true)
+}
+
+func (c *Config) set(key, value string, bare bool) error {
+	if err := validateKey(key, c.keys); err != nil {
+		return err
+	}
+	if err := c.keys.checkKeyLimits(key); err != nil {
+		return err
+	}
+	if err := validateValue(value, c.unescapeValues()); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
 	}
 
 	// can't set env vars
@@ -220,14 +362,22 @@ func (c *Config) Set(key, value string) error {
 		return nil
 	}
 
+	// new lines keep the spelling of the key, like git does, but the
+	// values are stored under the canonical key
+	spelling := key
+	key = c.canonicalKey(key)
+
 	if c.vars == nil {
 		c.vars = make(map[string][]string, 16)
 	}
 
-	// already present at the same value, no need to rewrite the config
-	if vs, found := c.vars[key]; found {
-		if slices.Contains(vs, value) {
-			debug.V(1).Log("key %q with value %q already present. Not re-writing.", key, value)
+	// already present at the same value, no need to rewrite the config.
+	// Only the first (or last, see CompatOptions) value would be replaced,
+	// so that's the one to compare against.
+	if vs, found := c.vars[key]; found && len(vs) > 0 {
+		i := c.valueIndex(len(vs))
+		if c.compare.equal(vs[i], value) && c.origin(key, i).bare == bare {
+			debug.V(1).Log("key %q with value %q already present (%s). Not re-writing.", key, value, c.compare)
 
 			return nil
 		}
@@ -237,148 +387,341 @@ func (c *Config) Set(key, value string) error {
 	if vs == nil {
 		vs = make([]string, 1)
 	}
-	vs[0] = value
+	target := c.valueIndex(len(vs))
+
+	// a value from an include is written there first, so a failed write
+	// doesn't leave a value in memory that is not in any file
+	var included *docLine
+	if vo := c.origin(key, target); present && c.routesTo(vo) {
+		l, err := c.setIncluded(vo, key, value, bare)
+		switch {
+		case err == nil:
+			included = &l
+		case errors.Is(err, ErrInvalidOrigin) || errors.Is(err, fs.ErrNotExist):
+			debug.V(1).Log("can not update %s in %s, writing it to %s: %s", key, vo.path, c.path, err)
+		default:
+			return err
+		}
+	}
+
+	vs[target] = value
 	c.vars[key] = vs
+	c.resetCoercions()
+	if !present {
+		c.setOrigin(key, valueOrigin{path: c.path, bare: bare, raw: c.writtenRaw(value, bare)})
+	} else if vo := c.origins[key]; target < len(vo) {
+		vo[target].bare = bare
+		vo[target].raw = c.writtenRaw(value, bare)
+	}
 
 	debug.V(3).Log("set %q to %q", key, value)
 
+	if included != nil {
+		c.origins[key][target].raw = rawIfDifferent(included.rawValue(), value)
+
+		return nil
+	}
+
 	// a new key, insert it into an existing section, if any
 	if !present {
 		debug.V(3).Log("inserting value")
 
-		return c.insertValue(key, value)
+		return c.insertValue(spelling, value, bare)
 	}
 
 	debug.V(3).Log("updating value")
 
-	var updated bool
+	return c.edit(func(d *document) {
+		l, ok := d.update(key, target, value, bare)
+		if !ok {
+			// the value is not defined in this file, e.g. it is from an
+			// include, so it is added here
+			d.insert(spelling, value, bare)
+			if vo := c.origins[key]; target < len(vo) {
+				vo[target] = valueOrigin{path: c.path, bare: bare, raw: c.writtenRaw(value, bare)}
+			}
 
-	return c.rewriteRaw(key, value, func(fKey, sKey, value, comment, line string) (string, bool) {
-		if updated {
-			return line, false
+			return
+		}
+		// the comment of the line is kept, so it is part of the raw value
+		if vo := c.origins[key]; target < len(vo) {
+			vo[target].raw = rawIfDifferent(l.rawValue(), value)
 		}
-		updated = true
-
-		return formatKeyValue(sKey, value, comment), false
 	})
 }
 
-func (c *Config) insertValue(key, value string) error {
-	debug.V(3).Log("input (%s: %s): \n--------------\n%s\n--------------\n", key, value, strings.Join(strings.Split("- "+c.raw.String(), "\n"), "\n- "))
-
-	wSection, wSubsection, wKey := splitKey(key)
-
-	s := bufio.NewScanner(strings.NewReader(c.raw.String()))
-
-	lines := make([]string, 0, 128)
-	var section string
-	var subsection string
-	var written bool
-	for s.Scan() {
-		line := s.Text()
+// SetWriteToTopLevel controls where Set writes keys that are defined in an
+// included file. By default the included file is updated, so the change is
+// not shadowed by a duplicate in the top-level file. If enabled, Set adds
+// the key to the top-level file instead, which then overrides the include.
+func (c *Config) SetWriteToTopLevel(enabled bool) {
+	c.topLevelWrites = enabled
+}
 
-		lines = append(lines, line)
+// routesTo reports whether Set writes the value defined at vo to the
+// included file it comes from.
+func (c *Config) routesTo(vo valueOrigin) bool {
+	return !c.topLevelWrites && vo.line > 0 && vo.path != "" && vo.path != c.path &&
+		slices.Contains(c.includes, vo.path)
+}
 
-		if written {
-			continue
-		}
-		if strings.HasPrefix(line, "#") {
-			continue
+// setIncluded updates the value defined at vo in an included file and
+// writes that file. Within a transaction the file is written when the
+// transaction succeeds and with NoWrites it is not written at all. It fails
+// with ErrInvalidOrigin if the line does not define the key anymore, e.g.
+// because the file was changed since loading.
+func (c *Config) setIncluded(vo valueOrigin, key, value string, bare bool) (docLine, error) {
+	var err error
+	inc := c.pendingIncludes[vo.path]
+	if inc == nil {
+		inc, err = loadConfig(vo.path, parseOptions{compat: c.compat, level: c.level, keys: c.keys})
+		if err != nil {
+			return docLine{}, fmt.Errorf("%w: %w", ErrWriteConfig, err)
 		}
-		if strings.HasPrefix(line, ";") {
-			continue
+		inc.noWrites = c.noWrites
+	}
+
+	var updated docLine
+	err = inc.editLines(func(d *document) error {
+		i, err := inc.lineAt(d, Origin{Path: vo.path, Line: vo.line})
+		if err != nil {
+			return err
 		}
-		if strings.HasPrefix(line, "[") {
-			s, subs, skip := parseSectionHeader(line)
-			if skip {
-				continue
-			}
-			section = s
-			subsection = subs
+		if d.lines[i].kind != lineKeyValue || d.lines[i].key != key {
+			return fmt.Errorf("%w: line %d of %s does not define %s", ErrInvalidOrigin, vo.line, vo.path, key)
 		}
+		updated = d.updateLine(i, value, bare)
 
-		if section != wSection {
-			continue
-		}
-		if subsection != wSubsection {
-			continue
+		return nil
+	})
+	if err != nil {
+		return docLine{}, err
+	}
+	if c.pendingIncludes != nil {
+		c.pendingIncludes[vo.path] = inc
+	} else if c.stamps != nil && !inc.noWrites {
+		c.stamps[vo.path] = statFile(vo.path)
+	}
+
+	return updated, nil
+}
+
+// writtenRaw returns the raw text docLine.format writes for the value, or
+// "" if it is written as is.
+func (c *Config) writtenRaw(value string, bare bool) string {
+	if bare || !c.unescapeValues() {
+		return ""
+	}
+
+	return rawIfDifferent(QuoteValue(value), value)
+}
+
+// SetValueComparison sets the comparison mode used by Set to decide
+// whether a value is unchanged and the config doesn't need to be rewritten.
+// The default is CompareExact.
+func (c *Config) SetValueComparison(vc ValueComparison) {
+	c.compare = vc
+}
+
+// transaction runs fn with disk writes suspended and persists the result
+// with a single write afterwards, after the included files changed by fn
+// (see setIncluded). If fn fails all in-memory changes are rolled back and
+// nothing is written.
+func (c *Config) transaction(fn func() error) error {
+	raw := c.raw.String()
+	var vars map[string][]string
+	if c.vars != nil {
+		vars = make(map[string][]string, len(c.vars))
+		for k, vs := range c.vars {
+			vars[k] = slices.Clone(vs)
 		}
+	}
+	origins := cloneOrigins(c.origins)
+	noWrites := c.noWrites
+
+	c.noWrites = true
+	c.pendingIncludes = map[string]*Config{}
+	err := fn()
+	c.noWrites = noWrites
+	included := c.pendingIncludes
+	c.pendingIncludes = nil
+
+	if err != nil {
+		c.raw = strings.Builder{}
+		c.raw.WriteString(raw)
+		c.vars = vars
+		c.origins = origins
+		c.resetCoercions()
 
-		lines = append(lines, formatKeyValue(wKey, value, ""))
-		written = true
+		return err
 	}
 
-	// not added to an existing section, so add it at the end
-	if !written {
-		sect := fmt.Sprintf("[%s]", wSection)
-		if wSubsection != "" {
-			sect = fmt.Sprintf("[%s \"%s\"]", wSection, wSubsection)
+	for _, p := range slices.Sorted(maps.Keys(included)) {
+		inc := included[p]
+		inc.noWrites = c.noWrites
+		if err := inc.flushRaw(); err != nil {
+			return err
+		}
+		if c.stamps != nil && !c.noWrites {
+			c.stamps[p] = statFile(p)
 		}
-		lines = append(lines, sect)
-		lines = append(lines, formatKeyValue(wKey, value, ""))
 	}
 
+	if c.raw.String() == raw {
+		return nil
+	}
+
+	return c.flushRaw()
+}
+
+func (c *Config) insertValue(key, value string, bare bool) error {
+	return c.edit(func(d *document) {
+		d.insert(key, value, bare)
+	})
+}
+
+// edit applies fn to the document of the raw config and persists the result.
+func (c *Config) edit(fn func(d *document)) error {
+	debug.V(3).Log("input: \n--------------\n%s\n--------------\n", strings.Join(strings.Split("- "+c.raw.String(), "\n"), "\n- "))
+
+	d := parseDocument(strings.NewReader(c.raw.String()), c.unescapeValues(), c.keys)
+	fn(d)
+
 	c.raw = strings.Builder{}
-	c.raw.WriteString(strings.Join(lines, "\n"))
-	c.raw.WriteString("\n")
+	c.raw.WriteString(d.String())
 
 	debug.V(3).Log("output: \n--------------\n%s\n--------------\n", strings.Join(strings.Split("+ "+c.raw.String(), "\n"), "\n+ "))
 
 	return c.flushRaw()
 }
 
-// formatKeyValue formats a configuration key-value pair for writing to file.
-// If the value is empty or whitespace-only, only the key is written.
-// The comment parameter preserves any trailing comment from the original line.
-func formatKeyValue(key, value, comment string) string {
-	if strings.TrimSpace(value) == "" {
-		return fmt.Sprintf(keyTpl, key, comment)
+// validateValue rejects values that would break the structure of the
+// config file when written. Newlines are escaped (see QuoteValue) unless
+// value unescaping is disabled, carriage returns and NUL can not be written.
+func validateValue(value string, unescape bool) error {
+	if strings.ContainsAny(value, "\r\x00") || (!unescape && strings.Contains(value, "\n")) {
+		return fmt.Errorf("%w: value %q contains a line break or NUL", ErrInvalidValue, value)
+	}
+
+	return nil
+}
+
+// sectionHeader returns the "[section "subsection"]" part of a trimmed line
+// if the line is structurally a section header, i.e. it starts with "[",
+// the bracket is closed outside of quotes and at most a comment follows.
+func sectionHeader(line string) (string, bool) {
+	if !strings.HasPrefix(line, "[") {
+		return "", false
+	}
+
+	inQuotes := false
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inQuotes {
+				i++ // skip the escaped character
+			}
+		case '"':
+			inQuotes = !inQuotes
+		case ']':
+			if inQuotes {
+				continue
+			}
+			rest := strings.TrimSpace(line[i+1:])
+			if rest != "" && rest[0] != '#' && rest[0] != ';' {
+				return "", false
+			}
+
+			return line[:i+1], true
+		}
 	}
 
-	return fmt.Sprintf(keyValueTpl, key, value, comment)
+	return "", false
 }
 
 // parseSectionHeader extracts the section and subsection from a config file section header line.
 // For example:
 //
-//	"[core]" returns ("core", "", false)
-//	"[remote \"origin\"]" returns ("remote", "origin", false)
+//	"[Core]" returns ("core", "", false)
+//	"[remote \"Origin\"]" returns ("remote", "Origin", false)
+//	"[Remote.Origin]" returns ("remote", "origin", false)
 //	"[]" returns ("", "", true) to indicate skip
 //
-// The skip return value indicates whether this line should be ignored.
-func parseSectionHeader(line string) (section, subsection string, skip bool) { //nolint:nonamedreturns
-	line = strings.Trim(line, "[]")
+// With git's KeyRules the section is lower-cased. The subsection is
+// unescaped, see unescapeSubsection, and lower-cased only in the deprecated
+// dotted syntax.
+// The skip return value indicates whether this line should be ignored
+// because it is empty or git would refuse it, e.g. for an invalid section
+// name or an unquoted subsection.
+func parseSectionHeader(line string, keys KeyRules) (section, subsection string, skip bool) { //nolint:nonamedreturns
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
 	if line == "" {
 		return "", "", true
 	}
-	wsp := strings.Index(line, " ")
+	wsp := strings.IndexAny(line, " \t")
 	if wsp < 0 {
-		return line, "", false
+		// the deprecated [section.subsection] syntax, git folds the
+		// subsection as well
+		if !validSectionName(line) || line[0] == '.' {
+			return "", "", true
+		}
+		section, subsection, _ = strings.Cut(line, ".")
+
+		return keys.section(section), keys.subsection(strings.ToLower(subsection)), false
 	}
 
-	section = line[:wsp]
-	subsection = line[wsp+1:]
-	subsection = strings.ReplaceAll(subsection, "\\", "")
-	subsection = strings.TrimPrefix(subsection, "\"")
-	subsection = strings.TrimSuffix(subsection, "\"")
+	// "Section names are case-insensitive", subsections are not. Like git
+	// we accept any whitespace before the subsection, which must be quoted
+	// and directly followed by the closing bracket.
+	if !validSectionName(line[:wsp]) {
+		return "", "", true
+	}
+	rest := strings.TrimLeft(line[wsp:], " \t")
+	if len(rest) < 2 || rest[0] != '"' || rest[len(rest)-1] != '"' {
+		return "", "", true
+	}
+	section = keys.section(line[:wsp])
+	subsection = rest[1 : len(rest)-1]
 
-	return section, subsection, false
+	return section, keys.subsection(unescapeSubsection(subsection)), false
 }
 
-// rewriteRaw is used to rewrite the raw config copy. It is used for set and unset operations
-// with different callbacks each.
-func (c *Config) rewriteRaw(key, value string, cb parseFunc) error {
-	debug.V(3).Log("input (%s: %s): \n--------------\n%s\n--------------\n", key, value, strings.Join(strings.Split("- "+c.raw.String(), "\n"), "\n- "))
+// unescapeSubsection processes the escape sequences of a quoted subsection
+// name. Like git, a backslash escapes the following character, so \" and
+// \\ stand for a double quote and a backslash. Backslashes before any other
+// character are dropped, e.g. \t is read as t.
+func unescapeSubsection(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
 
-	lines := parseConfig(strings.NewReader(c.raw.String()), key, value, cb)
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
 
-	c.raw = strings.Builder{}
-	c.raw.WriteString(strings.Join(lines, "\n"))
-	c.raw.WriteString("\n")
+	return sb.String()
+}
 
-	debug.V(3).Log("output: \n--------------\n%s\n--------------\n", strings.Join(strings.Split("+ "+c.raw.String(), "\n"), "\n+ "))
+// QuoteSubsection returns the subsection name in double quotes, with
+// backslashes and double quotes escaped, as it must appear in a section
+// header. Git and this package read the name back unchanged. Note that a
+// subsection name can not contain newlines or NUL bytes.
+//
+// Example:
+//
+//	hdr := "[remote " + gitconfig.QuoteSubsection(`my "fork"`) + "]"
+//	// hdr is [remote "my \"fork\""]
+func QuoteSubsection(s string) string {
+	if !strings.ContainsAny(s, "\\\"") {
+		return `"` + s + `"`
+	}
 
-	return c.flushRaw()
+	return `"` + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(s) + `"`
 }
 
 func (c *Config) flushRaw() error {
@@ -394,125 +737,100 @@ func (c *Config) flushRaw() error {
 
 	debug.V(3).Log("writing config to %s: \n--------------\n%s\n--------------", c.path, c.raw.String())
 
-	if err := os.WriteFile(c.path, []byte(c.raw.String()), 0o600); err != nil {
+	if err := os.WriteFile(c.path, []byte(c.format.apply(c.raw.String())), 0o600); err != nil {
 		return fmt.Errorf("%w: %s: %w", ErrWriteConfig, c.path, err)
 	}
 
 	debug.V(1).Log("wrote config to %s", c.path)
+	if c.stamps != nil {
+		c.stamps[c.path] = statFile(c.path)
+	}
 
 	return nil
 }
 
-type parseFunc func(fqkn, skn, value, comment, fullLine string) (newLine string, skipLine bool)
-
-// parseConfig implements a simple parser for the gitconfig subset we support.
-// The idea is to save all lines unaltered so we can reproduce the config
-// almost exactly. Then we skip comments and extract section and subsection
-// header. The next steps depend on the mode. Either we want to extract the
-// values when loading (key and value empty, parseFunc adds the key-value pairs
-// to the vars map), update a key (key is the target key, value the new value)
-// or delete a key (parseFunc returns skip).
-func parseConfig(in io.Reader, key, value string, cb parseFunc) []string {
-	wSection, wSubsection, wKey := splitKey(key)
-
-	s := bufio.NewScanner(in)
-
-	lines := make([]string, 0, 128)
-	var section string
-	var subsection string
-	for s.Scan() {
-		fullLine := s.Text()
-
-		lines = append(lines, fullLine)
-
-		line := strings.TrimSpace(fullLine)
-		// Handle full-line comments
-		if strings.HasPrefix(line, "#") {
-			continue
-		}
-		if strings.HasPrefix(line, ";") {
-			continue
-		}
+// hasOpenQuote reports whether the value of the key-value pair on line has
+// a double quote that is not closed by the end of the line.
+func hasOpenQuote(line string) bool {
+	_, v, found := strings.Cut(line, "=")
+	if !found || !strings.Contains(v, `"`) {
+		return false
+	}
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") || strings.HasPrefix(trimmed, "[") {
+		return false
+	}
 
-		// Handle section headers
-		if strings.HasPrefix(line, "[") {
-			s, subs, skip := parseSectionHeader(line)
-			if skip {
-				continue
+	inQuotes := false
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			i++ // skip the escaped character
+		case '"':
+			inQuotes = !inQuotes
+		case '#', ';':
+			if !inQuotes {
+				return false
 			}
-			section = s
-			subsection = subs
-		}
-
-		if key != "" && (section != wSection && subsection != wSubsection) {
-			continue
-		}
-
-		// Reference: https://git-scm.com/docs/git-config#_syntax.
-		k, v, found := strings.Cut(line, "=")
-		// This is a special case for bare booleans.
-		if !found && !strings.HasPrefix(line, "[") && strings.TrimSpace(line) != "" {
-			v = ""
-			found = true
-		}
-		if !found {
-			debug.V(3).Log("no valid KV-pair on line: %q", line)
-
-			continue
-		}
-		// Remove whitespace from key and value that might be around the '='
-		// "Whitespace characters surrounding name, = and value are discarded."
-		// https://git-scm.com/docs/git-config#_syntax
-		k = strings.TrimSpace(k)
-		v = strings.TrimSpace(v)
-
-		// keep a copy of the original key for serialization.
-		ok := k
-		// "The variable names are case-insensitive"
-		k = strings.ToLower(k)
-
-		if !reValidKey.MatchString(k) {
-			debug.V(3).Log("invalid key %q in line: %q", k, line)
-
-			continue
 		}
+	}
 
-		fKey := section + "."
-		if subsection != "" {
-			fKey += subsection + "."
-		}
-		fKey += k
-		if key == "" {
-			wKey = ok
-		}
+	return inQuotes
+}
 
-		// extract possilbe comment from the value
-		oValue, comment := splitValueComment(v)
+// validateKey checks the section, subsection and name of a key against
+// git's rules, so Set never writes a file git refuses to read.
+func validateKey(key string, keys KeyRules) error {
+	section, subsection, name := splitKey(key)
+	if section == "" || name == "" {
+		return fmt.Errorf("%w: %s", ErrInvalidKey, key)
+	}
+	if !validSectionName(section) {
+		return fmt.Errorf("%w: invalid section name %q in %s", ErrInvalidKey, section, key)
+	}
+	// "Subsection names are case sensitive and can contain any characters
+	// except newline and the null byte."
+	if strings.ContainsAny(subsection, "\n\x00") {
+		return fmt.Errorf("%w: invalid subsection name %q in %s", ErrInvalidKey, subsection, key)
+	}
+	if !keys.validName(name) {
+		return fmt.Errorf("%w: invalid variable name %q in %s", ErrInvalidKey, name, key)
+	}
 
-		// unescape value
-		if !CompatMode {
-			oValue = unescapeValue(oValue)
-		}
+	return nil
+}
 
-		if key != "" && (key != fKey) {
-			continue
-		}
-		if key != "" {
-			oValue = value
+// validSectionName reports whether the section name is valid.
+// "Only alphanumeric characters, - and . are allowed in section names."
+func validSectionName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := range len(s) {
+		c := s[i]
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' && c != '.' {
+			return false
 		}
+	}
 
-		newLine, skip := cb(fKey, wKey, oValue, comment, fullLine)
-		debug.V(3).Log("parsed line: %q -> %q, skip: %t", fullLine, newLine, skip)
-		if skip {
-			// remove the last line
-			lines = lines[:len(lines)-1]
+	return true
+}
 
-			continue
+// validKeyName reports whether the lower-cased variable name is valid.
+// "The variable names are case-insensitive, allow only alphanumeric
+// characters and -, and must start with an alphabetic character."
+func validKeyName(k string) bool {
+	if k == "" || k[0] < 'a' || k[0] > 'z' {
+		return false
+	}
+	for i := 1; i < len(k); i++ {
+		c := k[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
 		}
-		lines[len(lines)-1] = newLine
 	}
 
-	return lines
+	return true
 }
 
 // splitValueComment separates a config value from any trailing comment.
@@ -538,20 +856,193 @@ func splitValueComment(rValue string) (string, string) {
 	}
 
 	// Hard case: comment present and quoted.
-	return parseLineForComment(rValue)
+	value, _ := parseLineForComment(rValue)
+	if i := unquotedIndexAny(rValue, "#;"); i >= 0 {
+		return value, " " + rValue[i:]
+	}
+
+	return value, ""
+}
+
+// splitValue separates a raw config value from any trailing comment. Quotes
+// and escape sequences are processed like git does, unless disabled (see
+// CompatOptions.UnescapeValues).
+func splitValue(rValue string, unescape bool) (string, string) {
+	if !unescape {
+		return splitValueComment(rValue)
+	}
+
+	return parseValue(rValue)
+}
+
+// parseValue parses a trimmed raw value like git does: double quotes are
+// removed, escape sequences are processed, whitespace outside of quotes is
+// turned into single spaces and an unquoted # or ; starts a comment. The
+// comment is returned with its delimiter and a leading space so it can be
+// appended to a rewritten line.
+func parseValue(rValue string) (string, string) {
+	// fast path: nothing to process
+	if !strings.ContainsAny(rValue, "\"\\#;\t") {
+		return rValue, ""
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(rValue))
+
+	inQuotes := false
+	spaces := 0
+	for i := 0; i < len(rValue); i++ {
+		c := rValue[i]
+		if !inQuotes && (c == ' ' || c == '\t') {
+			if sb.Len() > 0 {
+				spaces++
+			}
+
+			continue
+		}
+		if !inQuotes && (c == '#' || c == ';') {
+			return sb.String(), " " + rValue[i:]
+		}
+		for ; spaces > 0; spaces-- {
+			sb.WriteByte(' ')
+		}
+
+		switch c {
+		case '\\':
+			if i+1 < len(rValue) {
+				if r, ok := unescapeChar(rValue[i+1]); ok {
+					sb.WriteByte(r)
+					i++
+
+					continue
+				}
+			}
+			// unknown escape sequences are kept as they are
+			sb.WriteByte(c)
+		case '"':
+			inQuotes = !inQuotes
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return sb.String(), ""
+}
+
+// invalidEscapes returns the offsets of all escape sequences in a trimmed
+// raw value that git does not know, e.g. "\q". Git refuses to load such a
+// value, while parseValue keeps the sequence as it is. Comments are skipped.
+func invalidEscapes(rValue string) []int {
+	if !strings.Contains(rValue, `\`) {
+		return nil
+	}
+
+	var out []int
+	inQuotes := false
+	for i := 0; i < len(rValue); i++ {
+		switch c := rValue[i]; {
+		case !inQuotes && (c == '#' || c == ';'):
+			return out
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == '\\' && i+1 < len(rValue):
+			if _, ok := unescapeChar(rValue[i+1]); !ok {
+				out = append(out, i)
+			}
+			i++
+		}
+	}
+
+	return out
 }
 
 // unescapeValue processes escape sequences in configuration values.
 // Supports: \\, \", \n (newline), \t (tab), \b (backspace).
-// Other escape sequences (including octal) are not supported per Git config spec.
+// Other escape sequences (including octal) are not supported per Git config spec
+// and are kept as they are.
 func unescapeValue(value string) string {
-	value = strings.ReplaceAll(value, `\\`, `\`)
-	value = strings.ReplaceAll(value, `\"`, `"`)
-	value = strings.ReplaceAll(value, `\n`, "\n")
-	value = strings.ReplaceAll(value, `\t`, "\t")
-	value = strings.ReplaceAll(value, `\b`, "\b")
+	if !strings.Contains(value, `\`) {
+		return value
+	}
 
-	return value
+	var sb strings.Builder
+	sb.Grow(len(value))
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			if r, ok := unescapeChar(value[i+1]); ok {
+				sb.WriteByte(r)
+				i++
+
+				continue
+			}
+		}
+		sb.WriteByte(value[i])
+	}
+
+	return sb.String()
+}
+
+// unescapeChar returns the character for the escape sequence "\c".
+func unescapeChar(c byte) (byte, bool) {
+	switch c {
+	case 'n':
+		return '\n', true
+	case 't':
+		return '\t', true
+	case 'b':
+		return '\b', true
+	case '"', '\\':
+		return c, true
+	default:
+		return 0, false
+	}
+}
+
+// QuoteValue escapes a value so git and this package read it back unchanged.
+// Backslashes, double quotes, newlines, tabs and backspaces are escaped. The
+// value is put in double quotes if it has leading or trailing whitespace or
+// contains a comment character. Use it to generate config snippets outside
+// of this package, e.g. from templates.
+//
+// Example:
+//
+//	line := "\tmessage = " + gitconfig.QuoteValue(" hello # world")
+//	// line is \tmessage = " hello # world"
+func QuoteValue(value string) string {
+	if value == "" {
+		return value
+	}
+
+	quote := strings.TrimSpace(value) != value || strings.ContainsAny(value, "#;")
+	if !quote && !strings.ContainsAny(value, "\\\"\n\t\b") {
+		return value
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(value) + 2)
+	if quote {
+		sb.WriteByte('"')
+	}
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\', '"':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\b':
+			sb.WriteString(`\b`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	if quote {
+		sb.WriteByte('"')
+	}
+
+	return sb.String()
 }
 
 // NewFromMap allows creating a new preset config from a map.
@@ -562,6 +1053,9 @@ func NewFromMap(data map[string]string) *Config {
 	}
 
 	for k, v := range data {
+		if ck := canonicalizeKey(k); ck != "" {
+			k = ck
+		}
 		c.vars[k] = []string{v}
 	}
 
@@ -570,13 +1064,13 @@ func NewFromMap(data map[string]string) *Config {
 
 // LoadConfig tries to load a gitconfig from the given path.
 func LoadConfig(fn string) (*Config, error) {
-	return loadConfigs(fn, "")
+	return loadConfigs(fn, "", parseOptions{})
 }
 
 // LoadConfigWithWorkdir tries to load a gitconfig from the given path and
 // a workdir. The workdir is used to resolve relative paths in the config.
 func LoadConfigWithWorkdir(fn, workdir string) (*Config, error) {
-	c, err := loadConfigs(fn, workdir)
+	c, err := loadConfigs(fn, workdir, parseOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -584,140 +1078,264 @@ func LoadConfigWithWorkdir(fn, workdir string) (*Config, error) {
 	return c, nil
 }
 
-func readGitBranch(workdir string) string {
-	if workdir == "" {
-		return ""
-	}
-	gitDir := filepath.Join(workdir, ".git")
-	// check if .git is a directory
-	if fi, err := os.Stat(gitDir); err != nil || !fi.IsDir() {
-		// it might be a file with gitdir: path, not handled for now
-		return ""
+// loadParams are the arguments a config was loaded with, see Reload.
+type loadParams struct {
+	workdir string
+	opts    parseOptions
+}
+
+// Reload reads the config file and its includes again, e.g. after they
+// were modified externally. The branch is read again from the workdir the
+// config was loaded with, unless it was set with SetBranch, and all
+// includeIf conditions are evaluated again. Only configs loaded with
+// LoadConfig or LoadConfigWithWorkdir can be reloaded. If loading fails the
+// config is left unchanged.
+//
+// Example:
+//
+//	c, _ := gitconfig.LoadConfigWithWorkdir("~/.gitconfig", "/path/to/repo")
+//	// ... git checkout other-branch ...
+//	if err := c.Reload(); err != nil { ... }
+func (c *Config) Reload() error {
+	if c == nil || c.loadedWith == nil {
+		return fmt.Errorf("%w: config was not loaded from a file", ErrNoConfigFile)
 	}
 
-	headFile := filepath.Join(gitDir, "HEAD")
-	content, err := os.ReadFile(headFile)
+	nc, err := loadConfigs(c.path, c.loadedWith.workdir, c.loadedWith.opts)
 	if err != nil {
-		return ""
+		return err
+	}
+	c.replace(nc)
+
+	return nil
+}
+
+// SetBranch sets the branch that onbranch conditions are matched against,
+// instead of reading it from HEAD in the workdir, and reloads the config to
+// evaluate its includeIf conditions again. This is useful if the caller
+// already knows the branch, e.g. in a hook. An empty name reads the branch
+// from the workdir again. If reloading fails the config is left unchanged.
+func (c *Config) SetBranch(name string) error {
+	if c == nil || c.loadedWith == nil {
+		return fmt.Errorf("%w: config was not loaded from a file", ErrNoConfigFile)
+	}
+
+	prev := c.loadedWith.opts.branch
+	c.loadedWith.opts.branch = name
+	if err := c.Reload(); err != nil {
+		c.loadedWith.opts.branch = prev
+
+		return err
 	}
 
-	// content is like "ref: refs/heads/main"
-	if branch, found := strings.CutPrefix(string(content), "ref: refs/heads/"); found {
-		return strings.TrimSpace(branch)
+	return nil
+}
+
+// Branch returns the branch that onbranch conditions were matched against,
+// or "" if it is not known, e.g. because HEAD is detached.
+func (c *Config) Branch() string {
+	if c == nil {
+		return ""
 	}
 
-	return "" // detached HEAD or other cases
+	return c.branch
 }
 
-// getEffectiveIncludes returns all include paths from the config, combining
-// basic [include] directives with conditional [includeIf] directives.
-// The workdir parameter is used to evaluate conditional includes.
-func getEffectiveIncludes(c *Config, workdir string) ([]string, bool) {
-	includePaths, includeExists := c.GetAll("include.path")
+// replace replaces the content of c with the freshly loaded nc, keeping the
+// settings of c like SetValueComparison or SetCommentPrefix.
+func (c *Config) replace(nc *Config) {
+	c.raw.Reset()
+	c.raw.WriteString(nc.raw.String())
+	c.vars = nc.vars
+	c.origins = nc.origins
+	c.format = nc.format
+	c.branch = nc.branch
+	c.gitDir = nc.gitDir
+	c.issues = nc.issues
+	c.includes = nc.includes
+	c.includeLimitReached = nc.includeLimitReached
+	c.skippedIncludes = nc.skippedIncludes
+	c.includeGraph = nc.includeGraph
+	c.loadedWith = nc.loadedWith
+	c.loadedAt = nc.loadedAt
+	c.resetCoercions()
+}
 
-	if cIncludes := getConditionalIncludes(c, workdir); len(cIncludes) > 0 {
-		includePaths = append(includePaths, cIncludes...)
-		includeExists = true
+// maxSymrefDepth limits how many symbolic refs are followed, like git's
+// SYMREF_MAXDEPTH.
+const maxSymrefDepth = 5
+
+// readGitBranch returns the branch checked out in the worktree at workdir,
+// or "" if it can not be determined. Every worktree has its own HEAD, see
+// worktreeGitDir, GIT_DIR and GIT_COMMON_DIR in env take precedence. Like git, HEAD is followed through symbolic refs (see
+// headRef) and the branch does not need to exist yet, e.g. in a new
+// repository. A detached HEAD is on no branch, so onbranch conditions never
+// match it.
+func readGitBranch(workdir string, env repoEnv) string {
+	gitDir := env.gitDirFor(workdir)
+	if gitDir == "" {
+		return ""
 	}
 
-	return includePaths, includeExists
+	ref, ok := headRef(gitDir, env.commonDirFor(gitDir))
+	if !ok {
+		debug.V(3).Log("HEAD of %s is detached or invalid", gitDir)
+
+		return ""
+	}
+
+	branch, found := strings.CutPrefix(ref, "refs/heads/")
+	if !found {
+		debug.V(3).Log("HEAD of %s points to %s, which is not a branch", gitDir, ref)
+
+		return ""
+	}
+
+	return branch
 }
 
-// getConditionalIncludes processes [includeIf "condition"] directives and returns
-// paths that match the current environment.
-// Supported conditions:
-//   - gitdir:<pattern> - Include if git directory matches pattern (case-sensitive)
-//   - gitdir/i:<pattern> - Include if git directory matches pattern (case-insensitive)
-func getConditionalIncludes(c *Config, workdir string) []string {
-	candidates := []string{}
-	for k := range c.vars {
-		debug.V(3).Log("found config key: %q", k)
-		// must have the form includeIf.<condition>.path
-		// e.g. includeIf."gitdir:/path/to/group/".path
-		// see https://git-scm.com/docs/git-config#_conditional_includes
-		if !strings.HasPrefix(k, "includeif.") || !strings.HasSuffix(k, ".path") {
-			continue
+// headRef returns the name of the ref HEAD of the git directory points to,
+// following symbolic refs like "ref: refs/heads/main" (or symlinks, as used
+// by old versions of git). Refs other than HEAD are looked up in the common
+// directory of linked worktrees (see commonGitDir). A ref that does not exist
+// as a loose file, e.g. an unborn branch or a packed ref, ends the chain.
+// It returns false for a detached HEAD, i.e. one that contains an object id.
+func headRef(gitDir, common string) (string, bool) {
+	name := "HEAD"
+	for range maxSymrefDepth {
+		dir := common
+		if name == "HEAD" {
+			dir = gitDir
 		}
-		candidates = append(candidates, k)
-	}
+		fn := filepath.Join(dir, filepath.FromSlash(name))
 
-	out := make([]string, 0, len(candidates))
-	for _, k := range filterCandidates(candidates, workdir, c) {
-		path, found := c.GetAll(k)
-		if !found {
-			debug.V(3).Log("skipping include candidate %q, no path found", k)
+		if target, err := os.Readlink(fn); err == nil && strings.HasPrefix(filepath.ToSlash(target), "refs/") {
+			name = filepath.ToSlash(target)
 
 			continue
 		}
-		out = append(out, path...)
+
+		content, err := os.ReadFile(fn)
+		if err != nil {
+			return name, name != "HEAD"
+		}
+		target, found := strings.CutPrefix(strings.TrimSpace(string(content)), "ref:")
+		if !found {
+			// an object id
+			return name, name != "HEAD"
+		}
+		name = strings.TrimSpace(target)
 	}
 
-	return out
+	debug.V(1).Log("too many levels of symbolic refs in %s", gitDir)
+
+	return "", false
 }
 
-// filterCandidates filters the candidates for include paths.
-// Currently only the gitdir condition is supported.
-// Others might be added in the future.
-func filterCandidates(candidates []string, workdir string, c *Config) []string {
-	out := make([]string, 0, len(candidates))
-	for _, candidate := range candidates {
-		sec, subsec, key := splitKey(candidate)
-		if sec != "includeif" || subsec == "" || key != "path" {
-			debug.V(3).Log("skipping invalid include candidate %q", candidate)
+// commonGitDir returns the directory shared by all worktrees of the
+// repository of gitDir. Linked worktrees point to it with a "commondir"
+// file, relative to gitDir, for the main worktree it is gitDir itself.
+func commonGitDir(gitDir string) string {
+	content, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
 
-			continue
+	dir := filepath.FromSlash(strings.TrimSpace(string(content)))
+	if dir == "" {
+		return gitDir
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+
+	return filepath.Clean(dir)
+}
+
+// worktreeGitDir returns the git directory of the worktree at workdir. In
+// a linked worktree .git is a file with a "gitdir: <path>" line pointing to
+// .git/worktrees/<name> of the main repository. The workdir may also be a
+// git directory itself. It returns "" if no git directory is found.
+func worktreeGitDir(workdir string) string {
+	if workdir == "" {
+		return ""
+	}
+
+	gitDir := filepath.Join(workdir, ".git")
+	fi, err := os.Stat(gitDir)
+	switch {
+	case err == nil && fi.IsDir():
+		return gitDir
+	case err == nil:
+		content, err := os.ReadFile(gitDir)
+		if err != nil {
+			debug.V(1).Log("failed to read %s: %s", gitDir, err)
+
+			return ""
 		}
+		target, found := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+		if !found {
+			debug.V(1).Log("%s is not a gitdir file", gitDir)
 
-		if matchSubSection(subsec, workdir, c) {
-			out = append(out, candidate)
+			return ""
+		}
+		target = filepath.FromSlash(strings.TrimSpace(target))
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(workdir, target)
 		}
+
+		return filepath.Clean(target)
 	}
 
-	return out
+	// workdir might be a git directory, e.g. when the local config is
+	// loaded as "config" relative to it
+	if _, err := os.Stat(filepath.Join(workdir, "HEAD")); err == nil {
+		return workdir
+	}
+
+	return ""
 }
 
 // matchSubSection determines if a subsection condition matches the current environment.
-// Handles gitdir, gitdir/i, onbranch, and other condition types.
+// Handles gitdir, gitdir/i, onbranch, and other condition types. The
+// condition is parsed once and cached, see parseCondition.
+// The gitdir conditions are matched against all of conditionDirs, ignoring
+// case for directories on a case-insensitive filesystem (see caseInsensitiveFS).
 // Returns true if the condition matches and the config should be included.
 func matchSubSection(subsec, workdir string, c *Config) bool {
-	if strings.HasPrefix(subsec, "gitdir") {
-		caseInsensitive := strings.Contains(subsec, "/i:")
-		p := strings.SplitN(subsec, ":", 2)
-		dir := p[1]
-
-		var exactMatch bool
-		if caseInsensitive {
-			exactMatch = strings.EqualFold(strings.TrimSuffix(workdir, "/"), strings.TrimSuffix(dir, "/"))
-		} else {
-			exactMatch = strings.TrimSuffix(workdir, "/") == strings.TrimSuffix(dir, "/")
+	cond := parseCondition(subsec)
+
+	switch cond.kind {
+	case conditionGitdir:
+		dir := resolveGitdirPattern(cond.pattern, c)
+		patterns := []string{dir}
+		if rp := resolvePatternBase(dir); rp != dir {
+			patterns = append(patterns, rp)
 		}
 
-		if exactMatch || prefixMatch(dir, workdir, caseInsensitive) {
-			return true
+		for _, wd := range conditionDirs(workdir, c.gitDir) {
+			fold := cond.fold || caseInsensitiveFS(wd)
+			for _, pattern := range patterns {
+				if gitdirMatch(pattern, wd, fold) {
+					return true
+				}
+			}
 		}
-		debug.V(3).Log("skipping include candidate, no exact match for workdir: %q == dir: %q and no prefix match for dir: %q, workdir: %q", subsec, workdir, dir, dir, workdir)
+		debug.V(3).Log("skipping include candidate %q, pattern %q does not match workdir %q", subsec, dir, workdir)
 
 		return false
-	}
-
-	if strings.HasPrefix(subsec, "onbranch:") {
-		p := strings.SplitN(subsec, ":", 2)
-		branchPattern := p[1]
+	case conditionOnbranch:
 		if c.branch == "" {
 			return false
 		}
-
-		match, err := globMatch(branchPattern, c.branch)
-		if err != nil {
-			debug.V(1).Log("invalid glob pattern in onbranch: %s", err)
+		if cond.err != nil {
+			debug.V(1).Log("invalid glob pattern in onbranch: %s", cond.err)
 
 			return false
 		}
-		if match {
-			return true
-		}
 
-		return false
+		return cond.branch.Match(c.branch)
+	case conditionUnsupported:
 	}
 
 	debug.V(3).Log("skipping unsupported include candidate %q", subsec)
@@ -725,94 +1343,304 @@ func matchSubSection(subsec, workdir string, c *Config) bool {
 	return false
 }
 
-// prefixMatch checks if a path matches a prefix pattern, with optional case-folding.
-// This is used for gitdir: and gitdir/i: conditional includes.
-// The fold parameter controls case-insensitive matching.
-func prefixMatch(path, prefix string, fold bool) bool {
-	if !strings.HasSuffix(prefix, "/") {
-		return false
+// resolveGitdirPattern expands a leading "~" of a gitdir pattern to the
+// home directory (see expandHome) and resolves a pattern starting with "./"
+// against the directory of the config file that contains it, like git
+// does. Other patterns are returned as they are. Configs that are not read
+// from a file keep a "./" pattern, so it never matches.
+func resolveGitdirPattern(pattern string, c *Config) string {
+	if p := expandHome(pattern); p != pattern {
+		return filepath.ToSlash(p) + trailingSlash(pattern)
+	}
+
+	rest, found := strings.CutPrefix(pattern, "./")
+	if !found || c == nil || c.path == "" {
+		return pattern
+	}
+
+	base := filepath.Dir(c.path)
+	if abs, err := filepath.Abs(base); err == nil {
+		base = abs
+	}
+
+	return filepath.ToSlash(base) + "/" + rest
+}
+
+// resolvePatternBase resolves the symlinks in the leading directories of an
+// absolute gitdir pattern, up to the first glob character, so a pattern
+// written with a symlinked path matches the resolved git directory (see
+// conditionDirs). Git only resolves the git directory, so this matches in
+// more cases than git does. The pattern is returned as it is if there is
+// nothing to resolve.
+func resolvePatternBase(pattern string) string {
+	if !strings.HasPrefix(pattern, "/") && !filepath.IsAbs(pattern) {
+		return pattern
+	}
+
+	literal := pattern
+	if i := strings.IndexAny(pattern, "*?[{\\"); i >= 0 {
+		literal = pattern[:i]
+	}
+	end := strings.LastIndex(literal, "/")
+	if end <= 0 {
+		return pattern
+	}
+
+	base, err := filepath.EvalSymlinks(filepath.FromSlash(pattern[:end]))
+	if err != nil {
+		return pattern
+	}
+
+	return filepath.ToSlash(base) + pattern[end:]
+}
+
+// trailingSlash returns "/" if p ends with one. filepath.Join drops it but
+// it is significant in gitdir patterns.
+func trailingSlash(p string) string {
+	if strings.HasSuffix(p, "/") {
+		return "/"
+	}
+
+	return ""
+}
+
+// gitdirMatch reports whether dir matches the pattern of a gitdir: or
+// gitdir/i: condition, following git's rules: a pattern not starting with
+// "/", "~/" or "./" matches in any directory (it gets a "**/" prefix) and a
+// pattern ending with "/" matches everything below (it gets a "**" suffix).
+// Both are glob patterns where "*" and "?" do not match "/". The fold
+// parameter enables case-insensitive matching.
+func gitdirMatch(pattern, dir string, fold bool) bool {
+	pattern = filepath.ToSlash(pattern)
+	dir = strings.TrimSuffix(filepath.ToSlash(dir), "/")
+	if !strings.HasPrefix(pattern, "/") && !filepath.IsAbs(pattern) && !strings.HasPrefix(pattern, "~/") && !strings.HasPrefix(pattern, "./") {
+		pattern = "**/" + pattern
+	}
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
 	}
 	if fold {
-		return strings.HasPrefix(strings.ToLower(path), strings.ToLower(prefix))
+		pattern = strings.ToLower(pattern)
+		dir = strings.ToLower(dir)
 	}
 
-	return strings.HasPrefix(path, prefix)
+	// the directory itself matches a pattern with a trailing slash
+	for _, d := range []string{dir, dir + "/"} {
+		match, err := globMatch(pattern, d)
+		if err != nil {
+			debug.V(1).Log("invalid glob pattern in gitdir: %s", err)
+
+			return false
+		}
+		if match {
+			return true
+		}
+	}
+
+	return false
 }
 
 // loadConfigs loads a config file and recursively processes all include directives.
 // This is the main entry point for loading configs with include support.
+// At most parseOptions.maxIncludes files are pulled in through includes,
+// any further includes are skipped and reported as an issue. Includes nested deeper
+// than parseOptions.maxIncludeDepth fail with an *IncludeDepthError. Files that are
+// already loaded are skipped, unless they are part of a cycle and
+// parseOptions.failOnCycle is set. Includes that can not be read are
+// handled according to parseOptions.includeErrors.
 // Returns the merged configuration from all included files.
-func loadConfigs(fn, workdir string) (*Config, error) {
-	c, err := loadConfig(fn)
+func loadConfigs(fn, workdir string, opts parseOptions) (*Config, error) {
+	c, err := loadConfig(fn, opts)
 	if err != nil {
 		return nil, err
 	}
 	c.path = fn
-	c.branch = readGitBranch(workdir)
+	c.branch = opts.branchFor(workdir)
+	c.gitDir = opts.repo.gitDir
 
 	loadedConfigs := map[string]struct{}{
-		fn: {},
+		canonicalPath(fn): {},
 	}
-	configsToLoad := []string{}
+	configsToLoad := []includeRef{}
 
-	includePaths, includeExists := getEffectiveIncludes(c, workdir)
-	if includeExists {
-		configsToLoad = append(configsToLoad, getPathsForNestedConfig(includePaths, c.path)...)
-	}
+	graph := []IncludeNode{}
+	configsToLoad, graph = appendIncludes(configsToLoad, graph, c, workdir, []string{fn})
 
 	// load all nested configs
 	// this is using a slice as a stack because when we load a config
 	// it may include other configs
 	// so we need to load them in the order they are found.
 	for len(configsToLoad) > 0 {
-		head := configsToLoad[0]
+		ref := configsToLoad[0]
+		head := ref.path
 		configsToLoad = configsToLoad[1:]
 
 		// check if we already loaded this config
 		// this is needed to avoid infinite loops when loading nested configs
-		_, ignore := loadedConfigs[head]
+		canonical := canonicalPath(head)
+		if opts.failOnCycle {
+			if cycle := includeCycle(ref, canonical); cycle != nil {
+				return nil, &CircularIncludeError{Cycle: cycle}
+			}
+		}
+		_, ignore := loadedConfigs[canonical]
 		if ignore {
 			debug.V(3).Log("skipping already loaded config %q", head)
 
 			continue
 		}
 
+		if limit := opts.includeDepthLimit(); limit > 0 && len(ref.chain) > limit {
+			return nil, &IncludeDepthError{Chain: append(slices.Clone(ref.chain), head), Max: limit}
+		}
+
+		if limit := opts.includeLimit(); limit > 0 && len(c.includes) >= limit {
+			debug.V(1).Log("include limit of %d files reached, skipping %q and any remaining includes", limit, head)
+			c.issues = append(c.issues, parseIssue{path: fn, msg: fmt.Sprintf("include limit of %d files reached, skipping %s and any remaining includes", limit, head)})
+			c.includeLimitReached = true
+
+			break
+		}
+
 		debug.V(2).Log("loading nested config %q", head)
-		nc, err := loadConfig(head)
+		nc, err := loadConfig(head, opts)
 		if err != nil {
-			return nil, err
+			if !isUnreadable(err) {
+				return nil, err
+			}
+			skipped := SkippedInclude{Path: head, From: ref.chain[len(ref.chain)-1], Err: err}
+			if opts.includeErrors == IncludeErrorFail {
+				return nil, &IncludeError{SkippedInclude: skipped}
+			}
+			debug.V(1).Log("%s", skipped)
+			if opts.includeErrors == IncludeErrorWarn {
+				c.issues = append(c.issues, parseIssue{path: skipped.From, msg: skipped.String()})
+			}
+			c.skippedIncludes = append(c.skippedIncludes, skipped)
+
+			continue
 		}
 
+		nc.branch = c.branch
+		nc.gitDir = c.gitDir
 		c = mergeConfigs(c, nc)
-		loadedConfigs[head] = struct{}{}
+		c.includes = append(c.includes, head)
+		loadedConfigs[canonical] = struct{}{}
 
-		includePaths, includeExists := getEffectiveIncludes(nc, workdir)
-		if includeExists {
-			configsToLoad = append(configsToLoad, getPathsForNestedConfig(includePaths, nc.path)...)
-		}
+		graph[ref.node].Loaded = true
+
+		configsToLoad, graph = appendIncludes(configsToLoad, graph, nc, workdir, append(slices.Clone(ref.chain), head))
 	}
+	c.includeGraph = graph
+	c.loadedWith = &loadParams{workdir: workdir, opts: opts}
+	c.loadedAt = timeNow()
 
 	return c, nil
 }
 
+// includeRef is an include waiting to be loaded by loadConfigs.
+type includeRef struct {
+	path  string
+	chain []string // the files that lead to path, starting with the loaded file
+	node  int      // the index of the include in the include graph
+}
+
+// includeCycle returns the files from the first occurrence of the file
+// with the canonical path in the chain of ref to ref itself, or nil if ref
+// does not include one of the files that lead to it.
+func includeCycle(ref includeRef, canonical string) []string {
+	for i, p := range ref.chain {
+		if canonicalPath(p) == canonical {
+			return append(slices.Clone(ref.chain[i:]), ref.path)
+		}
+	}
+
+	return nil
+}
+
+// appendIncludes records the include directives of nc, the last file of
+// chain, in the include graph and queues the paths of those that match.
+func appendIncludes(queue []includeRef, graph []IncludeNode, nc *Config, workdir string, chain []string) ([]includeRef, []IncludeNode) {
+	for _, n := range includeDirectives(nc, workdir) {
+		paths := getPathsForNestedConfig([]string{n.Path}, nc.path)
+		if len(paths) == 0 {
+			continue
+		}
+		n.Path = paths[0]
+		graph = append(graph, n)
+		if n.Matched {
+			queue = append(queue, includeRef{path: n.Path, chain: chain, node: len(graph) - 1})
+		}
+	}
+
+	return queue, graph
+}
+
+// canonicalPath returns a canonical representation of the given path so that
+// different spellings of the same physical file (relative, absolute, symlinked)
+// compare equal. If the path can not be resolved the cleaned absolute path
+// is returned.
+func canonicalPath(fn string) string {
+	abs, err := filepath.Abs(fn)
+	if err != nil {
+		return filepath.Clean(fn)
+	}
+
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return abs
+	}
+
+	return resolved
+}
+
 // loadConfig loads a single config file without processing includes.
 // This is used internally by loadConfigs to load individual files.
-func loadConfig(fn string) (*Config, error) {
+func loadConfig(fn string, opts parseOptions) (*Config, error) {
 	fh, err := os.Open(fn)
 	if err != nil {
 		return nil, err
 	}
 	defer fh.Close() //nolint:errcheck
 
-	c := ParseConfig(fh)
+	c, err := parseConfig(fh, opts)
+	if err != nil {
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			perr.Path = fn
+
+			return nil, perr
+		}
+
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
 	c.path = fn
+	for i := range c.issues {
+		c.issues[i].path = fn
+	}
+	for _, vo := range c.origins {
+		for i := range vo {
+			vo[i].path = fn
+		}
+	}
 
 	return c, nil
 }
 
 // mergeConfigs merge two configs, using first config as a base config extending it with vars, raw fields from the latter.
 func mergeConfigs(base *Config, extension *Config) *Config {
-	newConfig := Config{path: base.path, readonly: base.readonly, noWrites: base.noWrites, raw: strings.Builder{}, vars: map[string][]string{}}
+	newConfig := Config{path: base.path, readonly: base.readonly, noWrites: base.noWrites, compat: base.compat, level: base.level, format: base.format, branch: base.branch, gitDir: base.gitDir, raw: strings.Builder{}, vars: map[string][]string{}}
+	newConfig.issues = append(slices.Clone(base.issues), extension.issues...)
+	newConfig.includes = slices.Clone(base.includes)
+	newConfig.skippedIncludes = slices.Clone(base.skippedIncludes)
+	newConfig.includeGraph = slices.Clone(base.includeGraph)
+	newConfig.origins = cloneOrigins(base.origins)
+	for k, vo := range extension.origins {
+		if newConfig.origins == nil {
+			newConfig.origins = make(map[string][]valueOrigin, len(extension.origins))
+		}
+		newConfig.origins[k] = append(newConfig.origins[k], vo...)
+	}
 	newConfig.raw.WriteString(base.raw.String())
 	// Note: We can not append the included config raw to the base config raw, because it will
 	// write the included config to the base config file when we write the base config.
@@ -831,7 +1659,9 @@ func mergeConfigs(base *Config, extension *Config) *Config {
 	return &newConfig
 }
 
-// getPathsForNestedConfig tries to convert paths of nested configs ('/absolute', '~/from/home', 'relative/to/base') to absolute paths.
+// getPathsForNestedConfig tries to convert paths of nested configs ('/absolute', '~/from/home', '~user/from/their/home', 'relative/to/base') to absolute paths.
+// A leading "~" is expanded like git does, see expandPath. Paths that can
+// not be expanded are skipped.
 func getPathsForNestedConfig(nestedConfigs []string, baseConfig string) []string {
 	absolutePaths := []string{}
 	for _, nc := range nestedConfigs {
@@ -840,15 +1670,14 @@ func getPathsForNestedConfig(nestedConfigs []string, baseConfig string) []string
 
 			continue
 		}
-		if strings.HasPrefix(nc, "~/") {
-			home, exists := os.LookupEnv("HOME")
-			if !exists {
-				// cannot resolve home directory
-				debug.V(3).Log("cannot resolve home directory, skipping %q", nc)
+		if strings.HasPrefix(nc, "~") {
+			p, err := expandPath(nc)
+			if err != nil {
+				debug.V(3).Log("skipping %q: %s", nc, err)
 
 				continue
 			}
-			absolutePaths = append(absolutePaths, path.Join(home, strings.Replace(nc, "~/", "", 1)))
+			absolutePaths = append(absolutePaths, p)
 
 			continue
 		}
@@ -859,39 +1688,77 @@ func getPathsForNestedConfig(nestedConfigs []string, baseConfig string) []string
 }
 
 // ParseConfig will try to parse a gitconfig from the given io.Reader. It never fails.
-// Invalid configs will be silently rejected.
+// Invalid configs will be silently rejected, see Warnings. Binary content
+// results in an empty config.
 func ParseConfig(r io.Reader) *Config {
-	c := &Config{
-		vars: make(map[string][]string, 42),
+	c, err := parseConfig(r, parseOptions{})
+	if err != nil {
+		debug.V(1).Log("failed to read config: %s", err)
 	}
 
-	lines := parseConfig(r, "", "", func(fk, k, v, comment, _ string) (string, bool) {
-		fk = canonicalizeKey(fk)
-		c.vars[fk] = append(c.vars[fk], v)
+	return c
+}
 
-		return formatKeyValue(k, v, comment), false
+// parseConfig parses a config using the given CompatMode setting, nil uses
+// the package default.
+func parseConfig(r io.Reader, opts parseOptions) (*Config, error) {
+	c := &Config{
+		vars:    make(map[string][]string, 42),
+		origins: make(map[string][]valueOrigin, 42),
+		compat:  opts.compat,
+		level:   opts.level,
+		keys:    opts.keys,
+	}
+
+	empty := true
+	t := newTokenizer(c.unescapeValues(), func(l docLine) {
+		empty = false
+		if l.kind == lineKeyValue {
+			c.vars[l.key] = append(c.vars[l.key], l.value)
+			c.origins[l.key] = append(c.origins[l.key], valueOrigin{line: l.line, bare: l.bare, raw: rawIfDifferent(l.rawValue(), l.value), comment: l.commentText()})
+		}
+		c.raw.WriteString(l.text)
+		c.raw.WriteString("\n")
 	})
+	t.keys = c.keys
+	t.maxLine = opts.maxLineLength
+	err := t.tokenize(r)
+	if errors.Is(err, ErrNotAConfigFile) {
+		// do not return partial results for binary files
+		c = &Config{vars: map[string][]string{}, compat: opts.compat, level: opts.level, keys: opts.keys}
+		empty = true
+	}
+	c.issues = t.issues
+	c.format = t.format
 
-	c.raw.WriteString(strings.Join(lines, "\n"))
-	c.raw.WriteString("\n")
+	if empty {
+		c.raw.WriteString("\n")
+	}
 
 	debug.V(3).Log("processed config: %s\nvars: %+v", c.raw.String(), c.vars)
 
-	return c
+	return c, err
 }
 
 // LoadConfigFromEnv will try to parse an overlay config from the environment variables.
 // If no environment variables are set the resulting config will be valid but empty.
 // Either way it will not be writeable.
 func LoadConfigFromEnv(envPrefix string) *Config {
+	return loadConfigFromEnv(envPrefix, KeyRules{})
+}
+
+// loadConfigFromEnv is LoadConfigFromEnv with the given key rules.
+func loadConfigFromEnv(envPrefix string, keys KeyRules) *Config {
 	c := &Config{
 		noWrites: true,
+		keys:     keys,
 	}
 
 	count, err := strconv.Atoi(os.Getenv(envPrefix + "_COUNT"))
 	if err != nil || count < 1 {
 		return &Config{
 			noWrites: true,
+			keys:     keys,
 		}
 	}
 
@@ -907,9 +1774,13 @@ func LoadConfigFromEnv(envPrefix string) *Config {
 		if key == "" || !found {
 			return &Config{
 				noWrites: true,
+				keys:     keys,
 			}
 		}
 
+		if ck := keys.Canonical(key); ck != "" {
+			key = ck
+		}
 		c.vars[key] = append(c.vars[key], value)
 		debug.V(3).Log("added %s from env", key)
 	}
@@ -3,7 +3,9 @@ package gitconfig
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -72,3 +74,84 @@ func BenchmarkSet(b *testing.B) {
 		}
 	}
 }
+
+func writeHugeConfig(b *testing.B) string {
+	b.Helper()
+
+	configPath := filepath.Join(b.TempDir(), "config")
+
+	var sb strings.Builder
+	sb.WriteString("[url \"git@example.com:\"]\n")
+	for i := range 100000 {
+		sb.WriteString("\tinsteadOf = https://example.com/generated/repository/number/")
+		sb.WriteString(strconv.Itoa(i))
+		sb.WriteString("\n")
+	}
+
+	if err := os.WriteFile(configPath, []byte(sb.String()), 0o644); err != nil {
+		b.Fatal(err)
+	}
+
+	return configPath
+}
+
+func benchmarkLoadHuge(b *testing.B, load func(string) (*Config, error)) {
+	b.Helper()
+
+	configPath := writeHugeConfig(b)
+
+	b.ReportAllocs()
+
+	var heap uint64
+	for b.Loop() {
+		cfg, err := load(configPath)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		runtime.GC()
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		heap = ms.HeapInuse
+
+		runtime.KeepAlive(cfg)
+	}
+
+	b.ReportMetric(float64(heap), "heap-B")
+}
+
+func BenchmarkLoadConfigHuge(b *testing.B) {
+	benchmarkLoadHuge(b, LoadConfig)
+}
+
+func BenchmarkLoadConfigMappedHuge(b *testing.B) {
+	benchmarkLoadHuge(b, LoadConfigMapped)
+}
+
+func BenchmarkGetInt(b *testing.B) {
+	cfg := ParseConfig(strings.NewReader("[core]\n\tbigfilethreshold = 512m\n"))
+
+	for b.Loop() {
+		if _, _, err := cfg.GetInt("core.bigfilethreshold"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseConfigSections(b *testing.B) {
+	var sb strings.Builder
+	for i := range 2000 {
+		sb.WriteString("[section \"sub" + strconv.Itoa(i) + "\"]\n")
+		sb.WriteString("\t# a comment\n")
+		sb.WriteString("\tname = value " + strconv.Itoa(i) + "\n")
+		sb.WriteString("\tenabled = true ; trailing comment\n")
+		sb.WriteString("\tbare\n")
+	}
+	content := sb.String()
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		ParseConfig(strings.NewReader(content))
+	}
+}
@@ -37,6 +37,54 @@ func TestIncludeFileNotFound(t *testing.T) {
 	}
 }
 
+// TestIncludeErrorPolicy tests the handling of includes that can not be read.
+func TestIncludeErrorPolicy(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	configPath := filepath.Join(td, "config")
+	missing := filepath.Join(td, "missing.conf")
+	content := "[include]\n\tpath = missing.conf\n\tpath = dir.conf\n\tpath = other.conf\n[user]\n\tname = Test\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(td, "dir.conf"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "other.conf"), []byte("[core]\n\teditor = vim\n"), 0o644))
+
+	for _, policy := range []IncludeErrorPolicy{IncludeErrorIgnore, IncludeErrorWarn} {
+		cfg, err := loadConfigs(configPath, "", parseOptions{includeErrors: policy})
+		require.NoError(t, err, policy)
+
+		// the remaining includes are still loaded
+		editor, ok := cfg.Get("core.editor")
+		assert.True(t, ok, policy)
+		assert.Equal(t, "vim", editor, policy)
+
+		skipped := cfg.SkippedIncludes()
+		require.Len(t, skipped, 2, policy)
+		assert.Equal(t, missing, skipped[0].Path)
+		assert.Equal(t, configPath, skipped[0].From)
+		require.ErrorIs(t, skipped[0].Err, os.ErrNotExist)
+		assert.Equal(t, filepath.Join(td, "dir.conf"), skipped[1].Path)
+
+		if policy == IncludeErrorIgnore {
+			assert.Empty(t, cfg.Warnings())
+
+			continue
+		}
+		require.Len(t, cfg.Warnings(), 2)
+		assert.Equal(t, configPath, cfg.Warnings()[0].Path)
+		assert.Contains(t, cfg.Warnings()[0].Reason, "skipping include "+missing)
+	}
+
+	_, err := loadConfigs(configPath, "", parseOptions{includeErrors: IncludeErrorFail})
+	require.ErrorIs(t, err, ErrIncludeUnreadable)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	var ierr *IncludeError
+	require.ErrorAs(t, err, &ierr)
+	assert.Equal(t, missing, ierr.Path)
+	assert.Equal(t, configPath, ierr.From)
+	assert.Equal(t, "fail", IncludeErrorFail.String())
+}
+
 // TestIncludePermissionDenied tests behavior when included files are unreadable.
 func TestIncludePermissionDenied(t *testing.T) {
 	t.Parallel()
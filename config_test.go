@@ -3,16 +3,19 @@ package gitconfig
 import (
 	"bytes"
 	"fmt"
+	"maps"
 	"math/rand"
 	"os"
+	"os/exec"
+	"os/user"
 	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
 
-	"github.com/gopasspw/gopass/pkg/set"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -24,7 +27,7 @@ func TestInsertOnce(t *testing.T) {
 		noWrites: true,
 	}
 
-	require.NoError(t, c.insertValue("foo.bar", "baz"))
+	require.NoError(t, c.insertValue("foo.bar", "baz", false))
 	assert.Equal(t, `[foo]
 	bar = baz
 `, c.raw.String())
@@ -98,6 +101,138 @@ func TestConditionalIncludeOnBranch(t *testing.T) {
 	assert.Equal(t, []string{"7", "10"}, vs)
 }
 
+func TestConditionalIncludeOnBranchWorktree(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+
+	fn := filepath.Join(td, "config")
+	require.NoError(t, os.WriteFile(fn, []byte("[core]\n\tint = 7\n[includeIf \"onbranch:main\"]\n\tpath = main.config\n[includeIf \"onbranch:feat/*\"]\n\tpath = feat.config\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "main.config"), []byte("[core]\n\tint = 8\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "feat.config"), []byte("[core]\n\tint = 9\n"), 0o600))
+
+	// the main worktree is on main, the linked worktrees on a feature branch
+	repo := filepath.Join(td, "repo")
+	gitDir := filepath.Join(repo, ".git")
+	require.NoError(t, os.MkdirAll(filepath.Join(gitDir, "worktrees", "wt1"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(gitDir, "worktrees", "wt2"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "worktrees", "wt1", "HEAD"), []byte("ref: refs/heads/feat/one\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "worktrees", "wt2", "HEAD"), []byte("ref: refs/heads/main\n"), 0o644))
+
+	wt1 := filepath.Join(td, "wt1")
+	wt2 := filepath.Join(td, "wt2")
+	require.NoError(t, os.Mkdir(wt1, 0o755))
+	require.NoError(t, os.Mkdir(wt2, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(wt1, ".git"), []byte("gitdir: "+filepath.Join(gitDir, "worktrees", "wt1")+"\n"), 0o644))
+	// relative paths are relative to the worktree
+	require.NoError(t, os.WriteFile(filepath.Join(wt2, ".git"), []byte("gitdir: ../repo/.git/worktrees/wt2\n"), 0o644))
+
+	for workdir, want := range map[string][]string{
+		repo: {"7", "8"},
+		wt1:  {"7", "9"},
+		wt2:  {"7", "8"},
+		// a git directory can be used as the workdir as well
+		filepath.Join(gitDir, "worktrees", "wt1"): {"7", "9"},
+	} {
+		cfg, err := LoadConfigWithWorkdir(fn, workdir)
+		require.NoError(t, err)
+		vs, _ := cfg.GetAll("core.int")
+		assert.Equal(t, want, vs, workdir)
+	}
+}
+
+func TestReadGitBranch(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	repo := func(name, head string, refs map[string]string) string {
+		gitDir := filepath.Join(td, name, ".git")
+		require.NoError(t, os.MkdirAll(gitDir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte(head), 0o644))
+		for ref, content := range refs {
+			fn := filepath.Join(gitDir, filepath.FromSlash(ref))
+			require.NoError(t, os.MkdirAll(filepath.Dir(fn), 0o755))
+			require.NoError(t, os.WriteFile(fn, []byte(content), 0o644))
+		}
+
+		return filepath.Join(td, name)
+	}
+
+	for _, tc := range []struct {
+		name string
+		head string
+		refs map[string]string
+		want string
+	}{
+		{name: "branch", head: "ref: refs/heads/main\n", refs: map[string]string{"refs/heads/main": "0123456789abcdef0123456789abcdef01234567\n"}, want: "main"},
+		{name: "unborn", head: "ref: refs/heads/new\n", want: "new"},
+		{name: "packed", head: "ref: refs/heads/packed\n", refs: map[string]string{"packed-refs": "0123456789abcdef0123456789abcdef01234567 refs/heads/packed\n"}, want: "packed"},
+		{name: "symref", head: "ref: refs/heads/alias\n", refs: map[string]string{"refs/heads/alias": "ref: refs/heads/feat/x\n"}, want: "feat/x"},
+		{name: "detached", head: "0123456789abcdef0123456789abcdef01234567\n", want: ""},
+		{name: "remote", head: "ref: refs/remotes/origin/main\n", want: ""},
+		{name: "loop", head: "ref: refs/heads/a\n", refs: map[string]string{"refs/heads/a": "ref: refs/heads/b\n", "refs/heads/b": "ref: refs/heads/a\n"}, want: ""},
+	} {
+		assert.Equal(t, tc.want, readGitBranch(repo(tc.name, tc.head, tc.refs), repoEnv{}), tc.name)
+	}
+
+	// a linked worktree resolves symrefs in the common directory
+	main := repo("main", "ref: refs/heads/main\n", map[string]string{"refs/heads/alias": "ref: refs/heads/feat/y\n"})
+	wtGitDir := filepath.Join(main, ".git", "worktrees", "wt")
+	require.NoError(t, os.MkdirAll(wtGitDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(wtGitDir, "HEAD"), []byte("ref: refs/heads/alias\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(wtGitDir, "commondir"), []byte("../..\n"), 0o644))
+	wt := filepath.Join(td, "wt")
+	require.NoError(t, os.Mkdir(wt, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(wt, ".git"), []byte("gitdir: "+wtGitDir+"\n"), 0o644))
+	assert.Equal(t, "feat/y", readGitBranch(wt, repoEnv{}))
+	assert.Equal(t, "main", readGitBranch(main, repoEnv{}))
+
+	if runtime.GOOS != "windows" {
+		// old versions of git used a symlink
+		link := repo("symlink", "", nil)
+		require.NoError(t, os.Remove(filepath.Join(link, ".git", "HEAD")))
+		require.NoError(t, os.Symlink("refs/heads/linked", filepath.Join(link, ".git", "HEAD")))
+		assert.Equal(t, "linked", readGitBranch(link, repoEnv{}))
+	}
+}
+
+func TestConditionalIncludeOnBranchDetached(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	fn := filepath.Join(td, "config")
+	require.NoError(t, os.WriteFile(fn, []byte("[core]\n\tint = 7\n[includeIf \"onbranch:**\"]\n\tpath = any.config\n[includeIf \"onbranch:feat/\"]\n\tpath = feat.config\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "any.config"), []byte("[core]\n\tint = 8\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "feat.config"), []byte("[core]\n\tint = 9\n"), 0o600))
+
+	repo := filepath.Join(td, "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0o755))
+
+	for head, want := range map[string][]string{
+		"0123456789abcdef0123456789abcdef01234567\n": {"7"},
+		"ref: refs/heads/main\n":                     {"7", "8"},
+		"ref: refs/heads/feat/a/b\n":                 {"7", "8", "9"},
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(repo, ".git", "HEAD"), []byte(head), 0o644))
+		cfg, err := LoadConfigWithWorkdir(fn, repo)
+		require.NoError(t, err)
+		vs, _ := cfg.GetAll("core.int")
+		assert.ElementsMatch(t, want, vs, head)
+	}
+}
+
+func TestWorktreeGitDir(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(td, ".git"), []byte("not a gitdir file\n"), 0o644))
+
+	assert.Empty(t, worktreeGitDir(""))
+	assert.Empty(t, worktreeGitDir(td))
+	assert.Empty(t, worktreeGitDir(filepath.Join(td, "missing")))
+}
+
 func TestConditionalIncludeGitDirI(t *testing.T) {
 	t.Parallel()
 
@@ -210,14 +345,98 @@ func TestParseSection(t *testing.T) {
 			section: "aliases",
 			subs:    `subsection with spaces and " t 0 escapes`,
 		},
+		`[remote "C:\\repos\\\"quoted\\\""]`: {
+			section: "remote",
+			subs:    `C:\repos\"quoted\"`,
+		},
+		`[remote "ends with ]"]`: {
+			section: "remote",
+			subs:    "ends with ]",
+		},
+		`[]`: {
+			skip: true,
+		},
 	} {
-		section, subsection, skip := parseSectionHeader(in)
+		section, subsection, skip := parseSectionHeader(in, KeyRules{})
 		assert.Equal(t, out.section, section, in)
 		assert.Equal(t, out.subs, subsection, in)
 		assert.Equal(t, out.skip, skip, in)
 	}
 }
 
+// TestSectionNameConformance checks section headers from git's own test
+// suite (t1300-config.sh). A nil key list means git rejects the header.
+func TestSectionNameConformance(t *testing.T) {
+	t.Parallel()
+
+	for hdr, want := range map[string][]string{
+		`[section]`:               {"section.key"},
+		`[Section]`:               {"section.key"},
+		`[beta] ; silly comment`:  {"beta.key"},
+		`[foo-bar]`:               {"foo-bar.key"},
+		`[1.2.3]`:                 {"1.2.3.key"},
+		`[-]`:                     {"-.key"},
+		`[pager.diff]`:            {"pager.diff.key"},
+		`[Section.SubSection]`:    {"section.subsection.key"},
+		`[a.b.c]`:                 {"a.b.c.key"},
+		`[diff "ansi"]`:           {"diff.ansi.key"},
+		`[section "SubSection"]`:  {"section.SubSection.key"},
+		`[section "sub=section"]`: {"section.sub=section.key"},
+		`[section "sub.section"]`: {"section.sub.section.key"},
+		`[a.b "c"]`:               {"a.b.c.key"},
+		`[section  "sub"]`:        {"section.sub.key"},
+		"[section\t\"sub\"]":      {"section.sub.key"},
+		`[foo_bar]`:               nil,
+		`[foo bar]`:               nil,
+		`[ section]`:              nil,
+		`[.section]`:              nil,
+		`[section "sub" ]`:        nil,
+		`[section "sub"x]`:        nil,
+	} {
+		c := ParseConfig(strings.NewReader(hdr + "\n\tkey = value\n"))
+		c.noWrites = true
+
+		if want == nil {
+			assert.Empty(t, c.vars, hdr)
+			assert.Len(t, c.Warnings(), 2, hdr)
+
+			continue
+		}
+		assert.Empty(t, c.Warnings(), hdr)
+		assert.Equal(t, want, slices.Sorted(maps.Keys(c.vars)), hdr)
+
+		// splitKey must split the key so Set finds the existing line
+		section, subsection, key := splitKey(want[0])
+		require.Equal(t, want[0], joinSection(section, subsection)+"."+key, hdr)
+		require.NoError(t, c.Set(want[0], "new"), hdr)
+		assert.Equal(t, hdr+"\n\tkey = new\n", c.raw.String(), hdr)
+	}
+}
+
+func TestSubsectionEscapes(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `"plain"`, QuoteSubsection("plain"))
+	assert.Equal(t, `"my \"fork\" \\ 2"`, QuoteSubsection(`my "fork" \ 2`))
+
+	for _, subs := range []string{"plain", `with "quotes"`, `back\slash`, `C:\repos\`, `\"`} {
+		hdr := "[remote " + QuoteSubsection(subs) + "]"
+		_, got, _ := parseSectionHeader(hdr, KeyRules{})
+		assert.Equal(t, subs, got, hdr)
+	}
+
+	// new sections are written with escaped subsections and read back
+	c := ParseConfig(strings.NewReader(""))
+	c.noWrites = true
+	require.NoError(t, c.Set(`remote.my "fork" \ 2.url`, "x"))
+	assert.Equal(t, "\n[remote \"my \\\"fork\\\" \\\\ 2\"]\n\turl = x\n", c.raw.String())
+
+	c = ParseConfig(strings.NewReader(c.raw.String()))
+	v, ok := c.Get(`remote.my "fork" \ 2.url`)
+	assert.True(t, ok)
+	assert.Equal(t, "x", v)
+}
+
 func TestInsertMultiple(t *testing.T) {
 	t.Parallel()
 
@@ -231,9 +450,9 @@ func TestInsertMultiple(t *testing.T) {
 		"core.noshow": "true",
 	}
 
-	for _, k := range set.SortedKeys(updates) {
+	for _, k := range slices.Sorted(maps.Keys(updates)) {
 		v := updates[k]
-		require.NoError(t, c.insertValue(k, v))
+		require.NoError(t, c.insertValue(k, v, false))
 	}
 
 	assert.Equal(t, `[core]
@@ -262,7 +481,7 @@ func TestRewriteRaw(t *testing.T) {
 		"show.safecontent": "false",
 		"core.autoimport":  "false",
 	}
-	for _, k := range set.SortedKeys(updates) {
+	for _, k := range slices.Sorted(maps.Keys(updates)) {
 		v := updates[k]
 		require.NoError(t, c.Set(k, v))
 	}
@@ -454,6 +673,22 @@ func TestGetPathsForNestedConfig(t *testing.T) {
 		got := getPathsForNestedConfig([]string{v[1]}, v[0])
 		assert.Equal(t, []string{v[2]}, got)
 	}
+
+	// without $HOME the user database is used
+	t.Setenv("HOME", "")
+	t.Setenv("GOPASS_HOMEDIR", "")
+	if u, err := user.Current(); err == nil && runtime.GOOS != "windows" {
+		assert.Equal(t, []string{filepath.Join(u.HomeDir, "foo.config")}, getPathsForNestedConfig([]string{"~/foo.config"}, "/home/user/config"))
+		assert.Equal(t, []string{filepath.Join(u.HomeDir, "foo.config")}, getPathsForNestedConfig([]string{"~" + u.Username + "/foo.config"}, "/home/user/config"))
+	}
+
+	homeDir = func() string { return "/home/other" }
+	t.Cleanup(func() { homeDir = userHome })
+	assert.Equal(t, []string{filepath.Join("/home/other", "foo.config")}, getPathsForNestedConfig([]string{"~/foo.config"}, "/home/user/config"))
+
+	// paths that can not be expanded are skipped
+	homeDir = func() string { return "" }
+	assert.Empty(t, getPathsForNestedConfig([]string{"~/foo.config", "~no-such-user-gitconfig/foo.config"}, "/home/user/config"))
 }
 
 func TestMergeConfigs(t *testing.T) {
@@ -522,6 +757,35 @@ func TestMultiInclude(t *testing.T) {
 	assert.Equal(t, "false", v)
 }
 
+func TestIncludeSameFileDifferentSpellings(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on windows, symlinks require privileges")
+	}
+
+	td := t.TempDir()
+	fnFoo := filepath.Join(td, "foo.config")
+	require.NoError(t, os.WriteFile(fnFoo, []byte("[core]\n\tint = 8\n"), 0o600))
+	require.NoError(t, os.Symlink(fnFoo, filepath.Join(td, "link.config")))
+
+	fn := filepath.Join(td, "config")
+	require.NoError(t, os.WriteFile(fn, []byte(`[core]
+	int = 7
+[include]
+	path = ./foo.config
+	path = `+fnFoo+`
+	path = link.config
+	path = config
+`), 0o600))
+
+	cfg, err := LoadConfig(fn)
+	require.NoError(t, err)
+	vs, ok := cfg.GetAll("core.int")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"7", "8"}, vs)
+}
+
 func TestIncludeWrite(t *testing.T) {
 	t.Parallel()
 
@@ -565,20 +829,97 @@ func TestIncludeWrite(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, "true", v)
 
-	// Check if the config was written correctly
+	// Check if the config was written correctly, the file had no final newline
 	expected := `[core]
 	int = 9
 	string = bar
 	bar = true
   [include]
-	path = foo.config
-`
+	path = foo.config`
 
 	actual, err := os.ReadFile(fn)
 	require.NoError(t, err)
 	assert.Equal(t, expected, string(actual))
 }
 
+func TestLineEndings(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "lf",
+			in:   "[core]\n\teditor = vim\n",
+			want: "[core]\n\tpager = less\n\teditor = nano\n",
+		},
+		{
+			name: "crlf",
+			in:   "[core]\r\n\teditor = vim\r\n",
+			want: "[core]\r\n\tpager = less\r\n\teditor = nano\r\n",
+		},
+		{
+			name: "crlf without final newline",
+			in:   "[core]\r\n\teditor = vim",
+			want: "[core]\r\n\tpager = less\r\n\teditor = nano",
+		},
+		{
+			name: "mostly crlf",
+			in:   "[core]\r\n\teditor = vim\n# comment\r\n",
+			want: "[core]\r\n\tpager = less\r\n\teditor = nano\r\n# comment\r\n",
+		},
+		{
+			name: "lf without final newline",
+			in:   "[core]\n\teditor = vim",
+			want: "[core]\n\tpager = less\n\teditor = nano",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fn := filepath.Join(t.TempDir(), "config")
+			require.NoError(t, os.WriteFile(fn, []byte(tc.in), 0o600))
+
+			c, err := LoadConfig(fn)
+			require.NoError(t, err)
+			// raw always uses "\n"
+			assert.NotContains(t, c.raw.String(), "\r")
+
+			require.NoError(t, c.Set("core.editor", "nano"))
+			require.NoError(t, c.Set("core.pager", "less"))
+
+			buf, err := os.ReadFile(fn)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(buf))
+		})
+	}
+}
+
+func TestUTF8BOM(t *testing.T) {
+	t.Parallel()
+
+	fn := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(fn, []byte("\ufeff[core]\r\n\teditor = vim\r\n"), 0o600))
+
+	c, err := LoadConfig(fn)
+	require.NoError(t, err)
+	assert.Empty(t, c.Warnings())
+	v, ok := c.Get("core.editor")
+	assert.True(t, ok)
+	assert.Equal(t, "vim", v)
+
+	require.NoError(t, c.Set("core.editor", "nano"))
+	buf, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, "\ufeff[core]\r\n\teditor = nano\r\n", string(buf))
+
+	// a BOM is only skipped at the start of the file
+	c = ParseConfig(strings.NewReader("[core]\n\ufeff[user]\n"))
+	assert.Len(t, c.Warnings(), 1)
+}
+
 func TestConditionalInclude(t *testing.T) {
 	t.Parallel()
 
@@ -681,6 +1022,10 @@ func TestUnescapeValue(t *testing.T) {
 			input:    "invalid\\xescape",
 			expected: "invalid\\xescape",
 		},
+		"escaped backslash before n": {
+			input:    "a\\\\nb",
+			expected: "a\\nb",
+		},
 	}
 
 	for name, tc := range tests {
@@ -690,3 +1035,833 @@ func TestUnescapeValue(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAllRange(t *testing.T) {
+	t.Parallel()
+
+	in := "[url \"git@example.com:\"]\n"
+	for i := range 10 {
+		in += fmt.Sprintf("\tinsteadOf = https://example.com/%d\n", i)
+	}
+	cfg := ParseConfig(strings.NewReader(in))
+
+	key := "url.git@example.com:.insteadof"
+	assert.Equal(t, 10, cfg.CountValues(key))
+	assert.Equal(t, 0, cfg.CountValues("url.missing.insteadof"))
+
+	vs, ok := cfg.GetAllRange(key, 2, 3)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"https://example.com/2", "https://example.com/3", "https://example.com/4"}, vs)
+
+	vs, ok = cfg.GetAllRange(key, 8, 5)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"https://example.com/8", "https://example.com/9"}, vs)
+
+	vs, ok = cfg.GetAllRange(key, 7, -1)
+	assert.True(t, ok)
+	assert.Len(t, vs, 3)
+
+	vs, ok = cfg.GetAllRange(key, 20, 5)
+	assert.True(t, ok)
+	assert.Empty(t, vs)
+
+	// appending to a page must not modify the stored values
+	vs, _ = cfg.GetAllRange(key, 0, 1)
+	_ = append(vs, "modified")
+	v, _ := cfg.GetAllRange(key, 1, 1)
+	assert.Equal(t, []string{"https://example.com/1"}, v)
+
+	_, ok = cfg.GetAllRange("url.missing.insteadof", 0, 1)
+	assert.False(t, ok)
+}
+
+func TestGetRaw(t *testing.T) {
+	t.Parallel()
+
+	in := `[core]
+	path = "C:\\Program Files\\Git" # windows
+	pattern = ^a\\d+$
+	plain = value
+	bare
+	multi = "first"
+	multi = second
+`
+	c := ParseConfig(strings.NewReader(in))
+	c.noWrites = true
+
+	for key, want := range map[string]string{
+		"core.path":    `"C:\\Program Files\\Git" # windows`,
+		"core.pattern": `^a\\d+$`,
+		"core.plain":   "value",
+		"core.bare":    "",
+		"core.multi":   `"first"`,
+	} {
+		v, ok := c.GetRaw(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, want, v, key)
+	}
+	v, _ := c.Get("core.path")
+	assert.Equal(t, `C:\Program Files\Git`, v)
+	_, ok := c.GetRaw("core.missing")
+	assert.False(t, ok)
+
+	// values written by Set are reported as written
+	require.NoError(t, c.Set("core.path", `D:\Tools`))
+	v, _ = c.GetRaw("core.path")
+	assert.Equal(t, `D:\\Tools # windows`, v)
+	require.NoError(t, c.Set("core.new", " spaced "))
+	v, _ = c.GetRaw("core.new")
+	assert.Equal(t, `" spaced "`, v)
+}
+
+func TestSetKeepsLayout(t *testing.T) {
+	t.Parallel()
+
+	in := "[user]\n  name  = John\n  email = john@example.com\n[core]\n  editor=vim\n  pager =less\n"
+	c := ParseConfig(strings.NewReader(in))
+	c.noWrites = true
+
+	// loading does not touch the layout
+	assert.Equal(t, in, c.raw.String())
+
+	require.NoError(t, c.Set("user.email", "john@example.org"))
+	require.NoError(t, c.Set("user.signingkey", "ABC"))
+	require.NoError(t, c.Set("core.editor", "nano"))
+
+	assert.Equal(t, "[user]\n  signingkey = ABC\n  name  = John\n  email = john@example.org\n[core]\n  editor=nano\n  pager =less\n", c.raw.String())
+}
+
+func TestBareKeys(t *testing.T) {
+	t.Parallel()
+
+	in := "[core]\n\tbare\n\tempty = \n\tvalue = x\n"
+	c := ParseConfig(strings.NewReader(in))
+	c.noWrites = true
+
+	// loading keeps bare keys and empty values apart
+	assert.Equal(t, in, c.raw.String())
+
+	v, found := c.Get("core.bare")
+	assert.True(t, found)
+	assert.Empty(t, v)
+	b, ok := c.GetBool("core.bare")
+	assert.True(t, ok)
+	assert.True(t, b)
+
+	// setting an empty value writes "key = " like git
+	require.NoError(t, c.Set("core.bare", ""))
+	assert.Contains(t, c.raw.String(), "\tbare = \n")
+
+	require.NoError(t, c.SetBare("core.value"))
+	assert.Contains(t, c.raw.String(), "\tvalue\n")
+	b, ok = c.GetBool("core.value")
+	assert.True(t, ok)
+	assert.True(t, b)
+
+	require.NoError(t, c.SetBare("other.flag"))
+	assert.Contains(t, c.raw.String(), "[other]\n\tflag\n")
+	b, ok = c.GetBool("other.flag")
+	assert.True(t, ok)
+	assert.True(t, b)
+}
+
+func TestSetRejectsLineBreaks(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader("[core]\n\tfoo = bar\n"))
+	c.noWrites = true
+
+	for _, v := range []string{"x\r\ny", "x\ry", "x\x00y"} {
+		require.ErrorIs(t, c.Set("core.foo", v), ErrInvalidValue, v)
+		require.ErrorIs(t, c.Set("core.new", v), ErrInvalidValue, v)
+	}
+
+	assert.Equal(t, "[core]\n\tfoo = bar\n", c.raw.String())
+	assert.False(t, c.IsSet("core.new"))
+
+	// newlines are escaped
+	require.NoError(t, c.Set("core.foo", "x\n[evil]\n\tkey = value"))
+	assert.Equal(t, "[core]\n\tfoo = x\\n[evil]\\n\\tkey = value\n", c.raw.String())
+}
+
+func TestSetRejectsNewlinesWithoutUnescaping(t *testing.T) {
+	CompatMode = true
+	t.Cleanup(func() { CompatMode = false })
+
+	c := ParseConfig(strings.NewReader("[core]\n\tfoo = bar\n"))
+	c.noWrites = true
+
+	require.ErrorIs(t, c.Set("core.foo", "x\ny"), ErrInvalidValue)
+	assert.Equal(t, "[core]\n\tfoo = bar\n", c.raw.String())
+}
+
+// FuzzSetRoundTrip checks that a config written by Set always re-parses to
+// the same vars.
+func FuzzSetRoundTrip(f *testing.F) {
+	for _, v := range []string{"value", "x\ny", "[weird]", "a = b", "", " spaced ", "\r", "with # comment", `"quoted"`, `back\slash`, "\u00a0nbsp"} {
+		f.Add(v)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		for _, key := range []string{"core.foo", "core.new", "new.sub.key"} {
+			c := ParseConfig(strings.NewReader("[core]\n\tfoo = bar\n\tother = baz\n[user]\n\tname = John\n"))
+			c.noWrites = true
+
+			if err := c.Set(key, value); err != nil {
+				require.ErrorIs(t, err, ErrInvalidValue)
+
+				continue
+			}
+
+			reparsed := ParseConfig(strings.NewReader(c.raw.String()))
+			assert.Equal(t, c.vars, reparsed.vars, "raw: %q", c.raw.String())
+			v, _ := reparsed.Get(key)
+			assert.Equal(t, value, v, "raw: %q", c.raw.String())
+		}
+	})
+}
+
+func TestMultiLineQuotedValue(t *testing.T) {
+	t.Parallel()
+
+	in := "[core]\n\tmsg = \"first\n second\" # comment\n\tafter = x\n[user]\n\tname = John\n"
+	c := ParseConfig(strings.NewReader(in))
+	c.noWrites = true
+
+	// the physical layout is kept
+	assert.Equal(t, in, c.raw.String())
+
+	v, found := c.Get("core.msg")
+	assert.True(t, found)
+	assert.Equal(t, "first\n second", v)
+	v, _ = c.Get("core.after")
+	assert.Equal(t, "x", v)
+	assert.Equal(t, 4, c.origin("core.after", 0).line)
+	assert.Empty(t, c.issues)
+
+	// rewriting other keys keeps the multi-line value
+	require.NoError(t, c.Set("user.name", "Jane"))
+	assert.Contains(t, c.raw.String(), "\tmsg = \"first\n second\" # comment\n\tafter = x\n")
+
+	// rewriting the key replaces all of its lines
+	require.NoError(t, c.Set("core.msg", "single"))
+	assert.Equal(t, "[core]\n\tmsg = single # comment\n\tafter = x\n[user]\n\tname = Jane\n", c.raw.String())
+
+	c = ParseConfig(strings.NewReader(in))
+	c.noWrites = true
+	require.NoError(t, c.Unset("core.msg"))
+	assert.Equal(t, "[core]\n\tafter = x\n[user]\n\tname = John\n", c.raw.String())
+}
+
+func TestUnterminatedQuotedValue(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader("[core]\n\tmsg = \"open\n\tafter = x\n"))
+
+	v, _ := c.Get("core.after")
+	assert.Equal(t, "x", v)
+	require.Len(t, c.issues, 1)
+	assert.Equal(t, "line 2: unterminated quoted value", c.issues[0].String())
+}
+
+func TestHasOpenQuote(t *testing.T) {
+	t.Parallel()
+
+	for line, want := range map[string]bool{
+		`key = "open`:           true,
+		`key = "closed"`:        false,
+		`key = "esc \" still`:   true,
+		`key = x # "comment`:    false,
+		`key = "a # b`:          true,
+		`# key = "comment`:      false,
+		`[section "sub"]`:       false,
+		`key = a\"b`:            false,
+		"key = \"multi\nline\"": false,
+	} {
+		assert.Equal(t, want, hasOpenQuote(line), line)
+	}
+}
+
+func TestValuesLookingLikeHeaders(t *testing.T) {
+	t.Parallel()
+
+	in := "[core]\n\tweird = [weird]\n\tmsg = \"a\n[user]\n\tname = x\"\n[user]\n\tname = John\n"
+	c := ParseConfig(strings.NewReader(in))
+	c.noWrites = true
+
+	v, _ := c.Get("core.weird")
+	assert.Equal(t, "[weird]", v)
+	v, _ = c.Get("core.msg")
+	assert.Equal(t, "a\n[user]\n\tname = x", v)
+	v, _ = c.Get("user.name")
+	assert.Equal(t, "John", v)
+
+	// new keys go below the real header, not into the quoted value
+	require.NoError(t, c.Set("user.email", "john@example.com"))
+	require.NoError(t, c.Set("core.other", "[other]"))
+	assert.Equal(t, "[core]\n\tother = [other]\n\tweird = [weird]\n\tmsg = \"a\n[user]\n\tname = x\"\n[user]\n\temail = john@example.com\n\tname = John\n", c.raw.String())
+
+	// rewriting keeps the quoted value intact
+	require.NoError(t, c.Set("user.name", "Jane"))
+	require.NoError(t, c.Set("core.weird", "[still weird]"))
+	reparsed := ParseConfig(strings.NewReader(c.raw.String()))
+	assert.Equal(t, c.vars, reparsed.vars)
+}
+
+func TestSectionHeader(t *testing.T) {
+	t.Parallel()
+
+	for line, want := range map[string]string{
+		`[core]`:                 `[core]`,
+		`[core] # comment`:       `[core]`,
+		`[core]; comment`:        `[core]`,
+		`[url "a=b]c"]`:          `[url "a=b]c"]`,
+		`[sub "esc \" ] quote"]`: `[sub "esc \" ] quote"]`,
+	} {
+		got, ok := sectionHeader(line)
+		assert.True(t, ok, line)
+		assert.Equal(t, want, got, line)
+	}
+
+	for _, line := range []string{`[core`, `[core] key = value`, `[url "open]`, `key = [core]`} {
+		_, ok := sectionHeader(line)
+		assert.False(t, ok, line)
+	}
+
+	c := ParseConfig(strings.NewReader("[core\n\tkey = value\n[url \"a=b\"] # c\n\tinsteadof = x\n"))
+	v, _ := c.Get("url.a=b.insteadof")
+	assert.Equal(t, "x", v)
+	require.Len(t, c.issues, 1)
+	assert.Equal(t, "line 1: invalid section header", c.issues[0].String())
+}
+
+func TestValidKeyName(t *testing.T) {
+	t.Parallel()
+
+	for k, want := range map[string]bool{
+		"name":     true,
+		"a1-b":     true,
+		"x":        true,
+		"":         false,
+		"1nvalid":  false,
+		"-dash":    false,
+		"under_sc": false,
+		"Upper":    false,
+		"dot.ted":  false,
+	} {
+		assert.Equal(t, want, validKeyName(k), k)
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string][2]string{
+		`plain value`:                  {"plain value", ""},
+		`"quoted # not a comment" # c`: {"quoted # not a comment", " # c"},
+		`a "b  c" d ; c`:               {"a b  c d", " ; c"},
+		"a\t\tb":                       {"a  b", ""},
+		`" lead and trail "`:           {" lead and trail ", ""},
+		`esc\"aped\\n\n\t\b`:           {"esc\"aped\\n\n\t\b", ""},
+		`unknown \q`:                   {`unknown \q`, ""},
+		`"" # only comment`:            {"", " # only comment"},
+		`mid"quo"te`:                   {"midquote", ""},
+	} {
+		v, comment := parseValue(in)
+		assert.Equal(t, want[0], v, in)
+		assert.Equal(t, want[1], comment, in)
+	}
+}
+
+func TestQuoteValue(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"":               "",
+		"plain value":    "plain value",
+		" lead":          `" lead"`,
+		"trail\t":        `"trail\t"`,
+		"a # b":          `"a # b"`,
+		"a;b":            `"a;b"`,
+		`say "hi"`:       `say \"hi\"`,
+		`C:\path`:        `C:\\path`,
+		"multi\nline":    `multi\nline`,
+		"tab\tand\bback": `tab\tand\bback`,
+	} {
+		assert.Equal(t, want, QuoteValue(in), in)
+
+		v, _ := parseValue(QuoteValue(in))
+		assert.Equal(t, in, v, in)
+	}
+}
+
+func TestLongLines(t *testing.T) {
+	// lines longer than the default buffer of bufio.Scanner
+	long := strings.Repeat("x", 100*1024)
+	c := ParseConfig(strings.NewReader("[core]\n\tlong = " + long + "\n\tafter = 1\n"))
+	v, _ := c.Get("core.long")
+	assert.Equal(t, long, v)
+	v, _ = c.Get("core.after")
+	assert.Equal(t, "1", v)
+	assert.Empty(t, c.Warnings())
+
+	in := "[core]\n\tbefore = 1\n\tlong = " + long + "\n\tafter = 1\n"
+	opts := parseOptions{maxLineLength: 1024}
+
+	// the lines before the long line are kept
+	c, err := parseConfig(strings.NewReader(in), opts)
+	require.Error(t, err)
+	v, _ = c.Get("core.before")
+	assert.Equal(t, "1", v)
+	assert.False(t, c.IsSet("core.after"))
+	assert.Equal(t, []Warning{{Line: 3, Reason: "line is longer than 1024 bytes, ignoring the rest of the file"}}, c.Warnings())
+
+	fn := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(fn, []byte(in), 0o600))
+
+	_, err = loadConfigs(fn, "", opts)
+	require.ErrorIs(t, err, ErrParse)
+	var perr *ParseError
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, fn, perr.Path)
+	assert.Equal(t, 3, perr.Line)
+
+	// a negative value disables the limit
+	c, err = loadConfigs(fn, "", parseOptions{maxLineLength: -1})
+	require.NoError(t, err)
+	v, _ = c.Get("core.after")
+	assert.Equal(t, "1", v)
+
+	// the limit only applies to reading, Set can write longer lines
+	c, err = parseConfig(strings.NewReader("[core]\n\tbefore = 1\n"), opts)
+	require.NoError(t, err)
+	c.noWrites = true
+	require.NoError(t, c.Set("core.long", long))
+	require.NoError(t, c.Set("core.after", "1"))
+	v, _ = c.Get("core.long")
+	assert.Equal(t, long, v)
+	v, _ = c.Get("core.after")
+	assert.Equal(t, "1", v)
+}
+
+func TestBinaryContent(t *testing.T) {
+	t.Parallel()
+
+	in := "[core]\n\teditor = vim\n\x00\x01\x02garbage\n"
+
+	c := ParseConfig(strings.NewReader(in))
+	assert.Empty(t, c.vars)
+	assert.Equal(t, []Warning{{Line: 3, Reason: "NUL byte, not a config file"}}, c.Warnings())
+
+	_, err := ParseConfigStrict(strings.NewReader(in))
+	require.ErrorIs(t, err, ErrNotAConfigFile)
+
+	fn := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(fn, []byte(in), 0o600))
+
+	_, err = LoadConfig(fn)
+	require.ErrorIs(t, err, ErrNotAConfigFile)
+	assert.Contains(t, err.Error(), fn)
+
+	_, err = LoadConfigMapped(fn)
+	require.ErrorIs(t, err, ErrNotAConfigFile)
+}
+
+func TestCommentsFollowKeys(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader("[core]\n\t# managed by setup\n\teditor = vim # inline\n\t# the pager\n\tpager = less\n"))
+	c.noWrites = true
+
+	require.NoError(t, c.Set("core.editor", "nano"))
+	require.NoError(t, c.Set("core.autocrlf", "input"))
+	require.NoError(t, c.Unset("core.pager"))
+
+	assert.Equal(t, "[core]\n\t# managed by setup\n\tautocrlf = input\n\teditor = nano # inline\n", c.raw.String())
+}
+
+func TestGetComment(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader("[core]\n\teditor = vim # keep me\n\tpager = less ;  semicolon\n\tplain = x\n\tquoted = \"a # b\" # c\n"))
+	c.noWrites = true
+
+	for key, want := range map[string]string{
+		"core.editor": "keep me",
+		"core.pager":  "semicolon",
+		"core.quoted": "c",
+	} {
+		got, ok := c.GetComment(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, want, got, key)
+	}
+	_, ok := c.GetComment("core.plain")
+	assert.False(t, ok)
+	_, ok = c.GetComment("core.missing")
+	assert.False(t, ok)
+
+	// Set only rewrites the value
+	for _, key := range []string{"core.editor", "core.pager", "core.quoted"} {
+		require.NoError(t, c.Set(key, "new value"))
+	}
+	assert.Equal(t, "[core]\n\teditor = new value # keep me\n\tpager = new value ;  semicolon\n\tplain = x\n"+
+		"\tquoted = new value # c\n", c.raw.String())
+	got, _ := c.GetComment("core.editor")
+	assert.Equal(t, "keep me", got)
+
+	// the same holds in compat mode
+	c = ParseConfig(strings.NewReader("[core]\n\tquoted = \"a # b\" # c\n"))
+	c.noWrites = true
+	c.SetCompatMode(true)
+	require.NoError(t, c.Set("core.quoted", "d"))
+	assert.Equal(t, "[core]\n\tquoted = d # c\n", c.raw.String())
+}
+
+func TestSetIncludedKey(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	fn := filepath.Join(td, "config")
+	require.NoError(t, os.WriteFile(fn, []byte("[include]\n\tpath = included\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "included"), []byte("[core]\n\teditor = vim # from the include\n"), 0o600))
+
+	c, err := LoadConfig(fn)
+	require.NoError(t, err)
+	got, _ := c.GetComment("core.editor")
+	assert.Equal(t, "from the include", got)
+
+	// the value is written to the include that defines it
+	require.NoError(t, c.Set("core.editor", "nano"))
+	got, _ = c.GetComment("core.editor")
+	assert.Equal(t, "from the include", got)
+
+	buf, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, "[include]\n\tpath = included\n", string(buf))
+	buf, err = os.ReadFile(filepath.Join(td, "included"))
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\teditor = nano # from the include\n", string(buf))
+
+	c, err = LoadConfig(fn)
+	require.NoError(t, err)
+	v, _ := c.Get("core.editor")
+	assert.Equal(t, "nano", v)
+	origins := c.Origins("core.editor")
+	require.Len(t, origins, 1)
+	assert.Equal(t, filepath.Join(td, "included"), origins[0].Path)
+
+	// unless it is forced to the file itself, the include is not touched
+	c.SetWriteToTopLevel(true)
+	require.NoError(t, c.Set("core.editor", "emacs"))
+	_, ok := c.GetComment("core.editor")
+	assert.False(t, ok)
+
+	buf, err = os.ReadFile(filepath.Join(td, "included"))
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\teditor = nano # from the include\n", string(buf))
+
+	c, err = LoadConfig(fn)
+	require.NoError(t, err)
+	v, _ = c.Get("core.editor")
+	assert.Equal(t, "emacs", v)
+}
+
+func TestSetIncludedKeyChanged(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	fn := filepath.Join(td, "config")
+	inc := filepath.Join(td, "included")
+	require.NoError(t, os.WriteFile(fn, []byte("[include]\n\tpath = included\n"), 0o600))
+	require.NoError(t, os.WriteFile(inc, []byte("[core]\n\teditor = vim\n"), 0o600))
+
+	c, err := LoadConfig(fn)
+	require.NoError(t, err)
+
+	// the include changed since loading, so the key is written to the
+	// file itself
+	require.NoError(t, os.WriteFile(inc, []byte("# moved\n[core]\n\teditor = vim\n"), 0o600))
+	require.NoError(t, c.Set("core.editor", "nano"))
+
+	buf, err := os.ReadFile(inc)
+	require.NoError(t, err)
+	assert.Equal(t, "# moved\n[core]\n\teditor = vim\n", string(buf))
+
+	c, err = LoadConfig(fn)
+	require.NoError(t, err)
+	v, _ := c.Get("core.editor")
+	assert.Equal(t, "nano", v)
+}
+
+func TestSetIncludedKeyFailed(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	fn := filepath.Join(td, "config")
+	inc := filepath.Join(td, "included")
+	require.NoError(t, os.WriteFile(fn, []byte("[include]\n\tpath = included\n"), 0o600))
+	require.NoError(t, os.WriteFile(inc, []byte("[core]\n\teditor = vim\n"), 0o600))
+
+	c, err := LoadConfig(fn)
+	require.NoError(t, err)
+
+	// the include can not be read anymore, nothing is changed
+	require.NoError(t, os.Remove(inc))
+	require.NoError(t, os.Mkdir(inc, 0o700))
+	require.ErrorIs(t, c.Set("core.editor", "nano"), ErrWriteConfig)
+	v, _ := c.Get("core.editor")
+	assert.Equal(t, "vim", v)
+	buf, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, "[include]\n\tpath = included\n", string(buf))
+
+	// the include is gone, so the key is written to the file itself
+	require.NoError(t, os.Remove(inc))
+	require.NoError(t, c.Set("core.editor", "nano"))
+	v, _ = c.Get("core.editor")
+	assert.Equal(t, "nano", v)
+
+	c, err = LoadConfig(fn)
+	require.NoError(t, err)
+	v, _ = c.Get("core.editor")
+	assert.Equal(t, "nano", v)
+}
+
+const keyCaseTestConfig = `[Core]
+	Editor = vim
+[Foo.Bar]
+	x = 1
+[Remote "Origin"]
+	URL = u
+`
+
+func TestKeyCaseRules(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader(keyCaseTestConfig))
+	c.noWrites = true
+
+	// sections and names are folded, subsections only in the dotted syntax
+	for key, want := range map[string]bool{
+		"core.editor":       true,
+		"CORE.EDITOR":       true,
+		"foo.bar.x":         true,
+		"FOO.bar.X":         true,
+		"foo.Bar.x":         false,
+		"remote.Origin.url": true,
+		"REMOTE.Origin.URL": true,
+		"remote.origin.url": false,
+	} {
+		assert.Equal(t, want, c.IsSet(key), key)
+	}
+
+	// new values go into the existing sections, new lines and headers keep
+	// the spelling of the key
+	require.NoError(t, c.Set("Core.Pager", "less"))
+	require.NoError(t, c.Set("foo.bar.y", "2"))
+	require.NoError(t, c.Set("Remote.Origin.Fetch", "f"))
+	require.NoError(t, c.Set("New.Sub.Key", "v"))
+	require.NoError(t, c.Unset("CORE.EDITOR"))
+
+	assert.Equal(t, "[Core]\n\tPager = less\n[Foo.Bar]\n\ty = 2\n\tx = 1\n[Remote \"Origin\"]\n\tFetch = f\n\tURL = u\n"+
+		"[New \"Sub\"]\n\tKey = v\n", c.raw.String())
+
+	v, ok := c.Get("core.pager")
+	assert.True(t, ok)
+	assert.Equal(t, "less", v)
+	assert.True(t, c.IsSet("new.Sub.key"))
+	assert.False(t, c.IsSet("new.sub.key"))
+
+	// changing a value with a different spelling updates the line
+	require.NoError(t, c.Set("CORE.pager", "more"))
+	assert.Equal(t, 1, c.CountValues("core.pager"))
+	assert.Contains(t, c.raw.String(), "\tPager = more\n")
+}
+
+// TestKeyCaseRulesGitConformance checks the case rules against the git binary.
+func TestKeyCaseRulesGitConformance(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	t.Parallel()
+
+	fn := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(fn, []byte(keyCaseTestConfig), 0o600))
+
+	c, err := LoadConfig(fn)
+	require.NoError(t, err)
+	require.NoError(t, c.Set("Core.Pager", "less"))
+	require.NoError(t, c.Set("foo.bar.y", "2"))
+	require.NoError(t, c.Set("Remote.Origin.Fetch", "f"))
+	require.NoError(t, c.Unset("CORE.EDITOR"))
+
+	out, err := exec.Command("git", "config", "--file", fn, "--list").Output()
+	require.NoError(t, err)
+	theirs := strings.Split(strings.TrimSpace(string(out)), "\n")
+
+	ours := make([]string, 0, len(c.vars))
+	for k, vs := range c.vars {
+		for _, v := range vs {
+			ours = append(ours, k+"="+v)
+		}
+	}
+	slices.Sort(ours)
+	slices.Sort(theirs)
+	assert.Equal(t, theirs, ours)
+
+	for _, key := range []string{"core.pager", "CORE.PAGER", "foo.bar.x", "foo.Bar.x", "remote.Origin.url", "remote.origin.url"} {
+		err := exec.Command("git", "config", "--file", fn, "--get", key).Run()
+		assert.Equal(t, err == nil, c.IsSet(key), key)
+	}
+}
+
+func TestSetValidatesKey(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		key string
+		err string
+	}{
+		{key: "core.editor"},
+		{key: "Core.Editor"},
+		{key: "my-section.key-2"},
+		{key: `remote.has space "and quotes".url`},
+		{key: "has space.key", err: `invalid key: invalid section name "has space" in has space.key`},
+		{key: "my_section.key", err: `invalid key: invalid section name "my_section" in my_section.key`},
+		{key: "core.2key", err: `invalid key: invalid variable name "2key" in core.2key`},
+		{key: "core.my_key", err: `invalid key: invalid variable name "my_key" in core.my_key`},
+		{key: "remote.a\nb.url", err: `invalid key: invalid subsection name "a\nb" in remote.a` + "\n" + `b.url`},
+		{key: "core", err: "invalid key: core"},
+	} {
+		c := &Config{noWrites: true}
+		err := c.Set(tc.key, "v")
+		if tc.err == "" {
+			require.NoError(t, err, tc.key)
+			assert.True(t, c.IsSet(tc.key), tc.key)
+
+			continue
+		}
+		require.ErrorIs(t, err, ErrInvalidKey, tc.key)
+		assert.Equal(t, tc.err, err.Error(), tc.key)
+		assert.False(t, c.IsSet(tc.key), tc.key)
+		assert.Empty(t, c.raw.String(), tc.key)
+
+		// Marshal uses the same rules
+		require.ErrorIs(t, c.replaceAll(tc.key, []string{"a", "b"}), ErrInvalidKey, tc.key)
+	}
+}
+
+// TestGitdirMatch uses the results of git 2.x for a repository in
+// /tmp/gd/work/proj.
+func TestGitdirMatch(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("uses unix paths")
+	}
+
+	gitDir := "/tmp/gd/work/proj/.git"
+	for pattern, want := range map[string]bool{
+		"/tmp/gd/work/**":        true,
+		"work/proj/":             true,
+		"proj/":                  true,
+		"proj":                   false,
+		"/tmp/gd/*/proj/":        true,
+		"/tmp/gd/w?rk/":          true,
+		"/tmp/gd/work/proj":      false,
+		"/tmp/gd/work/proj/.git": true,
+		"**/proj/**":             true,
+		"/tmp/gd/**/proj/":       true,
+		"/tmp/gd/work/":          true,
+		"/tmp/gd/":               true,
+		"gd/*/":                  true,
+		"/tmp/gd/*/":             true,
+		"/tmp/gd/*":              false,
+		"/tmp/gd/WORK/":          false,
+		"/other/":                false,
+	} {
+		assert.Equal(t, want, gitdirMatch(pattern, gitDir, false), pattern)
+	}
+
+	assert.True(t, gitdirMatch("/tmp/gd/WORK/", gitDir, true))
+	assert.True(t, gitdirMatch("/tmp/gd/work/proj/", "/tmp/gd/work/proj", false))
+	assert.False(t, gitdirMatch("/tmp/gd/[work/", gitDir, false))
+}
+
+func TestRelativeGitdirCondition(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	repo := filepath.Join(td, "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(td, "sub"), 0o755))
+
+	fn := filepath.Join(td, "config")
+	require.NoError(t, os.WriteFile(fn, []byte("[include]\n\tpath = sub/inc.config\n[includeIf \"gitdir:./repo/\"]\n\tpath = a.config\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "a.config"), []byte("[user]\n\tname = a\n"), 0o600))
+	// relative to sub, so it does not match
+	require.NoError(t, os.WriteFile(filepath.Join(td, "sub", "inc.config"), []byte("[includeIf \"gitdir:./repo/\"]\n\tpath = b.config\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "sub", "b.config"), []byte("[user]\n\temail = b\n"), 0o600))
+
+	cfg, err := LoadConfigWithWorkdir(fn, repo)
+	require.NoError(t, err)
+	assert.Equal(t, "a", cfg.vars["user.name"][0])
+	assert.NotContains(t, cfg.vars, "user.email")
+
+	// without a file there is nothing to be relative to
+	assert.Equal(t, "./repo/", resolveGitdirPattern("./repo/", &Config{}))
+	assert.Equal(t, "repo/", resolveGitdirPattern("repo/", &Config{path: fn}))
+	assert.Equal(t, filepath.ToSlash(td)+"/repo/", resolveGitdirPattern("./repo/", &Config{path: fn}))
+}
+
+func TestConfigReloadBranch(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	repo := filepath.Join(td, "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0o755))
+	head := filepath.Join(repo, ".git", "HEAD")
+	require.NoError(t, os.WriteFile(head, []byte("ref: refs/heads/main\n"), 0o644))
+
+	// the onbranch condition is in a nested include
+	fn := filepath.Join(td, "config")
+	require.NoError(t, os.WriteFile(fn, []byte("[core]\n\tint = 1\n[include]\n\tpath = branches.config\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "branches.config"), []byte("[includeIf \"onbranch:feat/\"]\n\tpath = feat.config\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "feat.config"), []byte("[core]\n\tint = 2\n"), 0o600))
+
+	cfg, err := LoadConfigWithWorkdir(fn, repo)
+	require.NoError(t, err)
+	get := func() []string {
+		vs, _ := cfg.GetAll("core.int")
+
+		return vs
+	}
+	assert.Equal(t, "main", cfg.Branch())
+	assert.Equal(t, []string{"1"}, get())
+
+	// switching the branch takes effect on reload
+	require.NoError(t, os.WriteFile(head, []byte("ref: refs/heads/feat/x\n"), 0o644))
+	assert.Equal(t, []string{"1"}, get())
+	require.NoError(t, cfg.Reload())
+	assert.Equal(t, "feat/x", cfg.Branch())
+	assert.Equal(t, []string{"1", "2"}, get())
+
+	require.NoError(t, cfg.SetBranch("main"))
+	assert.Equal(t, "main", cfg.Branch())
+	assert.Equal(t, []string{"1"}, get())
+
+	// the branch set is kept on reload until it is cleared
+	require.NoError(t, cfg.Reload())
+	assert.Equal(t, []string{"1"}, get())
+	require.NoError(t, cfg.SetBranch(""))
+	assert.Equal(t, []string{"1", "2"}, get())
+
+	// a failed reload keeps the config
+	require.NoError(t, os.Remove(fn))
+	require.Error(t, cfg.SetBranch("main"))
+	assert.Equal(t, "feat/x", cfg.Branch())
+	assert.Equal(t, []string{"1", "2"}, get())
+
+	require.ErrorIs(t, ParseConfig(strings.NewReader("[core]\n\tint = 1\n")).Reload(), ErrNoConfigFile)
+	require.ErrorIs(t, ParseConfig(strings.NewReader("[core]\n\tint = 1\n")).SetBranch("main"), ErrNoConfigFile)
+}
@@ -1,15 +1,16 @@
 package gitconfig
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
-
-	"github.com/gopasspw/gopass/pkg/appdir"
-	"github.com/gopasspw/gopass/pkg/debug"
-	"github.com/gopasspw/gopass/pkg/set"
+	"sync"
+	"sync/atomic"
 )
 
 // Configs represents all git configuration files for a repository.
@@ -18,21 +19,37 @@ import (
 // interface. It handles loading and merging configurations from multiple sourc with priority.
 //
 // Scope Priority (highest to lowest):
-// 1. Environment variables (GIT_CONFIG_*)
-// 2. Worktree-specific config (.git/config.worktree)
-// 3. Local/repository config (.git/config)
-// 4. Global/user config (~/.gitconfig)
-// 5. System config (/etc/gitconfig)
-// 6. Preset/built-in defaults
+// 1. Overlay read from a stream, e.g. stdin (see LoadConfigFromReader)
+// 2. Environment variables (GIT_CONFIG_*)
+// 3. Worktree-specific config (.git/config.worktree)
+// 4. Local/repository config (.git/config)
+// 5. Global/user config (~/.gitconfig)
+// 6. System config (/etc/gitconfig)
+// 7. Preset/built-in defaults
 //
 // Fields:
-// - Preset: Built-in default configuration (optional)
-// - system, global, local, worktree, env: Config objects for each scope
+// - Preset: Built-in default configuration (optional, see also SetPresets)
+// - system, global, local, worktree, env, overlay: Config objects for each scope
 // - workdir: Working directory (used to locate local and worktree configs)
 // - Name: Configuration set name (e.g., "git" or "gopass")
-// - SystemConfig, GlobalConfig, LocalConfig, WorktreeConfig: File paths
+// - SystemConfig, GlobalConfig, LocalConfig, WorktreeConfig: File paths, local and worktree paths below ".git" follow a .git file (see repoConfigPath)
 // - EnvPrefix: Prefix for environment variables (e.g., "GIT_CONFIG")
 // - NoWrites: If true, prevents all writes to disk
+// - Comparison: How Set decides if a value is unchanged (see ValueComparison)
+// - KeyRules: Which parts of a key are case-insensitive, git's rules by default (see KeyRules)
+// - OnDeprecated: Called once per process for every deprecated key that is read (see Deprecate)
+// - Strict: If true, config files with syntax errors are rejected and their scope is read-only (see ParseError)
+// - FailOnPermissionDenied: If true, config files that exist but can not be read are reported by LoadReport.Err
+// - FailOnCircularInclude: If true, a scope whose includes form a cycle fails to load with a *CircularIncludeError
+// - WriteToTopLevel: If true, Set writes keys defined in included files to the top-level file of the scope (see Config.SetWriteToTopLevel)
+// - IncludeErrors: What to do with included files that can not be read, they are ignored like git does by default (see IncludeErrorPolicy)
+// - MaxIncludeDepth: Limits how deeply includes may be nested, i.e. how many files there may be between the loaded file and an included one. Like git, deeper includes fail to load with an *IncludeDepthError. Zero uses git's default of 10, a negative value disables the limit
+// - MaxIncludes: Limits the number of files pulled in through includes per scope, further includes are skipped and reported in the LoadReport. Zero uses the default of 100, a negative value disables the limit
+// - MaxLineLength: Limits the length of a single line of a config file, in bytes. Files with longer lines fail to load with a *ParseError. Zero uses the default of 16 MiB, a negative value disables the limit
+// - AllowSystemWrites: If true, the system config can be written with SetSystem and UnsetSystem, it is read-only by default. Set it before LoadAll
+// - EnableWorktreeConfig: If true, SetWorktree enables extensions.worktreeConfig in the local config instead of failing if it is not enabled yet
+// - SensitiveKeyNames: Key names whose values ExportBundle redacts, nil uses DefaultSensitiveKeyNames
+// - GitEnv: If true, GIT_DIR, GIT_COMMON_DIR and GIT_WORK_TREE locate the repository like git does: local and worktree paths below ".git" and gitdir and onbranch conditions use GIT_DIR, and GIT_WORK_TREE is the workdir if LoadAll gets none
 //
 // Usage:
 //
@@ -47,6 +64,7 @@ type Configs struct {
 	local    *Config
 	worktree *Config
 	env      *Config
+	overlay  *Config
 	workdir  string
 
 	Name           string
@@ -56,6 +74,37 @@ type Configs struct {
 	WorktreeConfig string
 	EnvPrefix      string
 	NoWrites       bool
+	Comparison     ValueComparison
+	KeyRules       KeyRules
+	OnDeprecated   func(Deprecation)
+	Strict         bool
+
+	FailOnPermissionDenied bool
+	FailOnCircularInclude  bool
+	IncludeErrors          IncludeErrorPolicy
+	MaxIncludeDepth        int
+	MaxIncludes            int
+	MaxLineLength          int
+	WriteToTopLevel        bool
+	GitEnv                 bool
+	AllowSystemWrites      bool
+	EnableWorktreeConfig   bool
+	SensitiveKeyNames      []string
+
+	subs         []*subscription
+	report       LoadReport
+	deprecations map[string]Deprecation
+	presets      *Presets
+
+	cipher        Cipher
+	encryptedKeys map[string]bool
+	compatMode    *bool        // see SetCompatMode
+	compatLevel   *compatState // see SetCompatLevel
+	branch        string       // see SetBranch
+	repo          repoEnv      // see GitEnv
+
+	loadMu     sync.Mutex    // serializes LoadAll and Reload
+	generation atomic.Uint64 // incremented by every LoadAll and Reload
 }
 
 // New creates a new Configs instance with default configuration.
@@ -103,9 +152,35 @@ func New() *Configs {
 // Reload reloads all configuration files from disk.
 //
 // This is useful when configuration files have been modified externally.
-// Uses the same workdir that was provided to the last LoadAll call.
+// Uses the same workdir that was provided to the last LoadAll call. The
+// branch is read again from the workdir, unless it was set with SetBranch,
+// and all includeIf conditions are evaluated again.
+// Concurrent calls to Reload and LoadAll are serialized and each one
+// increments the Generation.
 func (cs *Configs) Reload() {
-	cs.LoadAll(cs.workdir)
+	cs.loadMu.Lock()
+	defer cs.loadMu.Unlock()
+
+	cs.load(cs.workdir)
+}
+
+// SetBranch sets the branch that onbranch conditions are matched against,
+// instead of reading it from HEAD in the workdir, and reloads all configs
+// to evaluate their includeIf conditions again. This is useful if the
+// caller already knows the branch, e.g. in a hook. An empty name reads the
+// branch from the workdir again.
+//
+// Example:
+//
+//	cfg := gitconfig.New()
+//	cfg.LoadAll("/path/to/repo")
+//	cfg.SetBranch("release/1.0")
+func (cs *Configs) SetBranch(name string) {
+	cs.loadMu.Lock()
+	defer cs.loadMu.Unlock()
+
+	cs.branch = name
+	cs.load(cs.workdir)
 }
 
 // String implements fmt.Stringer for debugging.
@@ -132,64 +207,148 @@ func (cs *Configs) String() string {
 //	cfg.LoadAll("/path/to/repo")
 //	// Now ready to use Get, Set, etc.
 func (cs *Configs) LoadAll(workdir string) *Configs {
+	cs.loadMu.Lock()
+	defer cs.loadMu.Unlock()
+
+	cs.load(workdir)
+
+	return cs
+}
+
+// load loads all configs, notifies subscribers and bumps the generation.
+// The caller must hold loadMu.
+func (cs *Configs) load(workdir string) {
+	_ = cs.notifying(func() error {
+		cs.loadAll(workdir)
+
+		return nil
+	})
+	cs.generation.Add(1)
+}
+
+// Generation returns the number of completed LoadAll and Reload calls.
+// Long running operations can record the generation when they start and
+// compare it later to detect that the configuration was reloaded in the
+// meantime. It is safe to call Generation concurrently with Reload.
+//
+// Example:
+//
+//	gen := cfg.Generation()
+//	// ... long running operation ...
+//	if cfg.Generation() != gen {
+//		// configuration changed, restart
+//	}
+func (cs *Configs) Generation() uint64 {
+	return cs.generation.Load()
+}
+
+func (cs *Configs) loadAll(workdir string) {
+	cs.repo = repoEnv{}
+	if cs.GitEnv {
+		cs.repo = readRepoEnv()
+		if workdir == "" {
+			workdir = cs.repo.workTree
+		}
+	}
 	cs.workdir = workdir
+	cs.report = LoadReport{}
 
 	debug.Log("Loading gitconfigs for %s", cs.Name)
 
 	// load the system config, if any
 	if os.Getenv(cs.EnvPrefix+"_NOSYSTEM") == "" {
-		c, err := LoadConfig(cs.SystemConfig)
-		if err != nil {
+		c, err := cs.loadConfig(cs.SystemConfig)
+		cs.report.add(ScopeSystem, []string{cs.SystemConfig}, c, err)
+		switch {
+		case isUnusable(err):
 			debug.V(1).Log("[%s] failed to load system config: %s", cs.Name, err)
-		} else {
+			cs.system = &Config{path: cs.SystemConfig, readonly: true}
+		case err != nil:
+			debug.V(1).Log("[%s] failed to load system config: %s", cs.Name, err)
+			// set the path in case writes are allowed, see SetSystem. Only a
+			// missing file may be created, any other file could not be loaded
+			// and must not be overwritten.
+			cs.system = &Config{path: cs.SystemConfig, readonly: !cs.AllowSystemWrites || !isMissing(err)}
+		default:
 			debug.V(1).Log("[%s] loaded system config from %s", cs.Name, cs.SystemConfig)
 			cs.system = c
 			// the system config should generally not be written from gopass.
 			// in almost any scenario gopass shouldn't have write access
 			// and even if it does we shouldn't accidentially change it.
-			// It's for operators and package mainatiners.
-			cs.system.readonly = true
+			// It's for operators and package mainatiners, e.g. provisioning
+			// tools, which have to opt in with AllowSystemWrites.
+			cs.system.readonly = !cs.AllowSystemWrites
 		}
+		cs.applyScopeSettings(cs.system)
+	} else {
+		cs.report.Scopes = append(cs.report.Scopes, ScopeReport{Scope: ScopeSystem, Attempted: []string{}, Skipped: true, ReadOnly: !cs.AllowSystemWrites})
 	}
 
 	// load the "global" (per user) config, if any
-	cs.loadGlobalConfigs()
-	cs.global.noWrites = cs.NoWrites
+	switch p, err := cs.loadGlobalConfigs(); {
+	case err != nil:
+		cs.report.add(ScopeGlobal, cs.globalConfigLocations(), nil, err)
+	case p != "":
+		cs.report.add(ScopeGlobal, cs.globalConfigLocations(), cs.global, nil)
+	default:
+		cs.report.add(ScopeGlobal, cs.globalConfigLocations(), nil, os.ErrNotExist)
+	}
+	cs.applyScopeSettings(cs.global)
 
 	// load the local config, if any
 	if workdir != "" {
-		localConfigPath := filepath.Join(workdir, cs.LocalConfig)
-		c, err := LoadConfig(localConfigPath)
+		localConfigPath := repoConfigPath(workdir, cs.LocalConfig, true, cs.repo)
+		c, err := cs.loadConfig(localConfigPath)
+		cs.report.add(ScopeLocal, []string{localConfigPath}, c, err)
 		if err != nil {
 			debug.V(1).Log("[%s] failed to load local config from %s: %s", cs.Name, localConfigPath, err)
 			// set the path just in case we want to modify / write to it later
 			cs.local.path = localConfigPath
+			// only a missing file may be created, any other file could not
+			// be loaded and must not be overwritten
+			if !isMissing(err) {
+				cs.local = &Config{path: localConfigPath, readonly: true}
+			}
 		} else {
 			debug.V(1).Log("[%s] loaded local config from %s", cs.Name, localConfigPath)
 			cs.local = c
 		}
 	}
-	cs.local.noWrites = cs.NoWrites
+	cs.applyScopeSettings(cs.local)
 
 	// load the worktree config, if any
 	if workdir != "" {
-		worktreeConfigPath := filepath.Join(workdir, cs.WorktreeConfig)
-		c, err := LoadConfig(worktreeConfigPath)
+		worktreeConfigPath := repoConfigPath(workdir, cs.WorktreeConfig, false, cs.repo)
+		c, err := cs.loadConfig(worktreeConfigPath)
+		cs.report.add(ScopeWorktree, []string{worktreeConfigPath}, c, err)
 		if err != nil {
 			debug.V(3).Log("[%s] failed to load worktree config from %s: %s", cs.Name, worktreeConfigPath, err)
 			// set the path just in case we want to modify / write to it later
 			cs.worktree.path = worktreeConfigPath
+			// only a missing file may be created, any other file could not
+			// be loaded and must not be overwritten
+			if !isMissing(err) {
+				cs.worktree = &Config{path: worktreeConfigPath, readonly: true}
+			}
 		} else {
 			debug.V(1).Log("[%s] loaded worktree config from %s", cs.Name, worktreeConfigPath)
 			cs.worktree = c
 		}
 	}
-	cs.worktree.noWrites = cs.NoWrites
+	cs.applyScopeSettings(cs.worktree)
 
 	// load any env vars
-	cs.env = LoadConfigFromEnv(cs.EnvPrefix)
-
-	return cs
+	cs.env = loadConfigFromEnv(cs.EnvPrefix, cs.KeyRules)
+	cs.env.loadedAt = timeNow()
+	cs.applyCompatMode()
+	cs.report.Scopes = append(cs.report.Scopes, ScopeReport{Scope: ScopeEnv, Attempted: []string{}, Found: len(cs.env.vars) > 0})
+	cs.report.finish(cs)
+
+	for _, c := range []*Config{cs.system, cs.local, cs.worktree} {
+		c.stampFiles()
+	}
+	// a global config might show up at any of its locations
+	cs.global.stampFiles(cs.globalConfigLocations()...)
 }
 
 // globalConfigFile returns the path to the global (per-user) config file using XDG base directory spec.
@@ -198,33 +357,72 @@ func (cs *Configs) LoadAll(workdir string) *Configs {
 // This follows the XDG Base Directory specification for user-specific configuration files.
 func globalConfigFile(name string) string {
 	// $XDG_CONFIG_HOME/git/config
-	return filepath.Join(appdir.New(name).UserConfig(), "config")
+	return filepath.Join(userConfigDir(name), "config")
 }
 
-// loadGlobalConfigs will try to load the per-user (Git calls them "global") configs.
-// Since we might need to try different locations but only want to use the first one
-// it's easier to handle this in its own method.
-func (cs *Configs) loadGlobalConfigs() string {
+// globalConfigLocations returns the candidate locations for the per-user config
+// in the order they are tried.
+func (cs *Configs) globalConfigLocations() []string {
 	locs := []string{
 		globalConfigFile(cs.Name),
 	}
 
 	if cs.GlobalConfig != "" {
 		// ~/.gitconfig
-		locs = append(locs, filepath.Join(appdir.UserHome(), cs.GlobalConfig))
+		locs = append(locs, filepath.Join(appHome(), cs.GlobalConfig))
 	}
 
+	return locs
+}
+
+// applyScopeSettings applies the settings of cs to the config of a file
+// scope. Scopes that could not be loaded use the key rules for new values.
+func (cs *Configs) applyScopeSettings(c *Config) {
+	c.noWrites = cs.NoWrites
+	c.compare = cs.Comparison
+	c.keys = cs.KeyRules
+	c.topLevelWrites = cs.WriteToTopLevel
+}
+
+// isUnusable returns true if the error means that the config file exists but
+// can not be used. Such a file must not be overwritten, so the scope is
+// replaced by an empty, read-only config.
+func isUnusable(err error) bool {
+	return errors.Is(err, ErrParse) || errors.Is(err, ErrNotAConfigFile) || errors.Is(err, fs.ErrPermission) ||
+		errors.Is(err, ErrCircularInclude) || errors.Is(err, ErrIncludeUnreadable) || errors.Is(err, ErrIncludeDepthExceeded)
+}
+
+// isMissing returns true if the error means that the config file does not
+// exist, so it can be created. A missing include does not count, the file
+// that includes it exists.
+func isMissing(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) && !errors.Is(err, ErrIncludeUnreadable)
+}
+
+// loadGlobalConfigs will try to load the per-user (Git calls them "global") configs.
+// Since we might need to try different locations but only want to use the first one
+// it's easier to handle this in its own method.
+// It returns the path of the loaded config, if any, or an error if the config
+// was rejected in strict mode.
+func (cs *Configs) loadGlobalConfigs() (string, error) {
+	locs := cs.globalConfigLocations()
+
 	// if we already have a global config we can just reload it instead of trying all locations
 	if !cs.global.IsEmpty() {
 		if p := cs.global.path; p != "" {
 			debug.V(1).Log("[%s] reloading existing global config from %s", cs.Name, p)
-			cfg, err := LoadConfig(p)
+			cfg, err := cs.loadConfig(p)
+			if isUnusable(err) {
+				cs.global = &Config{path: p, readonly: true}
+
+				return "", err
+			}
 			if err != nil {
 				debug.V(1).Log("[%s] failed to reload global config from %s", cs.Name, p)
 			} else {
 				cs.global = cfg
 
-				return p
+				return p, nil
 			}
 		}
 	}
@@ -236,7 +434,12 @@ func (cs *Configs) loadGlobalConfigs() string {
 		if p == "" {
 			continue
 		}
-		cfg, err := LoadConfig(p)
+		cfg, err := cs.loadConfig(p)
+		if isUnusable(err) {
+			cs.global = &Config{path: p, readonly: true}
+
+			return "", err
+		}
 		if err != nil {
 			debug.V(1).Log("[%s] failed to load global config from %s: %s", cs.Name, p, err)
 
@@ -246,7 +449,7 @@ func (cs *Configs) loadGlobalConfigs() string {
 		debug.V(1).Log("[%s] loaded global config from %s", cs.Name, p)
 		cs.global = cfg
 
-		return p
+		return p, nil
 	}
 
 	debug.V(1).Log("[%s] no global config found", cs.Name)
@@ -256,14 +459,16 @@ func (cs *Configs) loadGlobalConfigs() string {
 		path: globalConfigFile(cs.Name),
 	}
 
-	return ""
+	return "", nil
 }
 
 // HasGlobalConfig indicates if a per-user config can be found.
 //
 // Returns true if a global config file exists at one of the configured locations.
 func (cs *Configs) HasGlobalConfig() bool {
-	return cs.loadGlobalConfigs() != ""
+	p, _ := cs.loadGlobalConfigs()
+
+	return p != ""
 }
 
 // Get returns the value for the given key from the first scope that contains it.
@@ -288,25 +493,69 @@ func (cs *Configs) HasGlobalConfig() bool {
 //	  fmt.Printf("Using editor: %s\n", editor)
 //	}
 func (cs *Configs) Get(key string) string {
-	for _, cfg := range []*Config{
-		cs.env,
-		cs.worktree,
-		cs.local,
-		cs.global,
-		cs.system,
-		cs.Preset,
-	} {
+	v, _ := cs.lookup(key)
+
+	return v
+}
+
+// GetRaw returns the value for the given key from the first scope that
+// contains it, exactly as it was written in the file (see Config.GetRaw).
+// Encrypted values are not decrypted.
+func (cs *Configs) GetRaw(key string) string {
+	for _, cfg := range cs.scopes() {
 		if cfg == nil || cfg.vars == nil {
 			continue
 		}
-		if v, found := cfg.Get(key); found {
+		if v, found := cfg.GetRaw(key); found {
 			return v
 		}
 	}
 
+	return ""
+}
+
+// GetComment returns the inline comment of the value Get returns for the
+// key (see Config.GetComment), or "" if it has none.
+func (cs *Configs) GetComment(key string) string {
+	cfg, _, found := cs.lookupConfig(key)
+	if !found {
+		return ""
+	}
+	comment, _ := cfg.GetComment(key)
+
+	return comment
+}
+
+// lookup returns the value for the given key from the first scope that contains it
+// and whether it was found at all.
+func (cs *Configs) lookup(key string) (string, bool) {
+	_, v, found := cs.lookupConfig(key)
+
+	return v, found
+}
+
+// lookupConfig is like lookup but also returns the config that provided the value.
+func (cs *Configs) lookupConfig(key string) (*Config, string, bool) {
+	for _, cfg := range cs.scopes() {
+		if cfg == nil || cfg.vars == nil {
+			continue
+		}
+		if v, found := cfg.Get(key); found {
+			cs.reportDeprecated(key)
+			v, err := cs.decrypt(key, v)
+			if err != nil {
+				debug.V(1).Log("[%s] %s", cs.Name, err)
+
+				return nil, "", false
+			}
+
+			return cfg, v, true
+		}
+	}
+
 	debug.V(3).Log("[%s] no value for %s found", cs.Name, key)
 
-	return ""
+	return nil, "", false
 }
 
 // GetAll returns all values for the given key from the first scope that contains it.
@@ -316,18 +565,14 @@ func (cs *Configs) Get(key string) string {
 //
 // Returns nil if key not found in any scope.
 func (cs *Configs) GetAll(key string) []string {
-	for _, cfg := range []*Config{
-		cs.env,
-		cs.worktree,
-		cs.local,
-		cs.global,
-		cs.system,
-		cs.Preset,
-	} {
+	for _, cfg := range cs.scopes() {
 		if cfg == nil || cfg.vars == nil {
 			continue
 		}
 		if vs, found := cfg.GetAll(key); found {
+			cs.reportDeprecated(key)
+			vs, _ = cs.decryptAll(key, vs)
+
 			return vs
 		}
 	}
@@ -337,27 +582,129 @@ func (cs *Configs) GetAll(key string) []string {
 	return nil
 }
 
+// GetAllRange returns up to limit values for the given key, starting at offset,
+// from the first scope that contains it.
+//
+// See Config.GetAllRange for details and GetAll for the scope priority.
+//
+// Returns nil if key not found in any scope.
+func (cs *Configs) GetAllRange(key string, offset, limit int) []string {
+	for _, cfg := range cs.scopes() {
+		if cfg == nil || cfg.vars == nil {
+			continue
+		}
+		if vs, found := cfg.GetAllRange(key, offset, limit); found {
+			vs, _ = cs.decryptAll(key, vs)
+
+			return vs
+		}
+	}
+
+	debug.V(3).Log("[%s] no value for %s found", cs.Name, key)
+
+	return nil
+}
+
+// CountValues returns the number of values for the given key in the first
+// scope that contains it. It returns 0 if the key is not set in any scope.
+func (cs *Configs) CountValues(key string) int {
+	for _, cfg := range cs.scopes() {
+		if cfg == nil || cfg.vars == nil {
+			continue
+		}
+		if cfg.IsSet(key) {
+			return cfg.CountValues(key)
+		}
+	}
+
+	return 0
+}
+
 // GetFrom returns the value for the given key from the given scope. Valid scopes are:
 // env, worktree, local, global, system and preset.
 func (cs *Configs) GetFrom(key string, scope string) (string, bool) {
-	switch strings.ToLower(scope) {
-	case "env":
-		return cs.env.Get(key)
-	case "worktree":
-		return cs.worktree.Get(key)
-	case "local":
-		return cs.local.Get(key)
-	case "global":
-		return cs.global.Get(key)
-	case "system":
-		return cs.system.Get(key)
-	case "preset":
-		return cs.Preset.Get(key)
-	default:
-		debug.V(3).Log("[%s] unknown config scope %s for key %s", cs.Name, scope, key)
+	v, found := cs.getFrom(key, scope)
+	if !found {
+		return "", false
+	}
+
+	v, err := cs.decrypt(key, v)
+	if err != nil {
+		debug.V(1).Log("[%s] %s", cs.Name, err)
 
 		return "", false
 	}
+
+	return v, true
+}
+
+// getFrom returns the raw value for the given key from the given scope.
+func (cs *Configs) getFrom(key string, scope string) (string, bool) {
+	cfg := cs.scopeConfig(scope)
+	if cfg == nil {
+		return "", false
+	}
+
+	return cfg.Get(key)
+}
+
+// GetAllFrom returns all values for the given key from the given scope,
+// see GetFrom for the valid scopes. The values of a scope include those
+// from files it includes, use Provenance to tell them apart.
+//
+// Example:
+//
+//	paths, _ := cfg.GetAllFrom("include.path", "global")
+func (cs *Configs) GetAllFrom(key string, scope string) ([]string, bool) {
+	cfg := cs.scopeConfig(scope)
+	if cfg == nil {
+		return nil, false
+	}
+
+	vs, found := cfg.GetAll(key)
+	if !found {
+		return nil, false
+	}
+
+	return cs.decryptAll(key, vs)
+}
+
+// GetBoolFrom returns the value for the given key from the given scope,
+// interpreted as a boolean. See Config.GetBool for the rules.
+func (cs *Configs) GetBoolFrom(key string, scope string) (bool, bool) {
+	v, found := cs.GetFrom(key, scope)
+	if !found {
+		return false, false
+	}
+	cfg := cs.scopeConfig(scope)
+	if cfg.isBare(cs.canonicalKey(key)) {
+		return true, true
+	}
+
+	b, err := coerce(cfg, key, TypeBool, v, cfg.parseBool)
+	if err != nil {
+		debug.V(1).Log("[%s] invalid boolean for %s: %s", cs.Name, key, err)
+
+		return false, false
+	}
+
+	return b, true
+}
+
+// GetIntFrom returns the value for the given key from the given scope,
+// interpreted as an integer. See Config.GetInt for the rules.
+func (cs *Configs) GetIntFrom(key string, scope string) (int64, bool, error) {
+	v, found := cs.GetFrom(key, scope)
+	if !found {
+		return 0, false, nil
+	}
+
+	n, err := coerce(cs.scopeConfig(scope), key, TypeInt, v, parseInt)
+	if err != nil {
+		return 0, true, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return n, true, nil
 }
 
 // GetGlobal specifically asks the per-user (global) config for a key.
@@ -408,16 +755,27 @@ func (cs *Configs) GetLocal(key string) string {
 	return ""
 }
 
+// GetWorktree specifically asks the per-worktree config (config.worktree)
+// for a key. Like GetLocal it bypasses the scope priority.
+//
+// Returns empty string if the key is not found in the worktree config.
+func (cs *Configs) GetWorktree(key string) string {
+	if cs.worktree == nil {
+		return ""
+	}
+
+	if v, found := cs.worktree.Get(key); found {
+		return v
+	}
+
+	debug.V(3).Log("[%s] no value for %s found", cs.Name, key)
+
+	return ""
+}
+
 // IsSet returns true if this key is set in any of our configs.
 func (cs *Configs) IsSet(key string) bool {
-	for _, cfg := range []*Config{
-		cs.env,
-		cs.worktree,
-		cs.local,
-		cs.global,
-		cs.system,
-		cs.Preset,
-	} {
+	for _, cfg := range cs.scopes() {
 		if cfg != nil && cfg.IsSet(key) {
 			return true
 		}
@@ -426,6 +784,53 @@ func (cs *Configs) IsSet(key string) bool {
 	return false
 }
 
+// IsSetAny returns true if the key is set in any of the given scopes.
+// If no scopes are given all scopes are checked, like IsSet does.
+//
+// Example:
+//
+//	// is this set anywhere below env?
+//	cfg.IsSetAny("core.editor", "worktree", "local", "global", "system")
+func (cs *Configs) IsSetAny(key string, scopes ...string) bool {
+	for _, sc := range cs.namedScopes() {
+		if sc.cfg == nil || !isIncluded(sc.name, scopes) {
+			continue
+		}
+		if sc.cfg.IsSet(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Exists returns true if any value of the key satisfies the predicate.
+// All values of the key in all given scopes are checked, not only the
+// one that would be returned by Get. If no scopes are given all scopes
+// are checked.
+//
+// Example:
+//
+//	enabled := cfg.Exists("core.fsmonitor", func(v string) bool {
+//		return v == "true"
+//	}, "local", "global")
+func (cs *Configs) Exists(key string, pred func(value string) bool, scopes ...string) bool {
+	for _, sc := range cs.namedScopes() {
+		if sc.cfg == nil || sc.cfg.vars == nil || !isIncluded(sc.name, scopes) {
+			continue
+		}
+		vs, found := sc.cfg.GetAll(key)
+		if !found {
+			continue
+		}
+		if slices.ContainsFunc(vs, pred) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // SetLocal sets (or adds) a key only in the per-directory (local) config.
 func (cs *Configs) SetLocal(key, value string) error {
 	if cs.workdir == "" {
@@ -433,14 +838,21 @@ func (cs *Configs) SetLocal(key, value string) error {
 	}
 	if cs.local == nil {
 		cs.local = &Config{
-			path: filepath.Join(cs.workdir, cs.LocalConfig),
+			path: repoConfigPath(cs.workdir, cs.LocalConfig, true, cs.repo),
 		}
 	}
 	if cs.local.path == "" {
-		cs.local.path = filepath.Join(cs.workdir, cs.LocalConfig)
+		cs.local.path = repoConfigPath(cs.workdir, cs.LocalConfig, true, cs.repo)
+	}
+
+	value, err := cs.encrypt(key, value)
+	if err != nil {
+		return err
 	}
 
-	return cs.local.Set(key, value)
+	return cs.notifying(func() error {
+		return cs.local.Set(key, value)
+	})
 }
 
 // SetGlobal sets (or adds) a key only in the per-user (global) config.
@@ -451,7 +863,14 @@ func (cs *Configs) SetGlobal(key, value string) error {
 		}
 	}
 
-	return cs.global.Set(key, value)
+	value, err := cs.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+
+	return cs.notifying(func() error {
+		return cs.global.Set(key, value)
+	})
 }
 
 // SetEnv sets (or adds) a key in the per-process (env) config. Useful
@@ -463,7 +882,174 @@ func (cs *Configs) SetEnv(key, value string) error {
 		}
 	}
 
-	return cs.env.Set(key, value)
+	value, err := cs.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+
+	return cs.notifying(func() error {
+		return cs.env.Set(key, value)
+	})
+}
+
+// SetSystem sets (or adds) a key only in the system-wide config, e.g. for
+// provisioning tools. It fails with an error wrapping ErrReadonly unless
+// AllowSystemWrites was set before LoadAll, or if the system config exists
+// but could not be loaded.
+//
+// Example:
+//
+//	cfg := gitconfig.New()
+//	cfg.SystemConfig = "/etc/gopass/config"
+//	cfg.AllowSystemWrites = true
+//	cfg.LoadAll("")
+//	if err := cfg.SetSystem("core.autoimport", "true"); err != nil { ... }
+func (cs *Configs) SetSystem(key, value string) error {
+	c, err := cs.systemScope()
+	if err != nil {
+		return err
+	}
+
+	value, err = cs.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+
+	return cs.notifying(func() error {
+		return c.Set(key, value)
+	})
+}
+
+// UnsetSystem deletes a key from the system-wide config. Like SetSystem it
+// requires AllowSystemWrites.
+func (cs *Configs) UnsetSystem(key string) error {
+	c, err := cs.systemScope()
+	if err != nil {
+		return err
+	}
+
+	return cs.notifying(func() error {
+		return c.Unset(key)
+	})
+}
+
+// systemScope returns the system config for writing, see SetSystem.
+func (cs *Configs) systemScope() (*Config, error) {
+	if !cs.AllowSystemWrites {
+		return nil, fmt.Errorf("%w: writing the %s config is not allowed, see AllowSystemWrites", ErrReadonly, ScopeSystem)
+	}
+	if cs.system == nil || cs.system.path == "" {
+		cs.system = &Config{
+			path:     cs.SystemConfig,
+			noWrites: cs.NoWrites,
+			keys:     cs.KeyRules,
+		}
+	}
+	if cs.system.readonly {
+		return nil, fmt.Errorf("%w: %s config %s", ErrReadonly, ScopeSystem, cs.system.path)
+	}
+
+	return cs.system, nil
+}
+
+// SetWorktree sets (or adds) a key only in the per-worktree config, e.g.
+// .git/config.worktree or the config.worktree in the git directory of a
+// linked worktree. Like git, this requires extensions.worktreeConfig to be
+// enabled in the local config, otherwise it fails with an error wrapping
+// ErrWorktreeConfigDisabled. If EnableWorktreeConfig is set the extension
+// is enabled instead, upgrading core.repositoryFormatVersion to 1 like git
+// does. The worktree config is created if it does not exist.
+//
+// Example:
+//
+//	cfg := gitconfig.New()
+//	cfg.LocalConfig = ".git/config"
+//	cfg.WorktreeConfig = ".git/config.worktree"
+//	cfg.EnableWorktreeConfig = true
+//	cfg.LoadAll("/path/to/worktree")
+//	if err := cfg.SetWorktree("core.sparseCheckout", "true"); err != nil { ... }
+func (cs *Configs) SetWorktree(key, value string) error {
+	if cs.workdir == "" {
+		return ErrWorkdirNotSet
+	}
+
+	value, err := cs.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+
+	return cs.notifying(func() error {
+		if err := cs.ensureWorktreeConfig(); err != nil {
+			return err
+		}
+		if cs.worktree == nil {
+			cs.worktree = &Config{}
+		}
+		if cs.worktree.path == "" {
+			cs.worktree.path = repoConfigPath(cs.workdir, cs.WorktreeConfig, false, cs.repo)
+		}
+
+		return cs.worktree.Set(key, value)
+	})
+}
+
+// checkWorktreeConfig reports whether extensions.worktreeConfig is enabled
+// in the local config. If it is not and must not be enabled, see
+// EnableWorktreeConfig, it fails with ErrWorktreeConfigDisabled.
+func (cs *Configs) checkWorktreeConfig() (bool, error) {
+	if cs.local != nil {
+		if enabled, _ := cs.local.GetBool("extensions.worktreeConfig"); enabled {
+			return true, nil
+		}
+	}
+
+	if !cs.EnableWorktreeConfig {
+		localPath := repoConfigPath(cs.workdir, cs.LocalConfig, true, cs.repo)
+
+		return false, fmt.Errorf("%w: set extensions.worktreeConfig in %s or see EnableWorktreeConfig", ErrWorktreeConfigDisabled, localPath)
+	}
+
+	return false, nil
+}
+
+// ensureWorktreeConfig checks that extensions.worktreeConfig is enabled in
+// the local config, enabling it if EnableWorktreeConfig is set.
+func (cs *Configs) ensureWorktreeConfig() error {
+	if enabled, err := cs.checkWorktreeConfig(); enabled || err != nil {
+		return err
+	}
+
+	localPath := repoConfigPath(cs.workdir, cs.LocalConfig, true, cs.repo)
+
+	if cs.local == nil {
+		cs.local = &Config{}
+	}
+	if cs.local.path == "" {
+		cs.local.path = localPath
+	}
+	if cs.local.readonly {
+		return fmt.Errorf("%w: can not enable extensions.worktreeConfig in %s", ErrReadonly, cs.local.path)
+	}
+
+	// extensions are only valid in repository format version 1
+	if v, _, _ := cs.local.GetInt("core.repositoryformatversion"); v < 1 {
+		if err := cs.local.Set("core.repositoryformatversion", "1"); err != nil {
+			return err
+		}
+	}
+
+	return cs.local.Set("extensions.worktreeConfig", "true")
+}
+
+// UnsetWorktree deletes a key from the worktree config.
+func (cs *Configs) UnsetWorktree(key string) error {
+	if cs.worktree == nil {
+		return nil
+	}
+
+	return cs.notifying(func() error {
+		return cs.worktree.Unset(key)
+	})
 }
 
 // UnsetLocal deletes a key from the local config.
@@ -472,7 +1058,9 @@ func (cs *Configs) UnsetLocal(key string) error {
 		return nil
 	}
 
-	return cs.local.Unset(key)
+	return cs.notifying(func() error {
+		return cs.local.Unset(key)
+	})
 }
 
 // UnsetGlobal deletes a key from the global config.
@@ -481,7 +1069,9 @@ func (cs *Configs) UnsetGlobal(key string) error {
 		return nil
 	}
 
-	return cs.global.Unset(key)
+	return cs.notifying(func() error {
+		return cs.global.Unset(key)
+	})
 }
 
 // Keys returns a list of all keys from all available scopes. Every key has section and possibly
@@ -492,71 +1082,111 @@ func (cs *Configs) UnsetGlobal(key string) error {
 //   - remote.gist.gopass.pw.path -> section: remote, subsection: gist.gopass.pw, key: path
 //   - core.timeout -> section: core, key: timeout
 func (cs *Configs) Keys() []string {
+	return cs.KeysExcept()
+}
+
+// KeysExcept is like Keys but ignores the given scopes. Use this to
+// e.g. hide ephemeral env overrides from a listing of the persisted
+// configuration:
+//
+//	keys := cfg.KeysExcept("env")
+func (cs *Configs) KeysExcept(exclude ...string) []string {
 	keys := make([]string, 0, 128)
 
-	for _, cfg := range []*Config{
-		cs.Preset,
-		cs.system,
-		cs.global,
-		cs.local,
-		cs.worktree,
-		cs.env,
-	} {
-		if cfg == nil {
+	for _, sc := range cs.namedScopes() {
+		if sc.cfg == nil || containsScope(sc.name, exclude) {
 			continue
 		}
-		for k := range cfg.vars {
+		for k := range sc.cfg.vars {
 			keys = append(keys, k)
 		}
 	}
 
-	return set.Sorted(keys)
+	return sortedSet(keys)
+}
+
+// KeysFrom returns a sorted list of all keys from the given scope only.
+// Valid scopes are: env, worktree, local, global, system and preset.
+func (cs *Configs) KeysFrom(scope string) []string {
+	keys := make([]string, 0, 128)
+
+	for _, sc := range cs.namedScopes() {
+		if sc.cfg == nil || !strings.EqualFold(sc.name, scope) {
+			continue
+		}
+		for k := range sc.cfg.vars {
+			keys = append(keys, k)
+		}
+	}
+
+	return sortedSet(keys)
 }
 
 // List returns all keys matching the given prefix. The prefix can be empty,
 // then this is identical to Keys().
 func (cs *Configs) List(prefix string) []string {
-	return set.SortedFiltered(cs.Keys(), func(k string) bool {
-		return strings.HasPrefix(k, prefix)
-	})
+	return cs.ListExcept(prefix)
+}
+
+// ListExcept is like List but ignores the given scopes.
+func (cs *Configs) ListExcept(prefix string, exclude ...string) []string {
+	return filterPrefix(cs.KeysExcept(exclude...), prefix)
+}
+
+// ListFrom returns all keys from the given scope matching the given prefix.
+func (cs *Configs) ListFrom(scope, prefix string) []string {
+	return filterPrefix(cs.KeysFrom(scope), prefix)
+}
+
+func filterPrefix(keys []string, prefix string) []string {
+	return sortedSet(slices.DeleteFunc(keys, func(k string) bool {
+		return !strings.HasPrefix(k, prefix)
+	}))
 }
 
 // ListSections returns a sorted list of all sections.
 func (cs *Configs) ListSections() []string {
-	return set.Sorted(set.Apply(cs.Keys(), func(k string) string {
+	keys := cs.Keys()
+	sections := make([]string, 0, len(keys))
+	for _, k := range keys {
 		section, _, _ := splitKey(k)
+		sections = append(sections, section)
+	}
 
-		return section
-	}))
+	return sortedSet(sections)
 }
 
 // ListSubsections returns a sorted list of all subsections
 // in the given section.
 func (cs *Configs) ListSubsections(wantSection string) []string {
-	// apply extracts the subsection and matches it to the empty string
-	// if it doesn't belong to the section we're looking for. Then the
-	// filter func filters out any empty string.
-	return set.SortedFiltered(set.Apply(cs.Keys(), func(k string) string {
+	keys := cs.Keys()
+	subsections := make([]string, 0, len(keys))
+	for _, k := range keys {
 		section, subsection, _ := splitKey(k)
-		if section != wantSection {
-			return ""
+		if section != wantSection || subsection == "" {
+			continue
 		}
+		subsections = append(subsections, subsection)
+	}
 
-		return subsection
-	}), func(s string) bool {
-		return s != ""
-	})
+	return sortedSet(subsections)
 }
 
 // KVList returns a list of all keys and values matching the given prefix.
 func (cs *Configs) KVList(prefix, sep string) []string {
+	return cs.KVListExcept(prefix, sep)
+}
+
+// KVListExcept is like KVList but ignores the given scopes, both for
+// listing the keys and for resolving their values.
+func (cs *Configs) KVListExcept(prefix, sep string, exclude ...string) []string {
 	if sep == "" {
 		sep = "="
 	}
-	keys := cs.List(prefix)
+	keys := cs.ListExcept(prefix, exclude...)
 	kv := make([]string, 0, len(keys))
 	for _, k := range keys {
-		vs := cs.GetAll(k)
+		vs := cs.getAllExcept(k, exclude)
 		for _, v := range vs {
 			if v == "" {
 				continue
@@ -569,3 +1199,39 @@ func (cs *Configs) KVList(prefix, sep string) []string {
 
 	return kv
 }
+
+// getAllExcept returns all values for the key from the first
+// scope that contains it, ignoring the excluded scopes.
+func (cs *Configs) getAllExcept(key string, exclude []string) []string {
+	for _, sc := range cs.namedScopes() {
+		if sc.cfg == nil || sc.cfg.vars == nil || containsScope(sc.name, exclude) {
+			continue
+		}
+		if vs, found := sc.cfg.GetAll(key); found {
+			return vs
+		}
+	}
+
+	return nil
+}
+
+// isIncluded returns true if scope is one of the wanted scopes or
+// if no scopes are wanted explicitly.
+func isIncluded(scope string, want []string) bool {
+	if len(want) == 0 {
+		return true
+	}
+
+	return containsScope(scope, want)
+}
+
+// containsScope returns true if scope is in scopes, ignoring case.
+func containsScope(scope string, scopes []string) bool {
+	for _, e := range scopes {
+		if strings.EqualFold(scope, e) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,6 +3,8 @@ package gitconfig
 import (
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -163,3 +165,491 @@ func TestGetFrom(t *testing.T) {
 	assert.False(t, ok)
 	assert.Empty(t, v)
 }
+
+func TestGetAllFrom(t *testing.T) {
+	t.Parallel()
+
+	c := New()
+	c.local = ParseConfig(strings.NewReader("[remote \"origin\"]\n\tfetch = a\n\tfetch = b\n[core]\n\tflag\n\tlimit = 2k\n"))
+	c.global = ParseConfig(strings.NewReader("[remote \"origin\"]\n\tfetch = x\n[core]\n\tflag = false\n\tlimit = nope\n"))
+
+	vs, ok := c.GetAllFrom("remote.origin.fetch", "local")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a", "b"}, vs)
+	vs, ok = c.GetAllFrom("remote.origin.fetch", "Global")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"x"}, vs)
+
+	_, ok = c.GetAllFrom("remote.origin.fetch", "system")
+	assert.False(t, ok)
+	_, ok = c.GetAllFrom("remote.origin.fetch", "unknownscope")
+	assert.False(t, ok)
+	// the preset scope is not set
+	_, ok = c.GetAllFrom("remote.origin.fetch", "preset")
+	assert.False(t, ok)
+
+	b, ok := c.GetBoolFrom("core.flag", "local")
+	assert.True(t, ok)
+	assert.True(t, b)
+	b, ok = c.GetBoolFrom("core.flag", "global")
+	assert.True(t, ok)
+	assert.False(t, b)
+	_, ok = c.GetBoolFrom("core.missing", "global")
+	assert.False(t, ok)
+
+	n, found, err := c.GetIntFrom("core.limit", "local")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(2048), n)
+	_, found, err = c.GetIntFrom("core.limit", "global")
+	require.ErrorIs(t, err, ErrInvalidValue)
+	assert.True(t, found)
+	_, found, err = c.GetIntFrom("core.limit", "system")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// the include paths of a scope, including those of included files
+	td := t.TempDir()
+	fn := filepath.Join(td, "global")
+	require.NoError(t, os.WriteFile(fn, []byte("[include]\n\tpath = a.config\n\tpath = missing.config\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "a.config"), []byte("[include]\n\tpath = b.config\n"), 0o600))
+	global, err := LoadConfig(fn)
+	require.NoError(t, err)
+	c.global = global
+	vs, ok = c.GetAllFrom("include.path", ScopeGlobal)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a.config", "missing.config", "b.config"}, vs)
+}
+
+func TestConfigsGetAllRange(t *testing.T) {
+	t.Parallel()
+
+	c := New()
+	c.local = ParseConfig(strings.NewReader("[core]\n\tmulti = a\n\tmulti = b\n\tmulti = c\n"))
+	c.global = ParseConfig(strings.NewReader("[core]\n\tmulti = x\n\tother = y\n"))
+
+	assert.Equal(t, 3, c.CountValues("core.multi"))
+	assert.Equal(t, 1, c.CountValues("core.other"))
+	assert.Equal(t, 0, c.CountValues("core.missing"))
+
+	assert.Equal(t, []string{"b", "c"}, c.GetAllRange("core.multi", 1, 10))
+	assert.Equal(t, []string{"y"}, c.GetAllRange("core.other", 0, 1))
+	assert.Nil(t, c.GetAllRange("core.missing", 0, 1))
+}
+
+func TestConfigsGetRaw(t *testing.T) {
+	t.Parallel()
+
+	c := New()
+	c.local = ParseConfig(strings.NewReader("[core]\n\tquoted = \"a\\tb\"\n"))
+	c.global = ParseConfig(strings.NewReader("[core]\n\tquoted = x\n\tother = y ; comment\n"))
+
+	assert.Equal(t, `"a\tb"`, c.GetRaw("core.quoted"))
+	assert.Equal(t, "a\tb", c.Get("core.quoted"))
+	assert.Equal(t, "y ; comment", c.GetRaw("core.other"))
+	assert.Empty(t, c.GetRaw("core.missing"))
+}
+
+func TestConfigsGetComment(t *testing.T) {
+	t.Parallel()
+
+	c := New()
+	c.local = ParseConfig(strings.NewReader("[core]\n\teditor = vim\n"))
+	c.global = ParseConfig(strings.NewReader("[core]\n\teditor = nano # shadowed\n\tpager = less # global\n"))
+
+	assert.Empty(t, c.GetComment("core.editor"))
+	assert.Equal(t, "global", c.GetComment("core.pager"))
+	assert.Empty(t, c.GetComment("core.missing"))
+}
+
+func TestConfigsListExcept(t *testing.T) {
+	t.Parallel()
+
+	c := New()
+	c.env = ParseConfig(strings.NewReader("[ci]\n\ttoken = secret\n[core]\n\teditor = ed\n"))
+	c.local = ParseConfig(strings.NewReader("[core]\n\teditor = vim\n\tpager = less\n"))
+	c.global = ParseConfig(strings.NewReader("[user]\n\tname = John\n"))
+	c.Preset = NewFromMap(map[string]string{"core.editor": "nano"})
+
+	assert.Equal(t, []string{"ci.token", "core.editor", "core.pager", "user.name"}, c.Keys())
+	assert.Equal(t, []string{"core.editor", "core.pager", "user.name"}, c.KeysExcept("env"))
+	assert.Equal(t, []string{"user.name"}, c.KeysExcept("ENV", "local", "preset"))
+
+	assert.Equal(t, []string{"ci.token", "core.editor"}, c.KeysFrom("env"))
+	assert.Equal(t, []string{"user.name"}, c.KeysFrom("global"))
+	assert.Empty(t, c.KeysFrom("unknown"))
+
+	assert.Equal(t, []string{"core.editor", "core.pager"}, c.ListExcept("core.", "env"))
+	assert.Equal(t, []string{"core.editor"}, c.ListFrom("preset", "core."))
+
+	assert.Equal(t, []string{"core.editor=ed", "core.pager=less"}, c.KVList("core.", ""))
+	assert.Equal(t, []string{"core.editor=vim", "core.pager=less"}, c.KVListExcept("core.", "", "env"))
+	assert.Equal(t, []string{"core.editor: nano"}, c.KVListExcept("core.", ": ", "env", "local"))
+}
+
+func TestConfigsIsSetAnyAndExists(t *testing.T) {
+	t.Parallel()
+
+	c := New()
+	c.env = ParseConfig(strings.NewReader("[core]\n\tfsmonitor = true\n"))
+	c.local = ParseConfig(strings.NewReader("[core]\n\tfsmonitor = false\n\tmulti = a\n\tmulti = b\n"))
+	c.global = ParseConfig(strings.NewReader("[core]\n\tfsmonitor = yes\n"))
+
+	assert.True(t, c.IsSetAny("core.fsmonitor"))
+	assert.True(t, c.IsSetAny("core.fsmonitor", "env"))
+	assert.True(t, c.IsSetAny("core.multi", "local", "global"))
+	assert.False(t, c.IsSetAny("core.multi", "env", "global"))
+	assert.False(t, c.IsSetAny("core.missing"))
+
+	isTrue := func(v string) bool { return v == "true" }
+	assert.True(t, c.Exists("core.fsmonitor", isTrue))
+	assert.False(t, c.Exists("core.fsmonitor", isTrue, "worktree", "local", "global", "system"))
+	assert.True(t, c.Exists("core.fsmonitor", func(v string) bool { return v == "yes" }, "local", "global"))
+	assert.True(t, c.Exists("core.multi", func(v string) bool { return v == "b" }))
+	assert.False(t, c.Exists("core.missing", func(string) bool { return true }))
+}
+
+func TestConfigsGeneration(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	c := New()
+	c.SystemConfig = ""
+	c.EnvPrefix = "GPTEST_GENERATION"
+	assert.Equal(t, uint64(0), c.Generation())
+
+	c.LoadAll(td)
+	assert.Equal(t, uint64(1), c.Generation())
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			c.Reload()
+			_ = c.Generation()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint64(9), c.Generation())
+}
+
+func TestConfigsBinaryLocalConfig(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	binary := "[core]\n\teditor = vim\n\x00\x00"
+	require.NoError(t, os.WriteFile(filepath.Join(td, "local"), []byte(binary), 0o600))
+
+	c := New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_BINARY_CONFIG"
+	c.LoadAll(td)
+
+	assert.Empty(t, c.Get("core.editor"))
+	local, ok := c.LoadReport().Scope("local")
+	require.True(t, ok)
+	assert.True(t, local.ReadOnly)
+	assert.Contains(t, local.Error, "not a config file")
+
+	// the file is not overwritten
+	require.NoError(t, c.SetLocal("core.pager", "less"))
+	buf, err := os.ReadFile(filepath.Join(td, "local"))
+	require.NoError(t, err)
+	assert.Equal(t, binary, string(buf))
+}
+
+func TestConfigsGitFile(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	// a repository with a linked worktree and a submodule, both have a
+	// .git file pointing to their git directory
+	gitDir := filepath.Join(td, "repo", ".git")
+	wtGitDir := filepath.Join(gitDir, "worktrees", "wt")
+	subGitDir := filepath.Join(gitDir, "modules", "sub")
+	require.NoError(t, os.MkdirAll(wtGitDir, 0o755))
+	require.NoError(t, os.MkdirAll(subGitDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "config"), []byte("[core]\n\tint = 1\n[includeIf \"onbranch:feat/*\"]\n\tpath = feat.config\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "feat.config"), []byte("[feat]\n\tenabled = true\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(wtGitDir, "HEAD"), []byte("ref: refs/heads/feat/x\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(wtGitDir, "commondir"), []byte("../..\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(wtGitDir, "config.worktree"), []byte("[core]\n\tint = 2\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(subGitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(subGitDir, "config"), []byte("[core]\n\tint = 3\n"), 0o644))
+
+	wt := filepath.Join(td, "wt")
+	sub := filepath.Join(td, "repo", "sub")
+	require.NoError(t, os.Mkdir(wt, 0o755))
+	require.NoError(t, os.Mkdir(sub, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(wt, ".git"), []byte("gitdir: "+wtGitDir+"\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(sub, ".git"), []byte("gitdir: ../.git/modules/sub\n"), 0o644))
+
+	load := func(workdir string) *Configs {
+		c := New()
+		c.SystemConfig = ""
+		c.LocalConfig = filepath.Join(".git", "config")
+		c.WorktreeConfig = filepath.Join(".git", "config.worktree")
+		c.EnvPrefix = "GPTEST_GITFILE_CONFIG"
+		c.LoadAll(workdir)
+
+		return c
+	}
+
+	// the worktree shares the local config of the repository
+	c := load(wt)
+	assert.Equal(t, "2", c.Get("core.int"))
+	v, _ := c.GetFrom("core.int", ScopeLocal)
+	assert.Equal(t, "1", v)
+	worktree, ok := c.LoadReport().Scope(ScopeWorktree)
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(wtGitDir, "config.worktree"), worktree.Path)
+	assert.Equal(t, "true", c.Get("feat.enabled"))
+
+	require.NoError(t, c.SetLocal("core.other", "x"))
+	buf, err := os.ReadFile(filepath.Join(gitDir, "config"))
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), "other = x")
+
+	// the main worktree is not on a feature branch
+	c = load(filepath.Join(td, "repo"))
+	assert.Equal(t, "1", c.Get("core.int"))
+	assert.Empty(t, c.Get("feat.enabled"))
+
+	// the submodule has its own config
+	c = load(sub)
+	assert.Equal(t, "3", c.Get("core.int"))
+}
+
+func TestConfigsSetBranch(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	gitDir := filepath.Join(td, "repo", ".git")
+	require.NoError(t, os.MkdirAll(gitDir, 0o755))
+	head := filepath.Join(gitDir, "HEAD")
+	require.NoError(t, os.WriteFile(head, []byte("ref: refs/heads/main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "config"), []byte("[includeIf \"onbranch:feat/*\"]\n\tpath = feat.config\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "feat.config"), []byte("[feat]\n\tenabled = true\n"), 0o644))
+
+	c := New()
+	c.SystemConfig = ""
+	c.LocalConfig = filepath.Join(".git", "config")
+	c.EnvPrefix = "GPTEST_SETBRANCH_CONFIG"
+	c.LoadAll(filepath.Join(td, "repo"))
+	assert.False(t, c.IsSet("feat.enabled"))
+
+	// Reload picks up the new branch
+	require.NoError(t, os.WriteFile(head, []byte("ref: refs/heads/feat/x\n"), 0o644))
+	c.Reload()
+	assert.Equal(t, "true", c.Get("feat.enabled"))
+
+	gen := c.Generation()
+	c.SetBranch("main")
+	assert.False(t, c.IsSet("feat.enabled"))
+	assert.Greater(t, c.Generation(), gen)
+
+	c.SetBranch("")
+	assert.Equal(t, "true", c.Get("feat.enabled"))
+}
+
+func TestConfigsGitEnv(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	resolved, err := filepath.EvalSymlinks(td)
+	require.NoError(t, err)
+
+	// a worktree whose git directory and common directory are relocated
+	gitDir := filepath.Join(resolved, "git", "wt")
+	common := filepath.Join(resolved, "git", "common")
+	work := filepath.Join(resolved, "work")
+	for _, d := range []string{gitDir, common, work} {
+		require.NoError(t, os.MkdirAll(d, 0o755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/feat/x\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "config.worktree"), []byte("[core]\n\tint = 2\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(common, "config"), []byte("[core]\n\tint = 1\n"), 0o644))
+
+	require.NoError(t, os.WriteFile(filepath.Join(td, "global"), []byte(`[includeIf "gitdir:`+filepath.ToSlash(gitDir)+`"]
+	path = git.config
+[includeIf "onbranch:feat/"]
+	path = feat.config
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "git.config"), []byte("[user]\n\temail = ci@example.com\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "feat.config"), []byte("[feat]\n\tenabled = true\n"), 0o600))
+
+	t.Setenv("GIT_DIR", gitDir)
+	t.Setenv("GIT_COMMON_DIR", common)
+	t.Setenv("GIT_WORK_TREE", work)
+
+	load := func(gitEnv bool) *Configs {
+		c := New()
+		c.SystemConfig = ""
+		c.GlobalConfig = "global"
+		c.LocalConfig = filepath.Join(".git", "config")
+		c.WorktreeConfig = filepath.Join(".git", "config.worktree")
+		c.EnvPrefix = "GPTEST_GITENV_CONFIG"
+		c.GitEnv = gitEnv
+		c.LoadAll("")
+
+		return c
+	}
+
+	// the variables are ignored by default
+	c := load(false)
+	assert.Empty(t, c.Workdir())
+	assert.Empty(t, c.GitDir())
+	assert.False(t, c.IsSet("core.int"))
+	assert.False(t, c.IsSet("user.email"))
+	assert.False(t, c.IsSet("feat.enabled"))
+
+	c = load(true)
+	assert.Equal(t, work, c.Workdir())
+	assert.Equal(t, gitDir, c.GitDir())
+	assert.Equal(t, "2", c.Get("core.int"))
+	v, _ := c.GetFrom("core.int", ScopeLocal)
+	assert.Equal(t, "1", v)
+	assert.Equal(t, "ci@example.com", c.Get("user.email"))
+	assert.Equal(t, "true", c.Get("feat.enabled"))
+
+	// the local config is written to the common directory
+	require.NoError(t, c.SetLocal("core.bare", "false"))
+	content, err := os.ReadFile(filepath.Join(common, "config"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "bare = false")
+	_, err = os.Stat(filepath.Join(work, ".git", "config"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestConfigsSetSystem(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	fn := filepath.Join(td, "system")
+	require.NoError(t, os.WriteFile(fn, []byte("[core]\n\tint = 1\n"), 0o644))
+
+	c := New()
+	c.SystemConfig = fn
+	c.EnvPrefix = "GPTEST_SYSTEM_CONFIG"
+	c.LoadAll("")
+
+	// read-only by default
+	require.ErrorIs(t, c.SetSystem("core.editor", "vim"), ErrReadonly)
+	require.ErrorIs(t, c.UnsetSystem("core.int"), ErrReadonly)
+	content, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\tint = 1\n", string(content))
+
+	c.AllowSystemWrites = true
+	c.LoadAll("")
+	sr, ok := c.LoadReport().Scope(ScopeSystem)
+	require.True(t, ok)
+	assert.False(t, sr.ReadOnly)
+
+	require.NoError(t, c.SetSystem("core.editor", "vim"))
+	require.NoError(t, c.UnsetSystem("core.int"))
+	assert.Equal(t, "vim", c.Get("core.editor"))
+	assert.False(t, c.IsSet("core.int"))
+	content, err = os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\teditor = vim\n", string(content))
+
+	// a missing system config is created
+	c.SystemConfig = filepath.Join(td, "new")
+	c.LoadAll("")
+	require.NoError(t, c.SetSystem("core.editor", "nano"))
+	content, err = os.ReadFile(c.SystemConfig)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "editor = nano")
+
+	// an unusable system config is never overwritten
+	require.NoError(t, os.WriteFile(fn, []byte("[core\n\tint = 1\n"), 0o644))
+	c.SystemConfig = fn
+	c.Strict = true
+	c.LoadAll("")
+	require.ErrorIs(t, c.SetSystem("core.editor", "vim"), ErrReadonly)
+	content, err = os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, "[core\n\tint = 1\n", string(content))
+
+	// neither is a config that fails to load for other reasons
+	cyclic := "[include]\n\tpath = system\n"
+	require.NoError(t, os.WriteFile(fn, []byte(cyclic), 0o644))
+	c.Strict = false
+	c.FailOnCircularInclude = true
+	c.LoadAll("")
+	require.ErrorIs(t, c.SetSystem("core.editor", "vim"), ErrReadonly)
+	require.ErrorIs(t, c.UnsetSystem("include.path"), ErrReadonly)
+	content, err = os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, cyclic, string(content))
+}
+
+func TestConfigsSetWorktree(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	repo := filepath.Join(td, "repo")
+	gitDir := filepath.Join(repo, ".git")
+	wtGitDir := filepath.Join(gitDir, "worktrees", "wt")
+	require.NoError(t, os.MkdirAll(wtGitDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "config"), []byte("[core]\n\trepositoryformatversion = 0\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(wtGitDir, "HEAD"), []byte("ref: refs/heads/wt\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(wtGitDir, "commondir"), []byte("../..\n"), 0o644))
+	wt := filepath.Join(td, "wt")
+	require.NoError(t, os.Mkdir(wt, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(wt, ".git"), []byte("gitdir: "+wtGitDir+"\n"), 0o644))
+
+	load := func(workdir string, enable bool) *Configs {
+		c := New()
+		c.SystemConfig = ""
+		c.LocalConfig = filepath.Join(".git", "config")
+		c.WorktreeConfig = filepath.Join(".git", "config.worktree")
+		c.EnvPrefix = "GPTEST_WORKTREE_CONFIG"
+		c.EnableWorktreeConfig = enable
+		c.LoadAll(workdir)
+
+		return c
+	}
+
+	require.ErrorIs(t, New().SetWorktree("core.bare", "false"), ErrWorkdirNotSet)
+
+	// the extension must be enabled
+	c := load(repo, false)
+	require.ErrorIs(t, c.SetWorktree("core.sparsecheckout", "true"), ErrWorktreeConfigDisabled)
+	_, err := os.Stat(filepath.Join(gitDir, "config.worktree"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	c = load(repo, true)
+	require.NoError(t, c.SetWorktree("core.sparsecheckout", "true"))
+	assert.Equal(t, "true", c.GetWorktree("core.sparsecheckout"))
+	assert.Empty(t, c.GetLocal("core.sparsecheckout"))
+	content, err := os.ReadFile(filepath.Join(gitDir, "config"))
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\trepositoryformatversion = 1\n[extensions]\n\tworktreeConfig = true\n", string(content))
+	content, err = os.ReadFile(filepath.Join(gitDir, "config.worktree"))
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\tsparsecheckout = true\n", string(content))
+
+	// once enabled it works without EnableWorktreeConfig, a linked
+	// worktree has its own config
+	c = load(wt, false)
+	assert.Empty(t, c.GetWorktree("core.sparsecheckout"))
+	require.NoError(t, c.SetWorktree("core.sparsecheckout", "false"))
+	assert.Equal(t, "false", c.Get("core.sparsecheckout"))
+	content, err = os.ReadFile(filepath.Join(wtGitDir, "config.worktree"))
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\tsparsecheckout = false\n", string(content))
+
+	c = load(repo, false)
+	assert.Equal(t, "true", c.GetWorktree("core.sparsecheckout"))
+	require.NoError(t, c.UnsetWorktree("core.sparsecheckout"))
+	assert.Empty(t, c.GetWorktree("core.sparsecheckout"))
+	assert.False(t, c.IsSet("core.sparsecheckout"))
+}
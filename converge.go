@@ -0,0 +1,234 @@
+package gitconfig
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ConvergeOptions control the behavior of Converge.
+//
+// Fields:
+// - Managed: Key prefixes owned by the caller. Keys in the target scope that
+// match one of these prefixes but are not part of the desired state are removed.
+// - DryRun: If true, only compute the changes without applying them.
+// - UseScopeComparison: If true, values are compared like Set does (see
+// Configs.Comparison) instead of semantically.
+type ConvergeOptions struct {
+	Managed            []string
+	DryRun             bool
+	UseScopeComparison bool
+}
+
+// Converge modifies the given scope so that it contains the desired state.
+//
+// It computes the minimal set of Set and Unset operations to reach the desired
+// state, applies them in memory and then writes the config file once. If any
+// operation fails none of the changes are kept. Values are compared
+// semantically (see CompareSemantic), so e.g. "yes" and "true" or "1k" and
+// "1024" are not considered a change, unless opts.UseScopeComparison is set.
+//
+// Keys not present in desired are left untouched unless they match one of
+// the managed prefixes in opts.
+//
+// Keys registered with SetCipher are encrypted before they are written and
+// compared with their decrypted value. The report shows the plaintext.
+//
+// Valid scopes are: env, worktree, local and global. Like SetWorktree, the
+// worktree scope requires extensions.worktreeConfig, see
+// Configs.EnableWorktreeConfig.
+//
+// The returned report lists all changes, even in dry-run mode.
+//
+// Example:
+//
+//	report, err := cfg.Converge(map[string]string{
+//		"core.autocrlf": "true",
+//		"mytool.enabled": "yes",
+//	}, "global", ConvergeOptions{Managed: []string{"mytool."}})
+func (cs *Configs) Converge(desired map[string]string, scope string, opts ConvergeOptions) (*ChangeReport, error) {
+	cfg, err := cs.writableScope(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	compare := CompareSemantic
+	if opts.UseScopeComparison {
+		compare = cfg.compare
+	}
+
+	changes, err := planConverge(cfg, desired, opts.Managed, compare, cs.decrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ChangeReport{Files: []FileChanges{}}
+	for _, ch := range changes {
+		report.add(cfg.path, ch)
+	}
+
+	if opts.DryRun || len(changes) == 0 {
+		return report, nil
+	}
+
+	values := make(map[string]string, len(changes))
+	for _, ch := range changes {
+		if ch.Kind == ChangeRemoved {
+			continue
+		}
+		v, err := cs.encrypt(ch.Key, ch.After)
+		if err != nil {
+			return nil, err
+		}
+		values[ch.Key] = v
+	}
+
+	if err := cs.notifying(func() error {
+		if cfg == cs.worktree {
+			if err := cs.ensureWorktreeConfig(); err != nil {
+				return err
+			}
+		}
+
+		return cfg.transaction(func() error {
+			for _, ch := range changes {
+				if ch.Kind == ChangeRemoved {
+					if err := cfg.Unset(ch.Key); err != nil {
+						return err
+					}
+
+					continue
+				}
+				if err := cfg.Set(ch.Key, values[ch.Key]); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	debug.V(1).Log("[%s] converged %s config with %d changes", cs.Name, scope, len(changes))
+
+	return report, nil
+}
+
+// planConverge computes the changes needed to bring cfg to the desired state,
+// comparing values with compare. The current values are decrypted first, a
+// value that can not be decrypted is always updated. The changes are sorted
+// by key.
+func planConverge(cfg *Config, desired map[string]string, managed []string, compare ValueComparison, decrypt func(key, value string) (string, error)) ([]Change, error) {
+	want := make(map[string]string, len(desired))
+	for k, v := range desired {
+		ck := cfg.canonicalKey(k)
+		if ck == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidKey, k)
+		}
+		want[ck] = v
+	}
+
+	changes := make([]Change, 0, len(want))
+	for _, k := range slices.Sorted(maps.Keys(want)) {
+		v := want[k]
+		cur, found := cfg.Get(k)
+		if !found {
+			changes = append(changes, Change{Kind: ChangeAdded, Key: k, After: v})
+
+			continue
+		}
+		if pt, err := decrypt(k, cur); err == nil {
+			if compare.equal(pt, v) {
+				continue
+			}
+			cur = pt
+		}
+		changes = append(changes, Change{Kind: ChangeUpdated, Key: k, Before: cur, After: v})
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(cfg.vars)) {
+		if _, found := want[k]; found {
+			continue
+		}
+		if !slices.ContainsFunc(managed, func(prefix string) bool {
+			return strings.HasPrefix(k, prefix)
+		}) {
+			continue
+		}
+		cur, _ := cfg.Get(k)
+		if pt, err := decrypt(k, cur); err == nil {
+			cur = pt
+		}
+		changes = append(changes, Change{Kind: ChangeRemoved, Key: k, Before: cur})
+	}
+
+	return changes, nil
+}
+
+// writableScope returns the config for the given scope, initializing it
+// the same way the scope specific setters do. Scopes that have not been
+// loaded get the settings LoadAll applies, see applyScopeSettings.
+func (cs *Configs) writableScope(scope string) (*Config, error) {
+	switch strings.ToLower(scope) {
+	case ScopeEnv:
+		if cs.env == nil {
+			cs.env = &Config{
+				noWrites: true,
+			}
+		}
+
+		return cs.env, nil
+	case ScopeWorktree:
+		if cs.workdir == "" {
+			return nil, ErrWorkdirNotSet
+		}
+		// git ignores the file unless the extension is enabled, it is
+		// enabled by Converge if needed
+		if _, err := cs.checkWorktreeConfig(); err != nil {
+			return nil, err
+		}
+		if cs.worktree == nil {
+			cs.worktree = &Config{}
+		}
+		if cs.worktree.path == "" {
+			cs.worktree.path = repoConfigPath(cs.workdir, cs.WorktreeConfig, false, cs.repo)
+		}
+
+		return cs.initScope(cs.worktree), nil
+	case ScopeLocal:
+		if cs.workdir == "" {
+			return nil, ErrWorkdirNotSet
+		}
+		if cs.local == nil {
+			cs.local = &Config{}
+		}
+		if cs.local.path == "" {
+			cs.local.path = repoConfigPath(cs.workdir, cs.LocalConfig, true, cs.repo)
+		}
+
+		return cs.initScope(cs.local), nil
+	case ScopeGlobal:
+		if cs.global == nil {
+			cs.global = &Config{
+				path: globalConfigFile(cs.Name),
+			}
+		}
+
+		return cs.initScope(cs.global), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownScope, scope)
+	}
+}
+
+// initScope applies the settings of cs to c if it has not been loaded.
+func (cs *Configs) initScope(c *Config) *Config {
+	if !c.IsEmpty() {
+		return c
+	}
+	cs.applyScopeSettings(c)
+	cs.applyCompat(c)
+
+	return c
+}
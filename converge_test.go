@@ -0,0 +1,254 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverge(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	c := New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.GlobalConfig = ""
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_CONFIG"
+
+	localPath := filepath.Join(td, c.LocalConfig)
+	require.NoError(t, os.WriteFile(localPath, []byte(`[core]
+	autocrlf = yes
+	editor = vim
+[mytool]
+	enabled = true
+	stale = 1
+[other]
+	key = keep
+`), 0o600))
+
+	c.LoadAll(td)
+
+	desired := map[string]string{
+		"core.autocrlf":  "true",
+		"core.editor":    "nano",
+		"core.pager":     "less",
+		"mytool.enabled": "on",
+	}
+
+	// values can be compared like Set does, exact by default
+	report, err := c.Converge(desired, "local", ConvergeOptions{DryRun: true, UseScopeComparison: true})
+	require.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Kind: ChangeAdded, Key: "core.pager", After: "less"},
+		{Kind: ChangeUpdated, Key: "core.autocrlf", Before: "yes", After: "true"},
+		{Kind: ChangeUpdated, Key: "core.editor", Before: "vim", After: "nano"},
+		{Kind: ChangeUpdated, Key: "mytool.enabled", Before: "true", After: "on"},
+	}, report.Changes())
+
+	// dry run doesn't modify anything
+	report, err = c.Converge(desired, "local", ConvergeOptions{Managed: []string{"mytool."}, DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, report.Files, 1)
+	assert.Equal(t, localPath, report.Files[0].Path)
+	assert.Equal(t, []Change{
+		{Kind: ChangeAdded, Key: "core.pager", After: "less"},
+		{Kind: ChangeUpdated, Key: "core.editor", Before: "vim", After: "nano"},
+		{Kind: ChangeRemoved, Key: "mytool.stale", Before: "1"},
+	}, report.Changes())
+	assert.Equal(t, "vim", c.GetLocal("core.editor"))
+
+	report, err = c.Converge(desired, "local", ConvergeOptions{Managed: []string{"mytool."}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Len())
+
+	buf, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.Equal(t, `[core]
+	pager = less
+	autocrlf = yes
+	editor = nano
+[mytool]
+	enabled = true
+[other]
+	key = keep
+`, string(buf))
+
+	// converging again is a no-op
+	report, err = c.Converge(desired, "local", ConvergeOptions{Managed: []string{"mytool."}})
+	require.NoError(t, err)
+	assert.True(t, report.IsEmpty())
+
+	// scopes that were not loaded get the settings of the instance
+	nc := New()
+	nc.NoWrites = true
+	nc.Comparison = CompareNormalized
+	_, err = nc.Converge(map[string]string{"core.editor": "vim"}, "global", ConvergeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "vim", nc.GetGlobal("core.editor"))
+	assert.Equal(t, CompareNormalized, nc.global.compare)
+	_, err = os.Stat(globalConfigFile(nc.Name))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestConvergeIncludedKey(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	c := New()
+	c.SystemConfig = ""
+	c.GlobalConfig = ""
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_CONVERGE_INCLUDE"
+
+	localPath := filepath.Join(td, c.LocalConfig)
+	incPath := filepath.Join(td, "inc.config")
+	require.NoError(t, os.WriteFile(localPath, []byte("[include]\n\tpath = inc.config\n"), 0o600))
+	require.NoError(t, os.WriteFile(incPath, []byte("[core]\n\teditor = vim\n"), 0o600))
+
+	c.LoadAll(td)
+
+	desired := map[string]string{
+		"core.editor": "nano",
+		"core.pager":  "less",
+	}
+	report, err := c.Converge(desired, "local", ConvergeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Len())
+
+	// the included key is updated where it is defined, not shadowed
+	buf, err := os.ReadFile(incPath)
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\teditor = nano\n", string(buf))
+	buf, err = os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.Equal(t, "[include]\n\tpath = inc.config\n[core]\n\tpager = less\n", string(buf))
+
+	c.LoadAll(td)
+	assert.Equal(t, "nano", c.GetLocal("core.editor"))
+	report, err = c.Converge(desired, "local", ConvergeOptions{})
+	require.NoError(t, err)
+	assert.True(t, report.IsEmpty())
+}
+
+func TestConvergeWorktree(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	gitDir := filepath.Join(td, ".git")
+	require.NoError(t, os.Mkdir(gitDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "config"), []byte("[core]\n\trepositoryformatversion = 0\n"), 0o644))
+
+	c := New()
+	c.SystemConfig = ""
+	c.LocalConfig = filepath.Join(".git", "config")
+	c.WorktreeConfig = filepath.Join(".git", "config.worktree")
+	c.EnvPrefix = "GPTEST_CONVERGE_WORKTREE"
+	c.LoadAll(td)
+
+	// git ignores the worktree config unless the extension is enabled
+	desired := map[string]string{"core.sparsecheckout": "true"}
+	_, err := c.Converge(desired, "worktree", ConvergeOptions{DryRun: true})
+	require.ErrorIs(t, err, ErrWorktreeConfigDisabled)
+	_, err = c.Converge(desired, "worktree", ConvergeOptions{})
+	require.ErrorIs(t, err, ErrWorktreeConfigDisabled)
+	_, err = os.Stat(filepath.Join(gitDir, "config.worktree"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// a dry run doesn't enable it
+	c.EnableWorktreeConfig = true
+	report, err := c.Converge(desired, "worktree", ConvergeOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Len())
+	assert.Empty(t, c.GetLocal("extensions.worktreeConfig"))
+
+	_, err = c.Converge(desired, "worktree", ConvergeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "true", c.GetLocal("extensions.worktreeConfig"))
+	content, err := os.ReadFile(filepath.Join(gitDir, "config.worktree"))
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\tsparsecheckout = true\n", string(content))
+}
+
+func TestConvergeErrors(t *testing.T) {
+	t.Parallel()
+
+	c := New()
+	c.NoWrites = true
+
+	_, err := c.Converge(map[string]string{"core.editor": "vim"}, "system", ConvergeOptions{})
+	require.ErrorIs(t, err, ErrUnknownScope)
+
+	_, err = c.Converge(map[string]string{"core.editor": "vim"}, "local", ConvergeOptions{})
+	require.ErrorIs(t, err, ErrWorkdirNotSet)
+
+	_, err = c.Converge(map[string]string{"invalid": "vim"}, "env", ConvergeOptions{})
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestConfigTransactionRollback(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	fn := filepath.Join(td, "config")
+	in := "[core]\n\teditor = vim\n"
+	require.NoError(t, os.WriteFile(fn, []byte(in), 0o600))
+
+	cfg, err := LoadConfig(fn)
+	require.NoError(t, err)
+
+	err = cfg.transaction(func() error {
+		require.NoError(t, cfg.Set("core.editor", "nano"))
+
+		return cfg.Set("invalid", "value")
+	})
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	v, _ := cfg.Get("core.editor")
+	assert.Equal(t, "vim", v)
+	assert.Equal(t, in, cfg.raw.String())
+
+	buf, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, in, string(buf))
+}
+
+func TestConfigTransactionRollbackIncluded(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	fn := filepath.Join(td, "config")
+	inc := filepath.Join(td, "included")
+	require.NoError(t, os.WriteFile(fn, []byte("[include]\n\tpath = included\n"), 0o600))
+	require.NoError(t, os.WriteFile(inc, []byte("[core]\n\teditor = vim\n"), 0o600))
+
+	cfg, err := LoadConfig(fn)
+	require.NoError(t, err)
+
+	// the included file is only written if the transaction succeeds
+	err = cfg.transaction(func() error {
+		require.NoError(t, cfg.Set("core.editor", "nano"))
+
+		return cfg.Set("invalid", "value")
+	})
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	v, _ := cfg.Get("core.editor")
+	assert.Equal(t, "vim", v)
+	buf, err := os.ReadFile(inc)
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\teditor = vim\n", string(buf))
+
+	require.NoError(t, cfg.transaction(func() error {
+		return cfg.Set("core.editor", "emacs")
+	}))
+	buf, err = os.ReadFile(inc)
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\teditor = emacs\n", string(buf))
+	buf, err = os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, "[include]\n\tpath = included\n", string(buf))
+}
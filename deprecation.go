@@ -0,0 +1,102 @@
+package gitconfig
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Deprecation marks a key as deprecated.
+//
+// Fields:
+// - Key: The deprecated key
+// - Replacement: The key that should be used instead, if any
+// - Message: Additional information for the user, if any
+type Deprecation struct {
+	Key         string `json:"key"`
+	Replacement string `json:"replacement,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
+// String implements fmt.Stringer.
+func (d Deprecation) String() string {
+	s := d.Key + " is deprecated"
+	if d.Replacement != "" {
+		s += fmt.Sprintf(", use %s instead", d.Replacement)
+	}
+	if d.Message != "" {
+		s += ": " + d.Message
+	}
+
+	return s
+}
+
+// reportedDeprecations records the keys that have been reported already.
+// Every deprecated key is only reported once per process.
+var reportedDeprecations sync.Map
+
+// Deprecate marks a key as deprecated. Reading the key with Get, GetAll or
+// one of the typed getters reports its usage once per process, either to
+// OnDeprecated or, if that is not set, to the debug log.
+//
+// Example:
+//
+//	cfg.Deprecate(gitconfig.Deprecation{Key: "core.autoimport", Replacement: "core.autosync"})
+func (cs *Configs) Deprecate(d Deprecation) {
+	if cs.deprecations == nil {
+		cs.deprecations = make(map[string]Deprecation, 8)
+	}
+	cs.deprecations[cs.canonicalKey(d.Key)] = d
+}
+
+// Deprecation returns the deprecation of the key, if it is deprecated.
+func (cs *Configs) Deprecation(key string) (Deprecation, bool) {
+	d, found := cs.deprecations[cs.canonicalKey(key)]
+
+	return d, found
+}
+
+// ListEntry is a key returned by ListAnnotated.
+type ListEntry struct {
+	Key        string       `json:"key"`
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+}
+
+// ListAnnotated is like List but annotates deprecated keys. Listing
+// doesn't count as usage, so nothing is reported.
+func (cs *Configs) ListAnnotated(prefix string) []ListEntry {
+	keys := cs.List(prefix)
+	out := make([]ListEntry, 0, len(keys))
+	for _, k := range keys {
+		e := ListEntry{Key: k}
+		if d, found := cs.Deprecation(k); found {
+			e.Deprecated = &d
+		}
+		out = append(out, e)
+	}
+
+	return out
+}
+
+// reportDeprecated reports the usage of a deprecated key, at most once
+// per key and process.
+func (cs *Configs) reportDeprecated(key string) {
+	if len(cs.deprecations) < 1 {
+		return
+	}
+
+	d, found := cs.Deprecation(key)
+	if !found {
+		return
+	}
+	if _, loaded := reportedDeprecations.LoadOrStore(canonicalizeKey(key), struct{}{}); loaded {
+		return
+	}
+
+	if cs.OnDeprecated != nil {
+		cs.OnDeprecated(d)
+
+		return
+	}
+
+	debug.Log("[%s] %s", cs.Name, d)
+}
@@ -0,0 +1,56 @@
+package gitconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecation(t *testing.T) {
+	t.Parallel()
+
+	cs := New()
+	cs.local = ParseConfig(strings.NewReader(`[deprecationtest]
+	old = 1
+	multi = a
+	multi = b
+	current = 2
+`))
+
+	var reported []Deprecation
+	cs.OnDeprecated = func(d Deprecation) {
+		reported = append(reported, d)
+	}
+	cs.Deprecate(Deprecation{Key: "deprecationTest.old", Replacement: "deprecationtest.current"})
+	cs.Deprecate(Deprecation{Key: "deprecationtest.multi", Message: "no longer used"})
+
+	d, found := cs.Deprecation("deprecationtest.OLD")
+	require.True(t, found)
+	assert.Equal(t, "deprecationTest.old is deprecated, use deprecationtest.current instead", d.String())
+	assert.Equal(t, "deprecationtest.multi is deprecated: no longer used", Deprecation{Key: "deprecationtest.multi", Message: "no longer used"}.String())
+
+	// listing is not usage
+	assert.Equal(t, []ListEntry{
+		{Key: "deprecationtest.current"},
+		{Key: "deprecationtest.multi", Deprecated: &Deprecation{Key: "deprecationtest.multi", Message: "no longer used"}},
+		{Key: "deprecationtest.old", Deprecated: &Deprecation{Key: "deprecationTest.old", Replacement: "deprecationtest.current"}},
+	}, cs.ListAnnotated("deprecationtest."))
+	assert.Empty(t, reported)
+
+	assert.Equal(t, "2", cs.Get("deprecationtest.current"))
+	assert.Empty(t, reported)
+
+	for range 3 {
+		assert.Equal(t, "1", cs.Get("deprecationtest.old"))
+		n, _, err := cs.GetInt("deprecationtest.old")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		assert.Equal(t, []string{"a", "b"}, cs.GetAll("deprecationtest.multi"))
+	}
+
+	require.Len(t, reported, 2)
+	assert.Equal(t, "deprecationTest.old", reported[0].Key)
+	assert.Equal(t, "deprecationtest.multi", reported[1].Key)
+}
@@ -0,0 +1,34 @@
+package gitconfig
+
+// The core of this package only depends on the standard library. The
+// helpers below are provided by third-party packages in the default build
+// (see deps_default.go) and by internal fallbacks when building with the
+// gitconfig_nodeps tag (see deps_nodeps.go):
+//
+//	go build -tags gitconfig_nodeps
+//
+// Without the tag debug logging uses github.com/gopasspw/gopass/pkg/debug,
+// the user directories follow github.com/gopasspw/gopass/pkg/appdir (e.g.
+// GOPASS_HOMEDIR).
+
+// verboseLogger logs a debug message at a fixed verbosity.
+type verboseLogger interface {
+	Log(format string, args ...any)
+}
+
+// debugLogger is the type of debug, which is used like the gopass debug
+// package: debug.Log(...) or debug.V(1).Log(...).
+type debugLogger struct{}
+
+// debug logs debug messages, see logDebug.
+var debug debugLogger
+
+// V returns a logger for messages at the given verbosity.
+func (debugLogger) V(level int) verboseLogger {
+	return logVerbose(level)
+}
+
+// Log logs a debug message.
+func (debugLogger) Log(format string, args ...any) {
+	logDebug(format, args...)
+}
@@ -0,0 +1,28 @@
+//go:build !gitconfig_nodeps
+
+package gitconfig
+
+import (
+	"github.com/gopasspw/gopass/pkg/appdir"
+	gdebug "github.com/gopasspw/gopass/pkg/debug"
+)
+
+func logVerbose(level int) verboseLogger {
+	return gdebug.V(level)
+}
+
+func logDebug(format string, args ...any) {
+	gdebug.Log(format, args...)
+}
+
+// appHome returns the home directory of the current user, or "" if it can
+// not be determined.
+func appHome() string {
+	return appdir.UserHome()
+}
+
+// userConfigDir returns the per-user config directory of the application,
+// e.g. $XDG_CONFIG_HOME/git.
+func userConfigDir(name string) string {
+	return appdir.New(name).UserConfig()
+}
@@ -0,0 +1,70 @@
+//go:build gitconfig_nodeps
+
+package gitconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// debugLevel is the highest verbosity that is logged, from GITCONFIG_DEBUG.
+// Debug logging is disabled if it is not set.
+var debugLevel = func() int {
+	v, found := os.LookupEnv("GITCONFIG_DEBUG")
+	if !found {
+		return -1
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0
+	}
+
+	return n
+}()
+
+// stderrLogger writes debug messages to stderr.
+type stderrLogger bool
+
+func (l stderrLogger) Log(format string, args ...any) {
+	if l {
+		fmt.Fprintf(os.Stderr, "[gitconfig] "+format+"\n", args...)
+	}
+}
+
+func logVerbose(level int) verboseLogger {
+	return stderrLogger(level <= debugLevel)
+}
+
+func logDebug(format string, args ...any) {
+	stderrLogger(debugLevel >= 0).Log(format, args...)
+}
+
+// appHome returns the home directory of the current user, or "" if it can
+// not be determined. Like the default build it honors GOPASS_HOMEDIR.
+func appHome() string {
+	if home := os.Getenv("GOPASS_HOMEDIR"); home != "" {
+		return home
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return home
+}
+
+// userConfigDir returns the per-user config directory of the application,
+// $XDG_CONFIG_HOME/<name> or ~/.config/<name> like git uses on all
+// platforms.
+func userConfigDir(name string) string {
+	if home := os.Getenv("GOPASS_HOMEDIR"); home != "" {
+		return filepath.Join(home, ".config", name)
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, name)
+	}
+
+	return filepath.Join(appHome(), ".config", name)
+}
@@ -0,0 +1,234 @@
+package gitconfig
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FindingCode is a machine-readable identifier of a Diagnose finding.
+type FindingCode string
+
+const (
+	// FindingUnreadableInclude indicates an include target that can not be read.
+	FindingUnreadableInclude FindingCode = "unreadable-include"
+	// FindingIncludeNeverMatches indicates an includeIf condition that doesn't match the current repository.
+	FindingIncludeNeverMatches FindingCode = "include-never-matches"
+	// FindingUnsupportedCondition indicates an includeIf condition that is not supported.
+	FindingUnsupportedCondition FindingCode = "unsupported-include-condition"
+	// FindingConflictingValues indicates a key that is set to different values in multiple scopes.
+	FindingConflictingValues FindingCode = "conflicting-values"
+	// FindingInvalidBool indicates a known boolean key with a value that is not a valid boolean.
+	FindingInvalidBool FindingCode = "invalid-bool"
+)
+
+// knownBoolKeys lists the keys git defines as booleans. Diagnose reports
+// invalid values for these keys, see DiagnoseOptions.BoolKeys.
+var knownBoolKeys = []string{
+	"core.bare",
+	"core.filemode",
+	"core.ignorecase",
+	"core.symlinks",
+	"core.precomposeunicode",
+	"commit.gpgsign",
+	"tag.gpgsign",
+	"fetch.prune",
+	"push.autosetupremote",
+	"rebase.autostash",
+}
+
+// DiagnoseOptions control the checks of Diagnose.
+//
+// Fields:
+// - BoolKeys: Keys that must hold a boolean value, in addition to the boolean keys of git like core.bare
+type DiagnoseOptions struct {
+	BoolKeys []string
+}
+
+// Finding is a single result of Diagnose.
+//
+// Fields:
+// - Code: Machine-readable identifier of the problem
+// - Scope: The scope the problem was found in, if any
+// - Key: The affected key, if any
+// - Path: The affected file, if any
+// - Message: Human readable description
+type Finding struct {
+	Code    FindingCode `json:"code"`
+	Scope   string      `json:"scope,omitempty"`
+	Key     string      `json:"key,omitempty"`
+	Path    string      `json:"path,omitempty"`
+	Message string      `json:"message"`
+}
+
+// String implements fmt.Stringer.
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s", f.Code, f.Message)
+}
+
+// Diagnose checks the loaded configuration for common misconfigurations
+// and returns actionable findings. It is meant to be embedded into an
+// application's "doctor" command.
+//
+// It reports:
+//   - include targets that can not be read
+//   - includeIf conditions that don't match the current repository or are not supported
+//   - keys that are set to conflicting values in multiple scopes
+//   - invalid booleans for known boolean keys and opts.BoolKeys
+//
+// Includes are reported as they were resolved when the scopes were loaded,
+// see Configs.Includes, so the findings follow the include limits, the
+// IncludeErrors policy and the branch of the last LoadAll.
+//
+// Example:
+//
+//	for _, f := range cfg.Diagnose(gitconfig.DiagnoseOptions{BoolKeys: []string{"mytool.enabled"}}) {
+//		fmt.Println(f)
+//	}
+func (cs *Configs) Diagnose(opts DiagnoseOptions) []Finding {
+	findings := make([]Finding, 0, 8)
+
+	for _, sc := range cs.namedScopes() {
+		if sc.cfg == nil || sc.cfg.path == "" || sc.name == ScopeEnv || sc.name == ScopePreset {
+			continue
+		}
+		findings = append(findings, cs.diagnoseIncludes(sc.name, sc.cfg)...)
+	}
+
+	findings = append(findings, cs.diagnoseConflicts()...)
+	findings = append(findings, cs.diagnoseBools(opts.BoolKeys)...)
+
+	return findings
+}
+
+// diagnoseIncludes reports the unreadable targets and the includeIf
+// conditions that don't match in the include graph of c, as it was
+// recorded when the scope was loaded, see Config.Includes.
+func (cs *Configs) diagnoseIncludes(scope string, c *Config) []Finding {
+	findings := make([]Finding, 0, 4)
+
+	// the scope could not be loaded because of an include, see IncludeErrorFail
+	var ierr *IncludeError
+	if sr, ok := cs.report.Scope(scope); ok && errors.As(sr.err, &ierr) {
+		findings = append(findings, unreadableInclude(scope, ierr.SkippedInclude))
+	}
+	for _, s := range c.SkippedIncludes() {
+		findings = append(findings, unreadableInclude(scope, s))
+	}
+
+	seen := map[string]bool{}
+	for _, n := range c.Includes() {
+		if !n.Conditional() || n.Matched {
+			continue
+		}
+		// a section may include several files, the condition is reported once
+		k := "includeif." + n.Condition + ".path"
+		if seen[n.Parent+"\x00"+k] {
+			continue
+		}
+		seen[n.Parent+"\x00"+k] = true
+
+		if !isSupportedCondition(n.Condition) {
+			findings = append(findings, Finding{
+				Code:    FindingUnsupportedCondition,
+				Scope:   scope,
+				Key:     k,
+				Path:    n.Parent,
+				Message: fmt.Sprintf("includeIf condition %q in %s is not supported", n.Condition, n.Parent),
+			})
+
+			continue
+		}
+		findings = append(findings, Finding{
+			Code:    FindingIncludeNeverMatches,
+			Scope:   scope,
+			Key:     k,
+			Path:    n.Parent,
+			Message: fmt.Sprintf("includeIf condition %q in %s does not match the current repository", n.Condition, n.Parent),
+		})
+	}
+
+	return findings
+}
+
+// unreadableInclude returns the finding for an include that could not be read.
+func unreadableInclude(scope string, s SkippedInclude) Finding {
+	return Finding{
+		Code:    FindingUnreadableInclude,
+		Scope:   scope,
+		Path:    s.Path,
+		Message: fmt.Sprintf("included file %s can not be read: %s", s.Path, s.Err),
+	}
+}
+
+// isSupportedCondition returns true if the includeIf condition is one we can evaluate.
+func isSupportedCondition(cond string) bool {
+	return parseCondition(cond).kind != conditionUnsupported
+}
+
+// diagnoseConflicts reports keys that are set to different values in multiple scopes.
+func (cs *Configs) diagnoseConflicts() []Finding {
+	findings := make([]Finding, 0, 4)
+
+	for _, k := range cs.KeysExcept(ScopePreset) {
+		var defs []string
+		var values []string
+		for _, sc := range cs.namedScopes() {
+			if sc.cfg == nil || sc.cfg.vars == nil || sc.name == ScopePreset {
+				continue
+			}
+			v, found := sc.cfg.Get(k)
+			if !found {
+				continue
+			}
+			defs = append(defs, fmt.Sprintf("%s (%q)", sc.name, v))
+			if !slices.Contains(values, v) {
+				values = append(values, v)
+			}
+		}
+		if len(values) < 2 {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			Code:    FindingConflictingValues,
+			Key:     k,
+			Message: fmt.Sprintf("%s is set to conflicting values in %s, the first one wins", k, strings.Join(defs, ", ")),
+		})
+	}
+
+	return findings
+}
+
+// diagnoseBools reports known boolean keys and the given extra keys with
+// invalid values.
+func (cs *Configs) diagnoseBools(extra []string) []Finding {
+	findings := make([]Finding, 0, 4)
+
+	for _, k := range slices.Concat(knownBoolKeys, extra) {
+		for _, sc := range cs.namedScopes() {
+			if sc.cfg == nil || sc.cfg.vars == nil {
+				continue
+			}
+			vs, found := sc.cfg.GetAll(k)
+			if !found {
+				continue
+			}
+			for _, v := range vs {
+				if _, err := sc.cfg.parseBool(v); err == nil {
+					continue
+				}
+				findings = append(findings, Finding{
+					Code:    FindingInvalidBool,
+					Scope:   sc.name,
+					Key:     k,
+					Path:    sc.cfg.path,
+					Message: fmt.Sprintf("%s in %s scope is %q, which is not a valid boolean", k, sc.name, v),
+				})
+			}
+		}
+	}
+
+	return findings
+}
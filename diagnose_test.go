@@ -0,0 +1,99 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnose(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	workdir := filepath.Join(td, "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(workdir, ".git"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(workdir, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0o600))
+
+	globalFn := filepath.Join(td, "global")
+	require.NoError(t, os.WriteFile(globalFn, []byte(`[include]
+	path = missing.conf
+[includeIf "gitdir:/nowhere/"]
+	path = other.conf
+[includeIf "onbranch:main"]
+	path = branch.conf
+[includeIf "hasconfig:remote.*.url:foo"]
+	path = other.conf
+[core]
+	editor = vim
+	filemode = maybe
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "branch.conf"), []byte("[user]\n\tname = main\n"), 0o600))
+
+	localFn := filepath.Join(workdir, "local")
+	require.NoError(t, os.WriteFile(localFn, []byte("[core]\n\teditor = nano\n\tbare = false\n"), 0o600))
+
+	c := New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.GlobalConfig = "global"
+	c.LocalConfig = "local"
+	c.WorktreeConfig = ""
+	c.EnvPrefix = "GPTEST_DIAGNOSE"
+	c.LoadAll(workdir)
+	assert.Equal(t, "main", c.GetGlobal("user.name"))
+
+	findings := c.Diagnose(DiagnoseOptions{})
+
+	codes := map[FindingCode][]Finding{}
+	for _, f := range findings {
+		codes[f.Code] = append(codes[f.Code], f)
+	}
+
+	require.Len(t, codes[FindingUnreadableInclude], 1)
+	assert.Equal(t, filepath.Join(td, "missing.conf"), codes[FindingUnreadableInclude][0].Path)
+	assert.Equal(t, "global", codes[FindingUnreadableInclude][0].Scope)
+
+	require.Len(t, codes[FindingIncludeNeverMatches], 1)
+	assert.Equal(t, "includeif.gitdir:/nowhere/.path", codes[FindingIncludeNeverMatches][0].Key)
+
+	require.Len(t, codes[FindingUnsupportedCondition], 1)
+
+	require.Len(t, codes[FindingConflictingValues], 1)
+	assert.Equal(t, "core.editor", codes[FindingConflictingValues][0].Key)
+	assert.Contains(t, codes[FindingConflictingValues][0].Message, `local ("nano")`)
+
+	require.Len(t, codes[FindingInvalidBool], 1)
+	assert.Equal(t, "core.filemode", codes[FindingInvalidBool][0].Key)
+	assert.Equal(t, "[invalid-bool] core.filemode in global scope is \"maybe\", which is not a valid boolean", codes[FindingInvalidBool][0].String())
+
+	// the findings follow how the scopes were loaded
+	c.SetBranch("other")
+	codes = map[FindingCode][]Finding{}
+	for _, f := range c.Diagnose(DiagnoseOptions{}) {
+		codes[f.Code] = append(codes[f.Code], f)
+	}
+	require.Len(t, codes[FindingIncludeNeverMatches], 2)
+	assert.Equal(t, "includeif.onbranch:main.path", codes[FindingIncludeNeverMatches][1].Key)
+
+	c.IncludeErrors = IncludeErrorFail
+	c.LoadAll(workdir)
+	codes = map[FindingCode][]Finding{}
+	for _, f := range c.Diagnose(DiagnoseOptions{}) {
+		codes[f.Code] = append(codes[f.Code], f)
+	}
+	require.Len(t, codes[FindingUnreadableInclude], 1)
+	assert.Equal(t, filepath.Join(td, "missing.conf"), codes[FindingUnreadableInclude][0].Path)
+	assert.Empty(t, codes[FindingIncludeNeverMatches])
+
+	// applications can add their own boolean keys
+	findings = c.Diagnose(DiagnoseOptions{BoolKeys: []string{"core.editor"}})
+	codes = map[FindingCode][]Finding{}
+	for _, f := range findings {
+		codes[f.Code] = append(codes[f.Code], f)
+	}
+	require.Len(t, codes[FindingInvalidBool], 1)
+	assert.Equal(t, "core.editor", codes[FindingInvalidBool][0].Key)
+	assert.Equal(t, ScopeLocal, codes[FindingInvalidBool][0].Scope)
+}
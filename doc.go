@@ -1,6 +1,6 @@
 // Package gitconfig implements a pure Go parser of Git SCM config files. The support
-// is currently not matching git exactly, e.g. includes, urlmatches and multivars are currently
-// not supported. And while we try to preserve the original file a much as possible
+// is currently not matching git exactly, e.g. includes and multivars are currently
+// not fully supported. And while we try to preserve the original file a much as possible
 // when writing we currently don't exactly retain (insignificant) whitespaces.
 //
 // The reference for this implementation is https://mirrors.edge.kernel.org/pub/software/scm/git/docs/git-config.html
@@ -80,6 +80,7 @@
 // Write to specific scopes in multi-scope configs:
 //
 //	cfg := gitconfig.New()
+//	cfg.AllowSystemWrites = true // the system config is read-only otherwise
 //	cfg.LoadAll(".")
 //	cfg.SetLocal("core.autocrlf", "true")   // Write to .git/config
 //	cfg.SetGlobal("user.signingkey", "...")  // Write to ~/.gitconfig
@@ -110,6 +111,5 @@
 // # Known limitations
 //
 // * Worktree support is only partial
-// * Bare boolean values are not supported (e.g. a setting were only the key is present)
 // * includeIf suppport is only partial, i.e. we only support the gitdir option
 package gitconfig
@@ -0,0 +1,687 @@
+package gitconfig
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// lineKind is the kind of a line in a config document.
+type lineKind uint8
+
+const (
+	// lineOther is a blank line or a comment.
+	lineOther lineKind = iota
+	// lineSection is a section header.
+	lineSection
+	// lineKeyValue is a key-value pair or a bare key.
+	lineKeyValue
+	// lineInvalid is a line that could not be parsed. It is kept as is.
+	lineInvalid
+)
+
+// docLine is a single logical line of a config document. A quoted value
+// that contains newlines spans several physical lines.
+//
+// Fields:
+// - kind: What the line contains
+// - text: The original text, physical lines are joined by "\n"
+// - line: The number of the first physical line, starting at 1
+// - section, subsection: The section the line belongs to (or starts)
+// - key: The canonical key of a key-value line (see KeyRules)
+// - name: The variable name as written
+// - value: The parsed value (see splitValue)
+// - comment: A trailing comment including its delimiter, if any
+// - bare: If the key is written without "=" (see SetBare)
+// - indent, sep: The layout of a key-value line, see lineStyle
+type docLine struct {
+	kind       lineKind
+	text       string
+	line       int
+	section    string
+	subsection string
+	key        string
+	name       string
+	value      string
+	comment    string
+	bare       bool
+	lineStyle
+}
+
+// lineStyle is the layout of a key-value line, so rewritten and inserted
+// lines look like the rest of the file.
+//
+// Fields:
+// - indent: The whitespace before the name
+// - sep: Everything between the name and the value, e.g. " = " or "="
+type lineStyle struct {
+	indent string
+	sep    string
+}
+
+// defaultStyle is used for files without any key-value lines.
+var defaultStyle = lineStyle{indent: "\t", sep: " = "}
+
+// format returns the text of a key-value line in the style of the line.
+// The value is quoted and escaped if unescape is set (see QuoteValue). An
+// empty value is written as "key = ", like git does.
+func (l docLine) format(unescape bool) string {
+	if l.bare {
+		return l.indent + l.name + l.comment
+	}
+
+	value := l.value
+	if unescape {
+		value = QuoteValue(value)
+	}
+
+	// plain concatenation, this is used for every rewritten line
+	return l.indent + l.name + l.sep + value + l.comment
+}
+
+// commentText returns the inline comment of the line without its
+// delimiter and surrounding whitespace.
+func (l docLine) commentText() string {
+	comment := strings.TrimSpace(l.comment)
+	if comment == "" {
+		return ""
+	}
+
+	return strings.TrimSpace(comment[1:])
+}
+
+// isComment returns true if the line is a full-line comment.
+func (l docLine) isComment() bool {
+	if l.kind != lineOther {
+		return false
+	}
+	text := strings.TrimSpace(l.text)
+
+	return text != "" && (text[0] == '#' || text[0] == ';')
+}
+
+// rawValue returns the value of a key-value line as written, including
+// quotes, escape sequences and any trailing comment.
+func (l docLine) rawValue() string {
+	_, v, _ := strings.Cut(l.text, "=")
+
+	return strings.TrimSpace(v)
+}
+
+// tokenizer splits a config into docLines. It keeps track of the current
+// section and subsection and passes every line to emit, so large configs
+// can be loaded without keeping the whole document in memory.
+type tokenizer struct {
+	emit       func(docLine)
+	unescape   bool // process quotes and escape sequences in values, see splitValue
+	section    string
+	subsection string
+	prefix     string // section and subsection of the current line, joined by "."
+	lineNo     int
+	pending    []string // physical lines of a quoted value that is not closed yet
+	issues     []parseIssue
+	format     fileFormat
+
+	skipSection bool           // the current section header exceeds a limit, see LimitError
+	values      map[string]int // number of values per key, see KeyRules.MaxValuesPerKey
+	keys        KeyRules       // how keys are canonicalized
+	maxLine     int            // see parseOptions.maxLineLength
+}
+
+// fileFormat describes the line endings and byte order mark of a file, so
+// it can be written back the same way (see Config.flushRaw).
+//
+// Fields:
+// - crlf: Most lines end with "\r\n"
+// - noFinalNewline: The last line does not end with a line break
+// - bom: The file starts with a UTF-8 byte order mark
+type fileFormat struct {
+	crlf           bool
+	noFinalNewline bool
+	bom            bool
+}
+
+// utf8BOM is the UTF-8 byte order mark some editors put at the start of a file.
+const utf8BOM = "\ufeff"
+
+// apply converts text with "\n" line endings and without a byte order mark
+// to this format.
+func (f fileFormat) apply(text string) string {
+	if f.noFinalNewline {
+		text = strings.TrimSuffix(text, "\n")
+	}
+	if f.crlf {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	if f.bom {
+		text = utf8BOM + text
+	}
+
+	return text
+}
+
+func newTokenizer(unescape bool, emit func(docLine)) *tokenizer {
+	return &tokenizer{emit: emit, unescape: unescape}
+}
+
+// parseIssue describes a line that was ignored by the parser.
+type parseIssue struct {
+	path string
+	line int
+	msg  string
+	text string // the offending line, if any
+}
+
+func (pi parseIssue) String() string {
+	if pi.path == "" {
+		return fmt.Sprintf("line %d: %s", pi.line, pi.msg)
+	}
+
+	return fmt.Sprintf("%s:%d: %s", pi.path, pi.line, pi.msg)
+}
+
+// tokenize passes all lines from in to the tokenizer. It also records the
+// dominant line ending and whether the last line is terminated. If in can
+// not be read to the end, e.g. because a line is longer than t.maxLine,
+// the lines read so far are kept, the problem is recorded as an issue and
+// returned as error. Binary content (a NUL byte) stops the tokenizer with
+// ErrNotAConfigFile.
+func (t *tokenizer) tokenize(in io.Reader) error {
+	var lf, crlf int
+	limit := limitOrDefault(t.maxLine, defaultMaxLineLength)
+	if limit == 0 {
+		limit = math.MaxInt
+	}
+	s := bufio.NewScanner(in)
+	s.Buffer(make([]byte, 0, min(limit, bufio.MaxScanTokenSize)), limit)
+	s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if token == nil {
+			return advance, token, err
+		}
+		switch {
+		case advance > 1 && data[advance-2] == '\r' && data[advance-1] == '\n':
+			crlf++
+		case advance > 0 && data[advance-1] == '\n':
+			lf++
+		default:
+			// the last line, without a line break
+			t.format.noFinalNewline = true
+		}
+
+		return advance, token, err
+	})
+	for s.Scan() {
+		line := s.Text()
+		if strings.IndexByte(line, 0) >= 0 {
+			t.issues = append(t.issues, parseIssue{line: t.lineNo + len(t.pending) + 1, msg: "NUL byte, not a config file"})
+
+			return fmt.Errorf("%w: NUL byte in line %d", ErrNotAConfigFile, t.lineNo+len(t.pending)+1)
+		}
+		t.feed(line)
+	}
+	// the line that could not be read
+	lineNo := t.lineNo + len(t.pending) + 1
+	t.flush()
+
+	t.format.crlf = crlf > lf
+
+	switch err := s.Err(); {
+	case errors.Is(err, bufio.ErrTooLong):
+		perr := &ParseError{Line: lineNo, Reason: fmt.Sprintf("line is longer than %d bytes, ignoring the rest of the file", limit)}
+		t.issues = append(t.issues, parseIssue{line: perr.Line, msg: perr.Reason})
+
+		return perr
+	case err != nil:
+		t.issues = append(t.issues, parseIssue{msg: fmt.Sprintf("failed to read the rest of the file: %s", err)})
+
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	return nil
+}
+
+// feed passes a physical line to the tokenizer. A quoted value may contain
+// newlines and span several physical lines. These are collected and parsed
+// as one logical line, joined by "\n", once the quote is closed.
+func (t *tokenizer) feed(line string) {
+	if t.lineNo == 0 && len(t.pending) == 0 {
+		line, t.format.bom = strings.CutPrefix(line, utf8BOM)
+	}
+	if len(t.pending) == 0 && !hasOpenQuote(line) {
+		t.token(line)
+
+		return
+	}
+
+	t.pending = append(t.pending, line)
+	logical := strings.Join(t.pending, "\n")
+	if hasOpenQuote(logical) {
+		return
+	}
+
+	extra := len(t.pending) - 1
+	t.pending = nil
+	t.token(logical)
+	// token only counts the first physical line
+	t.lineNo += extra
+}
+
+// flush parses any lines of a quoted value that was never closed. These are
+// parsed one by one, like git would refuse to, and reported as an issue.
+func (t *tokenizer) flush() {
+	if len(t.pending) == 0 {
+		return
+	}
+
+	pending := t.pending
+	t.pending = nil
+	t.issues = append(t.issues, parseIssue{line: t.lineNo + 1, msg: "unterminated quoted value", text: pending[0]})
+	for _, line := range pending {
+		t.token(line)
+	}
+}
+
+// token parses a single logical line and emits it.
+func (t *tokenizer) token(text string) {
+	t.lineNo++
+
+	l := docLine{
+		kind:       lineOther,
+		text:       text,
+		line:       t.lineNo,
+		section:    t.section,
+		subsection: t.subsection,
+	}
+
+	line := strings.TrimSpace(text)
+	switch {
+	case line == "" || line[0] == '#' || line[0] == ';':
+		// empty lines and full-line comments
+	case line[0] == '[':
+		l.kind = lineInvalid
+		if t.parseHeader(text, line) {
+			l.kind = lineSection
+			l.section = t.section
+			l.subsection = t.subsection
+		}
+	default:
+		l.kind = lineInvalid
+		if t.parsePair(&l, line) {
+			l.kind = lineKeyValue
+		}
+	}
+
+	t.emit(l)
+}
+
+// parseHeader updates the current section and subsection from a header line.
+func (t *tokenizer) parseHeader(text, line string) bool {
+	hdr, ok := sectionHeader(line)
+	if !ok {
+		t.issue(text, "invalid section header")
+
+		return false
+	}
+	s, subs, skip := parseSectionHeader(hdr, t.keys)
+	if skip {
+		msg := "invalid section header"
+		if hdr == "[]" {
+			msg = "empty section header"
+		}
+		t.issue(text, msg)
+		t.skipSection = true
+
+		return false
+	}
+	var lerr *LimitError
+	if errors.As(t.keys.checkSubsectionLimit(hdr, subs), &lerr) {
+		t.issue(text, lerr.issueText())
+		t.skipSection = true
+
+		return false
+	}
+	t.skipSection = false
+	t.section = s
+	t.subsection = subs
+	t.prefix = joinSection(t.section, t.subsection) + "."
+
+	return true
+}
+
+// parsePair fills in the key, name, value and comment of a trimmed key-value
+// line. A line without "=" is a bare key. It returns false (and records an
+// issue) if the key is invalid.
+func (t *tokenizer) parsePair(l *docLine, line string) bool {
+	// Reference: https://git-scm.com/docs/git-config#_syntax.
+	k, v, found := strings.Cut(line, "=")
+	// This is a special case for bare booleans.
+	l.bare = !found
+	l.indent = l.text[:len(l.text)-len(strings.TrimLeft(l.text, " \t"))]
+
+	// Remove whitespace from key and value that might be around the '='
+	// "Whitespace characters surrounding name, = and value are discarded."
+	// https://git-scm.com/docs/git-config#_syntax
+	l.name = strings.TrimSpace(k)
+
+	if !t.keys.validName(l.name) {
+		debug.V(3).Log("invalid key %q in line: %q", l.name, line)
+		t.issue(l.text, fmt.Sprintf("invalid key %q", l.name))
+
+		return false
+	}
+
+	if t.skipSection {
+		t.issue(l.text, "key in an ignored section")
+
+		return false
+	}
+
+	l.key = t.keys.Canonical(t.prefix + t.keys.name(l.name))
+	if !t.checkLimits(l) {
+		return false
+	}
+	if found {
+		// keep the whitespace around "=" as written
+		rest := l.text[len(l.indent)+len(l.name):]
+		i := strings.IndexByte(rest, '=') + 1
+		l.sep = rest[:i] + rest[i:len(rest)-len(strings.TrimLeft(rest[i:], " \t"))]
+	}
+	// extract possible comment from the value
+	rValue := strings.TrimSpace(v)
+	l.value, l.comment = splitValue(rValue, t.unescape)
+	if t.unescape {
+		t.checkEscapes(l, rValue)
+	}
+
+	return true
+}
+
+// checkLimits records an issue and returns false if the key of the line
+// exceeds KeyRules.MaxKeyLength or already has KeyRules.MaxValuesPerKey
+// values.
+func (t *tokenizer) checkLimits(l *docLine) bool {
+	if t.values == nil {
+		t.values = make(map[string]int, 42)
+	}
+	t.values[l.key]++
+
+	err := t.keys.checkKeyLimits(l.key)
+	if err == nil {
+		err = t.keys.checkValuesLimit(l.key, t.values[l.key])
+	}
+	var lerr *LimitError
+	if !errors.As(err, &lerr) {
+		return true
+	}
+	t.values[l.key]--
+	t.issue(l.text, lerr.issueText())
+
+	return false
+}
+
+// checkEscapes records an issue for every escape sequence in the raw value
+// of the line that git would reject. The value is still loaded, with the
+// sequence kept as it is, but strict mode fails on it.
+func (t *tokenizer) checkEscapes(l *docLine, rValue string) {
+	offsets := invalidEscapes(rValue)
+	if len(offsets) == 0 {
+		return
+	}
+
+	start := len(l.text) - len(strings.TrimLeft(l.text[len(l.indent)+len(l.name)+len(l.sep):], " \t"))
+	for _, i := range offsets {
+		t.issue(l.text, fmt.Sprintf("invalid escape sequence %q at column %d", rValue[i:i+2], start+i+1))
+	}
+}
+
+// issue records a problem with the current line.
+func (t *tokenizer) issue(text, msg string) {
+	t.issues = append(t.issues, parseIssue{line: t.lineNo, msg: msg, text: text})
+}
+
+// document is a config file split into lines. Untouched lines keep their
+// original text, so the file is reproduced exactly. The edit operations
+// (update, remove and insert) work on the document and leave everything
+// else alone.
+type document struct {
+	lines    []docLine
+	unescape bool     // see tokenizer
+	keys     KeyRules // see tokenizer
+}
+
+// parseDocument splits the config read from in into a document. If
+// unescape is set, values are parsed and written like git does (see
+// splitValue and docLine.format). The keys of the lines are canonicalized
+// with the given rules.
+func parseDocument(in io.Reader, unescape bool, keys KeyRules) *document {
+	d := &document{
+		lines:    make([]docLine, 0, 128),
+		unescape: unescape,
+		keys:     keys,
+	}
+	t := newTokenizer(unescape, func(l docLine) {
+		d.lines = append(d.lines, l)
+	})
+	t.keys = keys
+	// the text is already in memory, its lines were limited when it was read
+	t.maxLine = -1
+	if err := t.tokenize(in); err != nil {
+		debug.V(1).Log("failed to parse document: %s", err)
+	}
+
+	return d
+}
+
+// String returns the text of the document.
+func (d *document) String() string {
+	var sb strings.Builder
+	for _, l := range d.lines {
+		sb.WriteString(l.text)
+		sb.WriteString("\n")
+	}
+	if len(d.lines) == 0 {
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// update replaces the n-th value (counting from 0) of the key. The name and
+// any trailing comment of the line are kept. It returns the updated line or
+// false if the key does not have that many values.
+func (d *document) update(key string, n int, value string, bare bool) (docLine, bool) {
+	key = d.keys.Canonical(key)
+
+	for i, l := range d.lines {
+		if l.kind != lineKeyValue || l.key != key {
+			continue
+		}
+		if n > 0 {
+			n--
+
+			continue
+		}
+
+		return d.updateLine(i, value, bare), true
+	}
+
+	return docLine{}, false
+}
+
+// updateLine replaces the value of the key-value pair at index i, keeping
+// its name and any trailing comment, and returns the updated line.
+func (d *document) updateLine(i int, value string, bare bool) docLine {
+	l := d.lines[i]
+	if l.bare && !bare {
+		// a bare key has no separator yet
+		l.sep = d.style(l.section, l.subsection, l.name).sep
+	}
+	l.value = value
+	l.bare = bare
+	l.text = l.format(d.unescape)
+	d.lines[i] = l
+
+	return l
+}
+
+// remove removes all values of the key, together with their leading
+// comments (see leadingComments). It returns the number of values removed.
+func (d *document) remove(key string) int {
+	key = d.keys.Canonical(key)
+
+	drop := make([]bool, len(d.lines))
+	removed := 0
+	for i, l := range d.lines {
+		if l.kind != lineKeyValue || l.key != key {
+			continue
+		}
+		for j := d.leadingComments(i); j <= i; j++ {
+			drop[j] = true
+		}
+		removed++
+	}
+
+	kept := d.lines[:0]
+	for i, l := range d.lines {
+		if !drop[i] {
+			kept = append(kept, l)
+		}
+	}
+	d.lines = kept
+
+	return removed
+}
+
+// leadingComments returns the index of the first line of the comments that
+// belong to line i, i.e. the full-line comments directly above it. It
+// returns i if there are none. A blank line ends the comments. Comments
+// right below a section header belong to the section and not to the first
+// key, so they are never returned.
+func (d *document) leadingComments(i int) int {
+	start := i
+	for start > 0 && d.lines[start-1].isComment() {
+		start--
+	}
+	if start == 0 || d.lines[start-1].kind == lineSection {
+		return i
+	}
+
+	return start
+}
+
+// sectionComments returns the index of the last line of the comments right
+// below the section header at i, or i if there are none.
+func (d *document) sectionComments(i int) int {
+	for i+1 < len(d.lines) && d.lines[i+1].isComment() {
+		i++
+	}
+
+	return i
+}
+
+// insert adds a value for the key right below the first header of its
+// section and any comments that belong to it (see sectionComments), so the
+// comments stay with the section. If there is no such section it is added
+// at the end.
+func (d *document) insert(key, value string, bare bool) {
+	spelled, spelledSubsection, name := splitKey(key)
+	section, subsection := d.keys.section(spelled), d.keys.subsection(spelledSubsection)
+	l := docLine{
+		kind:       lineKeyValue,
+		section:    section,
+		subsection: subsection,
+		key:        d.keys.Canonical(key),
+		name:       name,
+		value:      value,
+		bare:       bare,
+		lineStyle:  d.style(section, subsection, name),
+	}
+	l.text = l.format(d.unescape)
+
+	for i, h := range d.lines {
+		if h.kind != lineSection || h.section != section || h.subsection != subsection {
+			continue
+		}
+		i = d.sectionComments(i)
+		d.lines = append(d.lines[:i+1], append([]docLine{l}, d.lines[i+1:]...)...)
+
+		return
+	}
+
+	// new headers keep the spelling of the key, like git does
+	hdr := fmt.Sprintf("[%s]", spelled)
+	if spelledSubsection != "" {
+		hdr = fmt.Sprintf("[%s %s]", spelled, QuoteSubsection(spelledSubsection))
+	}
+	d.lines = append(d.lines, docLine{kind: lineSection, text: hdr, section: section, subsection: subsection}, l)
+}
+
+// style returns the layout for a new line of name in the section. It
+// follows the key-value lines of the section, or of the whole file if the
+// section has none. If the section aligns its "=" the new line is aligned
+// as well, as long as the name fits.
+func (d *document) style(section, subsection, name string) lineStyle {
+	var fallback []docLine
+	samples := make([]docLine, 0, 8)
+	for _, l := range d.lines {
+		if l.kind != lineKeyValue || l.bare {
+			continue
+		}
+		if fallback == nil {
+			fallback = []docLine{l}
+		}
+		if l.section == section && l.subsection == subsection {
+			samples = append(samples, l)
+		}
+	}
+	if len(samples) == 0 {
+		samples = fallback
+	}
+	if len(samples) == 0 {
+		return defaultStyle
+	}
+
+	first := samples[0]
+	i := strings.Index(first.sep, "=")
+	before, after := first.sep[:i], first.sep[i+1:]
+	if before != "" {
+		// drop any padding, unless the section is aligned (see below)
+		before = before[:1]
+	}
+	s := lineStyle{indent: first.indent, sep: before + "=" + after}
+
+	if col, aligned := alignment(samples); aligned && len(s.indent)+len(name) < col {
+		s.sep = strings.Repeat(" ", col-len(s.indent)-len(name)) + "=" + after
+	}
+
+	return s
+}
+
+// alignment returns the column of "=" if all lines have it in the same
+// column, padded with spaces, and the names differ in length.
+func alignment(lines []docLine) (int, bool) {
+	if len(lines) < 2 {
+		return 0, false
+	}
+
+	col := -1
+	padded := false
+	for _, l := range lines {
+		i := strings.Index(l.sep, "=")
+		if strings.Trim(l.sep[:i], " ") != "" {
+			return 0, false
+		}
+		c := len(l.indent) + len(l.name) + i
+		if col >= 0 && c != col {
+			return 0, false
+		}
+		col = c
+		padded = padded || i > 1
+	}
+
+	return col, padded
+}
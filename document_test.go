@@ -0,0 +1,216 @@
+package gitconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const documentTestConfig = `# leading comment
+[core]
+	Editor = vim # trailing comment
+	multi = first
+	multi = second
+
+	bare
+	1nvalid = x
+[remote "origin"]
+	url = "multi
+line"
+[broken
+`
+
+func TestParseDocument(t *testing.T) {
+	t.Parallel()
+
+	d := parseDocument(strings.NewReader(documentTestConfig), true, KeyRules{})
+
+	// the document reproduces the input exactly
+	assert.Equal(t, documentTestConfig, d.String())
+
+	kinds := make([]lineKind, 0, len(d.lines))
+	for _, l := range d.lines {
+		kinds = append(kinds, l.kind)
+	}
+	assert.Equal(t, []lineKind{
+		lineOther, lineSection, lineKeyValue, lineKeyValue, lineKeyValue,
+		lineOther, lineKeyValue, lineInvalid, lineSection, lineKeyValue, lineInvalid,
+	}, kinds)
+
+	editor := d.lines[2]
+	assert.Equal(t, "core.editor", editor.key)
+	assert.Equal(t, "Editor", editor.name)
+	assert.Equal(t, "vim", editor.value)
+	assert.Equal(t, " # trailing comment", editor.comment)
+	assert.Equal(t, 3, editor.line)
+
+	assert.True(t, d.lines[6].bare)
+
+	url := d.lines[9]
+	assert.Equal(t, "remote.origin.url", url.key)
+	assert.Equal(t, "multi\nline", url.value)
+	assert.Equal(t, 10, url.line)
+	assert.Equal(t, 12, d.lines[10].line)
+
+	assert.Equal(t, "\n", (&document{}).String())
+}
+
+func TestDocumentUpdate(t *testing.T) {
+	t.Parallel()
+
+	d := parseDocument(strings.NewReader(documentTestConfig), true, KeyRules{})
+
+	// name and comment are kept
+	l, ok := d.update("core.editor", 0, "nano", false)
+	require.True(t, ok)
+	assert.Equal(t, "\tEditor = nano # trailing comment", d.lines[2].text)
+	assert.Equal(t, d.lines[2], l)
+	assert.Equal(t, "nano # trailing comment", l.rawValue())
+
+	_, ok = d.update("core.multi", 1, "last", false)
+	require.True(t, ok)
+	assert.Equal(t, "\tmulti = first", d.lines[3].text)
+	assert.Equal(t, "\tmulti = last", d.lines[4].text)
+
+	_, ok = d.update("core.multi", 0, "", true)
+	require.True(t, ok)
+	assert.Equal(t, "\tmulti", d.lines[3].text)
+
+	_, ok = d.update("core.multi", 2, "x", false)
+	assert.False(t, ok)
+	_, ok = d.update("core.missing", 0, "x", false)
+	assert.False(t, ok)
+
+	// everything else is untouched
+	want := strings.Replace(documentTestConfig, "vim #", "nano #", 1)
+	want = strings.Replace(want, "\tmulti = first\n\tmulti = second", "\tmulti\n\tmulti = last", 1)
+	assert.Equal(t, want, d.String())
+}
+
+func TestDocumentRemove(t *testing.T) {
+	t.Parallel()
+
+	d := parseDocument(strings.NewReader(documentTestConfig), true, KeyRules{})
+
+	assert.Equal(t, 2, d.remove("Core.Multi"))
+	assert.Equal(t, 1, d.remove("remote.origin.url"))
+	assert.Equal(t, 0, d.remove("core.missing"))
+
+	want := strings.Replace(documentTestConfig, "\tmulti = first\n\tmulti = second\n", "", 1)
+	want = strings.Replace(want, "\turl = \"multi\nline\"\n", "", 1)
+	assert.Equal(t, want, d.String())
+}
+
+func TestDocumentComments(t *testing.T) {
+	t.Parallel()
+
+	in := `[core]
+	# about the section
+	editor = vim
+	# about the pager
+	; in two lines
+	pager = less # inline
+
+	# detached by a blank line
+
+	multi = a
+	# about b
+	multi = b
+[other]
+	key = value
+`
+	d := parseDocument(strings.NewReader(in), true, KeyRules{})
+	assert.Equal(t, 2, d.leadingComments(2))
+	assert.Equal(t, 3, d.leadingComments(5))
+	assert.Equal(t, 9, d.leadingComments(9))
+	assert.Equal(t, 1, d.sectionComments(0))
+	assert.Equal(t, 12, d.sectionComments(12))
+
+	// comments move with the key, the section comments stay
+	assert.Equal(t, 1, d.remove("core.pager"))
+	assert.Equal(t, 2, d.remove("core.multi"))
+	d.insert("core.autocrlf", "false", false)
+	d.insert("other.new", "1", false)
+	assert.Equal(t, `[core]
+	# about the section
+	autocrlf = false
+	editor = vim
+
+	# detached by a blank line
+
+[other]
+	new = 1
+	key = value
+`, d.String())
+}
+
+func TestDocumentInsert(t *testing.T) {
+	t.Parallel()
+
+	d := parseDocument(strings.NewReader("[core]\n\ta = 1\n[remote \"origin\"]\n\turl = x\n"), true, KeyRules{})
+
+	d.insert("core.b", "2", false)
+	d.insert("remote.origin.fetch", "+refs/*", false)
+	d.insert("remote.upstream.url", "y", false)
+	d.insert("new.flag", "", true)
+
+	assert.Equal(t, "[core]\n\tb = 2\n\ta = 1\n[remote \"origin\"]\n\tfetch = +refs/*\n\turl = x\n[remote \"upstream\"]\n\turl = y\n[new]\n\tflag\n", d.String())
+}
+
+func TestDocumentStyle(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "spaces without blanks around =",
+			in:   "[core]\n    editor=vim\n",
+			want: "[core]\n    pager=less\n    editor=nano\n[user]\n    name=John\n",
+		},
+		{
+			name: "aligned section",
+			in:   "[core]\n\teditor     = vim\n\tautocrlf   = input\n",
+			want: "[core]\n\tpager      = less\n\teditor     = nano\n\tautocrlf   = input\n[user]\n\tname = John\n",
+		},
+		{
+			name: "empty file",
+			in:   "",
+			want: "[core]\n\tpager = less\n[user]\n\tname = John\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := parseDocument(strings.NewReader(tc.in), true, KeyRules{})
+			d.update("core.editor", 0, "nano", false)
+			d.insert("core.pager", "less", false)
+			d.insert("user.name", "John", false)
+
+			assert.Equal(t, tc.want, d.String())
+		})
+	}
+}
+
+func TestDocumentUpdateKeepsLayout(t *testing.T) {
+	t.Parallel()
+
+	d := parseDocument(strings.NewReader("[core]\n  editor   =   vim # comment\n  flag\n  other=x\n"), true, KeyRules{})
+
+	l, ok := d.update("core.editor", 0, "nano", false)
+	require.True(t, ok)
+	assert.Equal(t, "  editor   =   nano # comment", l.text)
+
+	// a bare key gets the separator of the section, without padding
+	l, ok = d.update("core.flag", 0, "false", false)
+	require.True(t, ok)
+	assert.Equal(t, "  flag =   false", l.text)
+
+	l, ok = d.update("core.other", 0, "", true)
+	require.True(t, ok)
+	assert.Equal(t, "  other", l.text)
+}
@@ -0,0 +1,259 @@
+package gitconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Origin identifies the line of a config file that defines a value. It is
+// used by the low-level editing operations (see ReplaceValueAt and
+// DeleteLine) to address a single line.
+//
+// Fields:
+// - Path: The file that defines the value, empty if parsed from a reader
+// - Line: The line number, starting at 1
+type Origin struct {
+	Path string
+	Line int
+}
+
+// Origins returns the origins of all values of the key, in the same order
+// as GetAll. Values that are not backed by a line (e.g. presets or values
+// added by Set since loading) have a zero Line. The low-level editing
+// operations keep the origins up to date.
+//
+// Example:
+//
+//	for _, o := range cfg.Origins("remote.origin.fetch") {
+//		fmt.Printf("%s:%d\n", o.Path, o.Line)
+//	}
+func (c *Config) Origins(key string) []Origin {
+	if c == nil {
+		return nil
+	}
+
+	key = c.canonicalKey(key)
+	vs := c.vars[key]
+	if len(vs) == 0 {
+		return nil
+	}
+
+	out := make([]Origin, 0, len(vs))
+	for i := range vs {
+		vo := c.origin(key, i)
+		out = append(out, Origin{Path: vo.path, Line: vo.line})
+	}
+
+	return out
+}
+
+// InsertLineAfterSection inserts a raw line right below the first header of
+// the section. The line must be a single valid key-value pair, a bare key, a
+// comment or a blank line. It is written as is, e.g. to add a comment.
+//
+// Example:
+//
+//	err := cfg.InsertLineAfterSection("core", "", "\t# managed by migrate-config")
+func (c *Config) InsertLineAfterSection(section, subsection, line string) error {
+	if err := c.checkEditable(); err != nil {
+		return err
+	}
+
+	l, err := parseSingleLine(section, subsection, line, c.unescapeValues(), c.keys)
+	if err != nil {
+		return err
+	}
+
+	return c.editLines(func(d *document) error {
+		i, err := sectionIndex(d, section, subsection)
+		if err != nil {
+			return err
+		}
+		d.lines = append(d.lines[:i+1], append([]docLine{l}, d.lines[i+1:]...)...)
+
+		return nil
+	})
+}
+
+// ReplaceValueAt replaces the value of the key-value line at the origin. The
+// name and any trailing comment of the line are kept. Unlike Set it can
+// address any value of a multi-valued key.
+//
+// Example:
+//
+//	origins := cfg.Origins("remote.origin.fetch")
+//	err := cfg.ReplaceValueAt(origins[1], "+refs/heads/main:refs/remotes/origin/main")
+func (c *Config) ReplaceValueAt(o Origin, value string) error {
+	if err := c.checkEditable(); err != nil {
+		return err
+	}
+	if err := validateValue(value, c.unescapeValues()); err != nil {
+		return err
+	}
+
+	return c.editLines(func(d *document) error {
+		i, err := c.lineAt(d, o)
+		if err != nil {
+			return err
+		}
+		if d.lines[i].kind != lineKeyValue {
+			return fmt.Errorf("%w: line %d is not a key-value pair", ErrInvalidOrigin, o.Line)
+		}
+
+		l := d.lines[i]
+		l.value = value
+		l.bare = false
+		l.text = l.format(d.unescape)
+		d.lines[i] = l
+
+		return nil
+	})
+}
+
+// DeleteLine removes the line at the origin. Section headers can not be
+// deleted, since that would move the following keys into another section.
+//
+// Example:
+//
+//	if origins := cfg.Origins("core.legacyoption"); len(origins) > 0 {
+//		err := cfg.DeleteLine(origins[0])
+//	}
+func (c *Config) DeleteLine(o Origin) error {
+	if err := c.checkEditable(); err != nil {
+		return err
+	}
+
+	return c.editLines(func(d *document) error {
+		i, err := c.lineAt(d, o)
+		if err != nil {
+			return err
+		}
+		if d.lines[i].kind == lineSection {
+			return fmt.Errorf("%w: line %d is a section header", ErrInvalidOrigin, o.Line)
+		}
+		d.lines = append(d.lines[:i], d.lines[i+1:]...)
+
+		return nil
+	})
+}
+
+// checkEditable rejects low-level edits of configs that can not be changed.
+func (c *Config) checkEditable() error {
+	if c == nil || c.readonly {
+		return ErrReadonly
+	}
+
+	return nil
+}
+
+// lineAt returns the index of the document line the origin refers to. The
+// origin must belong to this config, not to one of its includes.
+func (c *Config) lineAt(d *document, o Origin) (int, error) {
+	if o.Path != c.path {
+		return 0, fmt.Errorf("%w: %s is not %s", ErrInvalidOrigin, o.Path, c.path)
+	}
+	for i, l := range d.lines {
+		if l.line == o.Line {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: no line %d", ErrInvalidOrigin, o.Line)
+}
+
+// sectionIndex returns the index of the first header of the section.
+func sectionIndex(d *document, section, subsection string) (int, error) {
+	for i, h := range d.lines {
+		if h.kind == lineSection && h.section == d.keys.section(section) && h.subsection == d.keys.subsection(subsection) {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: no section %q", ErrInvalidKey, joinSection(section, subsection))
+}
+
+// parseSingleLine parses line as it would appear in the section. It
+// rejects anything that is not exactly one key-value pair, bare key,
+// comment or blank line.
+func parseSingleLine(section, subsection, line string, unescape bool, keys KeyRules) (docLine, error) {
+	if strings.ContainsAny(line, "\r\n\x00") {
+		return docLine{}, fmt.Errorf("%w: line %q contains a line break or NUL", ErrInvalidValue, line)
+	}
+
+	var l docLine
+	t := newTokenizer(unescape, func(dl docLine) { l = dl })
+	t.keys = keys
+	t.section = keys.section(section)
+	t.subsection = keys.subsection(subsection)
+	t.prefix = joinSection(t.section, subsection) + "."
+	t.token(line)
+
+	switch {
+	case len(t.issues) > 0:
+		return docLine{}, fmt.Errorf("%w: %s", ErrInvalidValue, t.issues[0].msg)
+	case l.kind == lineSection:
+		return docLine{}, fmt.Errorf("%w: line %q is a section header", ErrInvalidValue, line)
+	case hasOpenQuote(line):
+		return docLine{}, fmt.Errorf("%w: line %q has an unterminated quote", ErrInvalidValue, line)
+	}
+
+	return l, nil
+}
+
+// joinSection returns the section and subsection joined by ".".
+func joinSection(section, subsection string) string {
+	if subsection == "" {
+		return section
+	}
+
+	return section + "." + subsection
+}
+
+// editLines applies fn to the document of the raw config. If fn succeeds
+// the values are reloaded from the document, so origins stay accurate, and
+// the result is persisted through flushRaw like any other change.
+func (c *Config) editLines(fn func(d *document) error) error {
+	d := parseDocument(strings.NewReader(c.raw.String()), c.unescapeValues(), c.keys)
+	if err := fn(d); err != nil {
+		return err
+	}
+	c.reloadVars(d)
+
+	c.raw = strings.Builder{}
+	c.raw.WriteString(d.String())
+
+	return c.flushRaw()
+}
+
+// reloadVars rebuilds the values defined by this config from the document.
+// Values from included files are kept and still follow the own values.
+func (c *Config) reloadVars(d *document) {
+	vars := make(map[string][]string, len(c.vars))
+	origins := make(map[string][]valueOrigin, len(c.vars))
+	lineNo := 1
+	for _, l := range d.lines {
+		// the line numbers of the document are stale after an edit
+		line := lineNo
+		lineNo += strings.Count(l.text, "\n") + 1
+		if l.kind != lineKeyValue {
+			continue
+		}
+		vars[l.key] = append(vars[l.key], l.value)
+		origins[l.key] = append(origins[l.key], valueOrigin{path: c.path, line: line, bare: l.bare, raw: rawIfDifferent(l.rawValue(), l.value), comment: l.commentText()})
+	}
+
+	for k, vs := range c.vars {
+		for i, v := range vs {
+			vo := c.origin(k, i)
+			if vo.path == "" || vo.path == c.path {
+				continue
+			}
+			vars[k] = append(vars[k], v)
+			origins[k] = append(origins[k], vo)
+		}
+	}
+
+	c.vars = vars
+	c.origins = origins
+	c.resetCoercions()
+}
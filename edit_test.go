@@ -0,0 +1,129 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const editTestConfig = `[core]
+	editor = vim # keep me
+[remote "origin"]
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	fetch = +refs/tags/*:refs/tags/*
+`
+
+func TestOrigins(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader(editTestConfig))
+
+	assert.Equal(t, []Origin{{Line: 4}, {Line: 5}}, c.Origins("remote.origin.fetch"))
+	assert.Equal(t, []Origin{{Line: 2}}, c.Origins("Core.Editor"))
+	assert.Nil(t, c.Origins("core.missing"))
+}
+
+func TestReplaceValueAt(t *testing.T) {
+	t.Parallel()
+
+	fn := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(fn, []byte(editTestConfig), 0o600))
+
+	c, err := LoadConfig(fn)
+	require.NoError(t, err)
+
+	origins := c.Origins("remote.origin.fetch")
+	require.Len(t, origins, 2)
+	require.NoError(t, c.ReplaceValueAt(origins[1], "+refs/tags/v*:refs/tags/v*"))
+
+	vs, _ := c.GetAll("remote.origin.fetch")
+	assert.Equal(t, []string{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/v*:refs/tags/v*"}, vs)
+
+	// the comment is kept and the change is written
+	require.NoError(t, c.ReplaceValueAt(c.Origins("core.editor")[0], "nano"))
+	buf, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\teditor = nano # keep me\n[remote \"origin\"]\n"+
+		"\tfetch = +refs/heads/*:refs/remotes/origin/*\n\tfetch = +refs/tags/v*:refs/tags/v*\n", string(buf))
+
+	// guards
+	require.ErrorIs(t, c.ReplaceValueAt(Origin{Path: fn, Line: 1}, "x"), ErrInvalidOrigin)
+	require.ErrorIs(t, c.ReplaceValueAt(Origin{Path: fn, Line: 42}, "x"), ErrInvalidOrigin)
+	require.ErrorIs(t, c.ReplaceValueAt(Origin{Path: "other", Line: 2}, "x"), ErrInvalidOrigin)
+	require.ErrorIs(t, c.ReplaceValueAt(Origin{Path: fn, Line: 2}, "a\rb"), ErrInvalidValue)
+}
+
+func TestDeleteLine(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader(editTestConfig))
+	c.noWrites = true
+
+	require.NoError(t, c.DeleteLine(c.Origins("remote.origin.fetch")[0]))
+	vs, _ := c.GetAll("remote.origin.fetch")
+	assert.Equal(t, []string{"+refs/tags/*:refs/tags/*"}, vs)
+	// the origins follow the edit
+	assert.Equal(t, []Origin{{Line: 4}}, c.Origins("remote.origin.fetch"))
+
+	require.ErrorIs(t, c.DeleteLine(Origin{Line: 3}), ErrInvalidOrigin)
+	assert.NotContains(t, c.raw.String(), "refs/heads")
+}
+
+func TestInsertLineAfterSection(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader(editTestConfig))
+	c.noWrites = true
+
+	require.NoError(t, c.InsertLineAfterSection("Core", "", "\t# managed by a tool"))
+	require.NoError(t, c.InsertLineAfterSection("remote", "origin", "  pushurl=git@example.com:repo.git"))
+
+	// the lines are written as is
+	assert.Equal(t, "[core]\n\t# managed by a tool\n\teditor = vim # keep me\n[remote \"origin\"]\n  pushurl=git@example.com:repo.git\n"+
+		"\tfetch = +refs/heads/*:refs/remotes/origin/*\n\tfetch = +refs/tags/*:refs/tags/*\n", c.raw.String())
+
+	v, ok := c.Get("remote.origin.pushurl")
+	assert.True(t, ok)
+	assert.Equal(t, "git@example.com:repo.git", v)
+	assert.Equal(t, []Origin{{Line: 3}}, c.Origins("core.editor"))
+
+	for _, line := range []string{"[other]", "1nvalid = x", "a = \"open", "a = 1\nb = 2"} {
+		require.ErrorIs(t, c.InsertLineAfterSection("core", "", line), ErrInvalidValue, line)
+	}
+	require.ErrorIs(t, c.InsertLineAfterSection("missing", "", "a = 1"), ErrInvalidKey)
+}
+
+func TestLowLevelEditsReadonly(t *testing.T) {
+	t.Parallel()
+
+	c := NewFromMap(map[string]string{"core.editor": "vim"})
+
+	require.ErrorIs(t, c.InsertLineAfterSection("core", "", "a = 1"), ErrReadonly)
+	require.ErrorIs(t, c.ReplaceValueAt(Origin{Line: 1}, "x"), ErrReadonly)
+	require.ErrorIs(t, c.DeleteLine(Origin{Line: 1}), ErrReadonly)
+}
+
+func TestLowLevelEditsKeepIncludes(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	fn := filepath.Join(td, "config")
+	inc := filepath.Join(td, "included")
+	require.NoError(t, os.WriteFile(fn, []byte("[include]\n\tpath = included\n[core]\n\teditor = vim\n"), 0o600))
+	require.NoError(t, os.WriteFile(inc, []byte("[core]\n\tpager = less\n"), 0o600))
+
+	c, err := LoadConfig(fn)
+	require.NoError(t, err)
+	require.Equal(t, "less", c.vars["core.pager"][0])
+
+	require.NoError(t, c.ReplaceValueAt(c.Origins("core.editor")[0], "nano"))
+	v, _ := c.Get("core.pager")
+	assert.Equal(t, "less", v)
+
+	// lines of included files can not be edited through the including config
+	require.ErrorIs(t, c.DeleteLine(c.Origins("core.pager")[0]), ErrInvalidOrigin)
+}
@@ -0,0 +1,97 @@
+package gitconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// encPrefix marks an encrypted value.
+const encPrefix = "!enc:"
+
+// Cipher encrypts and decrypts the values of sensitive keys. The key is
+// passed along so implementations can use it e.g. as associated data.
+// The ciphertext must be a single line of text, e.g. base64 encoded.
+type Cipher interface {
+	Encrypt(key, plaintext string) (string, error)
+	Decrypt(key, ciphertext string) (string, error)
+}
+
+// SetCipher registers a Cipher for the given keys. Values written to these
+// keys with SetLocal, SetGlobal, SetEnv or Converge (and the typed setters) are
+// encrypted and stored as "!enc:<ciphertext>". Reading them with Get, GetAll,
+// GetFrom or one of the typed getters returns the decrypted value. Values
+// without the prefix are returned as they are, so existing plaintext values
+// keep working until they are written again. All other keys are not touched.
+//
+// Calling SetCipher again replaces the cipher and the registered keys.
+// A nil cipher disables encryption.
+//
+// Example:
+//
+//	cfg.SetCipher(myCipher, "github.token", "remote.origin.password")
+func (cs *Configs) SetCipher(c Cipher, keys ...string) {
+	cs.cipher = c
+	cs.encryptedKeys = make(map[string]bool, len(keys))
+	for _, k := range keys {
+		cs.encryptedKeys[cs.canonicalKey(k)] = true
+	}
+}
+
+// isEncryptedKey reports whether values of key are encrypted.
+func (cs *Configs) isEncryptedKey(key string) bool {
+	return cs.cipher != nil && cs.encryptedKeys[cs.canonicalKey(key)]
+}
+
+// encrypt encrypts the value if key is a registered sensitive key.
+func (cs *Configs) encrypt(key, value string) (string, error) {
+	if !cs.isEncryptedKey(key) {
+		return value, nil
+	}
+
+	ct, err := cs.cipher.Encrypt(key, value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt %s: %w", key, err)
+	}
+	if strings.ContainsAny(ct, "\r\n") {
+		return "", fmt.Errorf("%w: ciphertext for %s contains a newline", ErrInvalidValue, key)
+	}
+
+	return encPrefix + ct, nil
+}
+
+// decrypt decrypts the value if key is a registered sensitive key and the
+// value is encrypted.
+func (cs *Configs) decrypt(key, value string) (string, error) {
+	ct, found := strings.CutPrefix(value, encPrefix)
+	if !found || !cs.isEncryptedKey(key) {
+		return value, nil
+	}
+
+	pt, err := cs.cipher.Decrypt(key, ct)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", key, err)
+	}
+
+	return pt, nil
+}
+
+// decryptAll decrypts all values of a multivar. It returns false if any
+// of them can not be decrypted.
+func (cs *Configs) decryptAll(key string, vs []string) ([]string, bool) {
+	if !cs.isEncryptedKey(key) {
+		return vs, true
+	}
+
+	out := make([]string, 0, len(vs))
+	for _, v := range vs {
+		pt, err := cs.decrypt(key, v)
+		if err != nil {
+			debug.V(1).Log("[%s] %s", cs.Name, err)
+
+			return nil, false
+		}
+		out = append(out, pt)
+	}
+
+	return out, true
+}
@@ -0,0 +1,129 @@
+package gitconfig
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestDecrypt = errors.New("bad ciphertext")
+
+// testCipher "encrypts" by base64 encoding the key and the value.
+type testCipher struct{}
+
+func (testCipher) Encrypt(key, plaintext string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(key + ":" + plaintext)), nil
+}
+
+func (testCipher) Decrypt(key, ciphertext string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	pt, found := strings.CutPrefix(string(b), key+":")
+	if !found {
+		return "", errTestDecrypt
+	}
+
+	return pt, nil
+}
+
+func TestEncryptedKeys(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	cs := New()
+	cs.workdir = td
+	cs.local = ParseConfig(strings.NewReader("[github]\n\tlegacy = plain\n\tbroken = !enc:nope\n"))
+	cs.local.path = filepath.Join(td, cs.LocalConfig)
+	cs.SetCipher(testCipher{}, "github.token", "GitHub.Legacy", "github.broken")
+
+	require.NoError(t, cs.SetLocal("github.token", "s3cret"))
+	require.NoError(t, cs.SetLocal("github.user", "alice"))
+
+	buf, err := os.ReadFile(cs.local.path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(buf), "s3cret")
+	assert.Contains(t, string(buf), "\ttoken = !enc:")
+	assert.Contains(t, string(buf), "\tuser = alice\n")
+
+	assert.Equal(t, "s3cret", cs.Get("github.token"))
+	assert.Equal(t, []string{"s3cret"}, cs.GetAll("github.token"))
+	v, found := cs.GetFrom("github.token", "local")
+	assert.True(t, found)
+	assert.Equal(t, "s3cret", v)
+	assert.Equal(t, "alice", cs.Get("github.user"))
+
+	// plaintext values of registered keys are returned as they are
+	assert.Equal(t, "plain", cs.Get("github.legacy"))
+
+	// values that can not be decrypted are treated as unset
+	_, found = cs.lookup("github.broken")
+	assert.False(t, found)
+	assert.Nil(t, cs.GetAll("github.broken"))
+
+	// without a cipher the stored value is returned
+	cs.SetCipher(nil)
+	assert.True(t, strings.HasPrefix(cs.Get("github.token"), encPrefix))
+}
+
+func TestEncryptNewline(t *testing.T) {
+	t.Parallel()
+
+	cs := New()
+	cs.SetCipher(newlineCipher{}, "a.b")
+
+	require.ErrorIs(t, cs.SetEnv("a.b", "c"), ErrInvalidValue)
+}
+
+type newlineCipher struct{ testCipher }
+
+func (newlineCipher) Encrypt(_, _ string) (string, error) {
+	return "a\nb", nil
+}
+
+func TestConvergeEncryptedKeys(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	cs := New()
+	cs.workdir = td
+	cs.local = ParseConfig(strings.NewReader("[github]\n\tbroken = !enc:nope\n\tuser = alice\n"))
+	cs.local.path = filepath.Join(td, cs.LocalConfig)
+	cs.SetCipher(testCipher{}, "github.token", "github.broken")
+
+	desired := map[string]string{
+		"github.token":  "s3cret",
+		"github.broken": "fixed",
+		"github.user":   "alice",
+	}
+	report, err := cs.Converge(desired, "local", ConvergeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Kind: ChangeAdded, Key: "github.token", After: "s3cret"},
+		{Kind: ChangeUpdated, Key: "github.broken", Before: "!enc:nope", After: "fixed"},
+	}, report.Changes())
+
+	buf, err := os.ReadFile(cs.local.path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(buf), "s3cret")
+	assert.NotContains(t, string(buf), "fixed")
+	assert.Contains(t, string(buf), "\ttoken = !enc:")
+	assert.Equal(t, "s3cret", cs.Get("github.token"))
+	assert.Equal(t, "fixed", cs.Get("github.broken"))
+
+	// the decrypted values are compared, so converging again is a no-op
+	report, err = cs.Converge(desired, "local", ConvergeOptions{})
+	require.NoError(t, err)
+	assert.True(t, report.IsEmpty())
+
+	report, err = cs.Converge(map[string]string{}, "local", ConvergeOptions{Managed: []string{"github.token"}, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Kind: ChangeRemoved, Key: "github.token", Before: "s3cret"}}, report.Changes())
+}
@@ -0,0 +1,113 @@
+package gitconfig
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// envVar is a single environment variable.
+type envVar struct {
+	name  string
+	value string
+}
+
+// envVars returns the env scope as environment variables, starting with
+// <prefix>_COUNT, followed by the key and value of every value in order.
+func (cs *Configs) envVars(prefix string) []envVar {
+	if cs.env == nil || len(cs.env.vars) == 0 {
+		return []envVar{{prefix + "_COUNT", "0"}}
+	}
+
+	out := make([]envVar, 1, 2*len(cs.env.vars)+1)
+	n := 0
+	for _, k := range slices.Sorted(maps.Keys(cs.env.vars)) {
+		for _, v := range cs.env.vars[k] {
+			out = append(out,
+				envVar{fmt.Sprintf("%s_KEY_%d", prefix, n), k},
+				envVar{fmt.Sprintf("%s_VALUE_%d", prefix, n), v},
+			)
+			n++
+		}
+	}
+	out[0] = envVar{prefix + "_COUNT", strconv.Itoa(n)}
+
+	return out
+}
+
+// EnvExport returns the env scope as environment variables in the format
+// read by LoadConfigFromEnv, i.e. <prefix>_COUNT and one <prefix>_KEY_<n>
+// and <prefix>_VALUE_<n> pair per value. Keys are sorted, the values of a
+// multi-valued key keep their order. If prefix is empty, EnvPrefix is used.
+// An empty env scope results in an empty map.
+//
+// This includes the values set with SetEnv, so wrappers can pass them on
+// to child processes (see also CommandEnv):
+//
+//	cfg.SetEnv("core.pager", "less")
+//	for k, v := range cfg.EnvExport("GIT_CONFIG") {
+//		cmd.Env = append(cmd.Env, k+"="+v)
+//	}
+func (cs *Configs) EnvExport(prefix string) map[string]string {
+	if prefix == "" {
+		prefix = cs.EnvPrefix
+	}
+	if cs.env == nil || len(cs.env.vars) == 0 {
+		return map[string]string{}
+	}
+
+	out := make(map[string]string, 2*len(cs.env.vars)+1)
+	for _, ev := range cs.envVars(prefix) {
+		out[ev.name] = ev.value
+	}
+
+	return out
+}
+
+// EnvExportShell is like EnvExport but returns the variables as POSIX shell
+// export statements, one per line, ready to be eval'ed:
+//
+//	export GIT_CONFIG_COUNT='1'
+//	export GIT_CONFIG_KEY_0='core.pager'
+//	export GIT_CONFIG_VALUE_0='less'
+func (cs *Configs) EnvExportShell(prefix string) string {
+	if prefix == "" {
+		prefix = cs.EnvPrefix
+	}
+	if cs.env == nil || len(cs.env.vars) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, ev := range cs.envVars(prefix) {
+		// single-quoted, so the shell does not expand anything in the value
+		fmt.Fprintf(&sb, "export %s='%s'\n", ev.name, strings.ReplaceAll(ev.value, "'", `'\''`))
+	}
+
+	return sb.String()
+}
+
+// CommandEnv returns the environment entries ("NAME=value") a child process
+// needs to see the same env scope as this process, including the values set
+// with SetEnv. They use EnvPrefix, so for the default prefix git itself picks
+// them up. <prefix>_NOSYSTEM is passed on if it is set. The entries are
+// meant to be appended to the environment of the child, they replace any
+// inherited values:
+//
+//	cmd := exec.Command("git", "log")
+//	cmd.Env = append(os.Environ(), cfg.CommandEnv()...)
+func (cs *Configs) CommandEnv() []string {
+	vars := cs.envVars(cs.EnvPrefix)
+	out := make([]string, 0, len(vars)+1)
+	for _, ev := range vars {
+		out = append(out, ev.name+"="+ev.value)
+	}
+	if v, ok := os.LookupEnv(cs.EnvPrefix + "_NOSYSTEM"); ok {
+		out = append(out, cs.EnvPrefix+"_NOSYSTEM="+v)
+	}
+
+	return out
+}
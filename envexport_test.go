@@ -0,0 +1,93 @@
+package gitconfig
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvExport(t *testing.T) {
+	t.Setenv("GPTEST_EXPORT_CONFIG_COUNT", "2")
+	t.Setenv("GPTEST_EXPORT_CONFIG_KEY_0", "remote.origin.fetch")
+	t.Setenv("GPTEST_EXPORT_CONFIG_VALUE_0", "a")
+	t.Setenv("GPTEST_EXPORT_CONFIG_KEY_1", "remote.origin.fetch")
+	t.Setenv("GPTEST_EXPORT_CONFIG_VALUE_1", "b")
+
+	c := New()
+	c.SystemConfig = ""
+	c.EnvPrefix = "GPTEST_EXPORT_CONFIG"
+	assert.Empty(t, c.EnvExport(""))
+	assert.Empty(t, c.EnvExportShell(""))
+
+	c.LoadAll("")
+	require.NoError(t, c.SetEnv("core.pager", "it's less"))
+
+	assert.Equal(t, map[string]string{
+		"GPTEST_EXPORT_CONFIG_COUNT":   "3",
+		"GPTEST_EXPORT_CONFIG_KEY_0":   "core.pager",
+		"GPTEST_EXPORT_CONFIG_VALUE_0": "it's less",
+		"GPTEST_EXPORT_CONFIG_KEY_1":   "remote.origin.fetch",
+		"GPTEST_EXPORT_CONFIG_VALUE_1": "a",
+		"GPTEST_EXPORT_CONFIG_KEY_2":   "remote.origin.fetch",
+		"GPTEST_EXPORT_CONFIG_VALUE_2": "b",
+	}, c.EnvExport(""))
+	assert.Len(t, c.EnvExport("GIT_CONFIG"), 7)
+
+	want := `export GIT_CONFIG_COUNT='3'
+export GIT_CONFIG_KEY_0='core.pager'
+export GIT_CONFIG_VALUE_0='it'\''s less'
+export GIT_CONFIG_KEY_1='remote.origin.fetch'
+export GIT_CONFIG_VALUE_1='a'
+export GIT_CONFIG_KEY_2='remote.origin.fetch'
+export GIT_CONFIG_VALUE_2='b'
+`
+	assert.Equal(t, want, c.EnvExportShell("GIT_CONFIG"))
+
+	// git reads the exported values
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	out, err := exec.Command("sh", "-c", want+"git config --get-all remote.origin.fetch; git config core.pager").Output()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "it's less"}, strings.Split(strings.TrimSpace(string(out)), "\n"))
+}
+
+func TestCommandEnv(t *testing.T) {
+	t.Setenv("GPTEST_CMDENV_CONFIG_COUNT", "1")
+	t.Setenv("GPTEST_CMDENV_CONFIG_KEY_0", "user.name")
+	t.Setenv("GPTEST_CMDENV_CONFIG_VALUE_0", "me")
+	t.Setenv("GPTEST_CMDENV_CONFIG_NOSYSTEM", "1")
+
+	c := New()
+	c.EnvPrefix = "GPTEST_CMDENV_CONFIG"
+	assert.Equal(t, []string{"GPTEST_CMDENV_CONFIG_COUNT=0", "GPTEST_CMDENV_CONFIG_NOSYSTEM=1"}, c.CommandEnv())
+
+	c.LoadAll("")
+	require.NoError(t, c.SetEnv("core.pager", "less"))
+	assert.Equal(t, []string{
+		"GPTEST_CMDENV_CONFIG_COUNT=2",
+		"GPTEST_CMDENV_CONFIG_KEY_0=core.pager",
+		"GPTEST_CMDENV_CONFIG_VALUE_0=less",
+		"GPTEST_CMDENV_CONFIG_KEY_1=user.name",
+		"GPTEST_CMDENV_CONFIG_VALUE_1=me",
+		"GPTEST_CMDENV_CONFIG_NOSYSTEM=1",
+	}, c.CommandEnv())
+
+	// a child process sees the same env scope
+	t.Setenv("GPTEST_CMDENV_CONFIG_COUNT", "0")
+	child := New()
+	child.EnvPrefix = c.EnvPrefix
+	for _, kv := range c.CommandEnv() {
+		k, v, _ := strings.Cut(kv, "=")
+		t.Setenv(k, v)
+	}
+	child.LoadAll("")
+	assert.Equal(t, "less", child.Get("core.pager"))
+	assert.Equal(t, "me", child.Get("user.name"))
+}
@@ -3,7 +3,7 @@ package gitconfig
 import "errors"
 
 var (
-	// ErrInvalidKey indicates a config key missing section or key name.
+	// ErrInvalidKey indicates a config key with a missing or invalid section, subsection or variable name.
 	ErrInvalidKey = errors.New("invalid key")
 	// ErrWorkdirNotSet indicates a workdir is required but not configured.
 	ErrWorkdirNotSet = errors.New("no workdir set")
@@ -11,4 +11,32 @@ var (
 	ErrCreateConfigDir = errors.New("failed to create config directory")
 	// ErrWriteConfig indicates a config file could not be written.
 	ErrWriteConfig = errors.New("failed to write config")
+	// ErrInvalidValue indicates a config value could not be parsed as the requested type.
+	ErrInvalidValue = errors.New("invalid value")
+	// ErrUnknownScope indicates a scope name that is not known or can not be used for the operation.
+	ErrUnknownScope = errors.New("unknown scope")
+	// ErrGitNotFound indicates that no git binary could be found.
+	ErrGitNotFound = errors.New("git not found")
+	// ErrUnsupportedType indicates a Go type that can not be mapped to or from config values.
+	ErrUnsupportedType = errors.New("unsupported type")
+	// ErrParse indicates a config file with invalid syntax. See ParseError.
+	ErrParse = errors.New("parse error")
+	// ErrReadonly indicates a config that can not be modified.
+	ErrReadonly = errors.New("config is readonly")
+	// ErrInvalidOrigin indicates an Origin that does not refer to a suitable line of the config.
+	ErrInvalidOrigin = errors.New("invalid origin")
+	// ErrNoConfigFile indicates that no config file was selected, see NewFileConfigs.
+	ErrNoConfigFile = errors.New("no config file")
+	// ErrNotAConfigFile indicates a file with binary content, e.g. NUL bytes, that can not be a config file.
+	ErrNotAConfigFile = errors.New("not a config file")
+	// ErrLimitExceeded indicates a key or value count beyond one of the configured limits. See LimitError.
+	ErrLimitExceeded = errors.New("limit exceeded")
+	// ErrIncludeDepthExceeded indicates includes nested deeper than Configs.MaxIncludeDepth. See IncludeDepthError.
+	ErrIncludeDepthExceeded = errors.New("include depth exceeded")
+	// ErrCircularInclude indicates includes that form a cycle. See CircularIncludeError.
+	ErrCircularInclude = errors.New("circular include")
+	// ErrIncludeUnreadable indicates an included file that can not be read. See IncludeError.
+	ErrIncludeUnreadable = errors.New("include can not be read")
+	// ErrWorktreeConfigDisabled indicates a write to the worktree config while extensions.worktreeConfig is not enabled. See Configs.SetWorktree.
+	ErrWorktreeConfigDisabled = errors.New("worktree config is not enabled")
 )
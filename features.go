@@ -0,0 +1,74 @@
+package gitconfig
+
+import "maps"
+
+// Feature identifies an optional capability of this package. Since this
+// package doesn't follow semantic versioning, callers can use Features or
+// Supports to detect at runtime what the linked version can do.
+type Feature string
+
+const (
+	// FeatureInclude indicates support for [include] directives.
+	FeatureInclude Feature = "include"
+	// FeatureIncludeIfGitdir indicates support for includeIf "gitdir:" and "gitdir/i:".
+	FeatureIncludeIfGitdir Feature = "includeif-gitdir"
+	// FeatureIncludeIfOnbranch indicates support for includeIf "onbranch:".
+	FeatureIncludeIfOnbranch Feature = "includeif-onbranch"
+	// FeatureHasconfig indicates support for includeIf "hasconfig:remote.*.url:".
+	FeatureHasconfig Feature = "includeif-hasconfig"
+	// FeatureWorktree indicates support for the worktree scope.
+	FeatureWorktree Feature = "worktree"
+	// FeatureMultivarWrite indicates support for writing individual values of multivars.
+	FeatureMultivarWrite Feature = "multivar-write"
+	// FeatureURLMatch indicates support for GetURLMatch.
+	FeatureURLMatch Feature = "urlmatch"
+	// FeatureInsteadOf indicates support for url.<base>.insteadOf rewriting.
+	FeatureInsteadOf Feature = "insteadof"
+	// FeatureBareBool indicates that bare keys (without "=") are told apart from empty values.
+	FeatureBareBool Feature = "bare-bool"
+	// FeatureTypedValues indicates support for typed getters and setters (bool, int, path, ...).
+	FeatureTypedValues Feature = "typed-values"
+	// FeatureExpiryDate indicates support for expiry date values.
+	FeatureExpiryDate Feature = "expiry-date"
+	// FeatureColor indicates support for color values.
+	FeatureColor Feature = "color"
+	// FeatureCompatLevel indicates support for CompatLevel.
+	FeatureCompatLevel Feature = "compat-level"
+)
+
+// features lists the capabilities of this version of the package.
+var features = map[Feature]bool{
+	FeatureInclude:           true,
+	FeatureIncludeIfGitdir:   true,
+	FeatureIncludeIfOnbranch: true,
+	FeatureHasconfig:         false,
+	FeatureWorktree:          true,
+	FeatureMultivarWrite:     false,
+	FeatureURLMatch:          true,
+	FeatureInsteadOf:         true,
+	FeatureBareBool:          true,
+	FeatureTypedValues:       true,
+	FeatureExpiryDate:        true,
+	FeatureColor:             true,
+	FeatureCompatLevel:       true,
+}
+
+// Features returns all known features and whether they are supported.
+// Features unknown to this version are missing from the map. The returned
+// map is a copy and can be modified by the caller.
+//
+// Example:
+//
+//	if gitconfig.Features()[gitconfig.FeatureHasconfig] {
+//		// rely on hasconfig includes
+//	}
+func Features() map[Feature]bool {
+	return maps.Clone(features)
+}
+
+// Supports returns true if the feature is supported by this version.
+// Use string literals for features that may not exist in older versions,
+// e.g. Supports("includeif-hasconfig").
+func Supports(f Feature) bool {
+	return features[f]
+}
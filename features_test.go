@@ -0,0 +1,24 @@
+package gitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatures(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, Supports(FeatureInclude))
+	assert.True(t, Supports(FeatureURLMatch))
+	assert.False(t, Supports(FeatureHasconfig))
+	assert.False(t, Supports("unknown-feature"))
+
+	f := Features()
+	assert.Len(t, f, len(features))
+	assert.True(t, f[FeatureIncludeIfOnbranch])
+
+	// the returned map is a copy
+	f[FeatureHasconfig] = true
+	assert.False(t, Supports(FeatureHasconfig))
+}
@@ -0,0 +1,60 @@
+package gitconfig
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// FileConfigs is bound to a single config file that was selected
+// explicitly, like git does for `git config --file` or when GIT_CONFIG is
+// set. All reads and writes go to that file. No other scopes are consulted
+// and includes are not processed, since git ignores them for explicit files
+// as well.
+//
+// Fields:
+// - Config: The content of the file, use it to read and write values
+// - Path: The file that was selected
+// - FromEnv: If the file was selected by the environment variable
+type FileConfigs struct {
+	*Config
+
+	Path    string
+	FromEnv bool
+}
+
+// NewFileConfigs loads the config file named by the envPrefix environment
+// variable itself, without any suffix (e.g. GIT_CONFIG). If it is unset or
+// empty the file fallback is used instead. A missing file is not an error,
+// it is created on the first write.
+//
+// Example:
+//
+//	fc, err := gitconfig.NewFileConfigs("GIT_CONFIG", "tool.conf")
+//	if err != nil { ... }
+//	if err := fc.Set("core.editor", "vim"); err != nil { ... }
+func NewFileConfigs(envPrefix, fallback string) (*FileConfigs, error) {
+	fc := &FileConfigs{
+		Path: fallback,
+	}
+	if p := os.Getenv(envPrefix); p != "" {
+		fc.Path = p
+		fc.FromEnv = true
+	}
+	if fc.Path == "" {
+		return nil, fmt.Errorf("%w: %s is not set and no default file given", ErrNoConfigFile, envPrefix)
+	}
+
+	c, err := loadConfig(fc.Path, parseOptions{})
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		debug.V(1).Log("config file %s does not exist yet", fc.Path)
+		c = &Config{path: fc.Path}
+	case err != nil:
+		return nil, fmt.Errorf("%s: %w", fc.Path, err)
+	}
+	fc.Config = c
+
+	return fc, nil
+}
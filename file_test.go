@@ -0,0 +1,61 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFileConfigs(t *testing.T) {
+	td := t.TempDir()
+	fallback := filepath.Join(td, "fallback")
+	selected := filepath.Join(td, "selected")
+	require.NoError(t, os.WriteFile(fallback, []byte("[core]\n\teditor = vim\n"), 0o600))
+	require.NoError(t, os.WriteFile(selected, []byte("[include]\n\tpath = fallback\n[core]\n\tpager = less\n"), 0o600))
+
+	t.Setenv("GPTEST_FILE_CONFIG", "")
+
+	fc, err := NewFileConfigs("GPTEST_FILE_CONFIG", fallback)
+	require.NoError(t, err)
+	assert.Equal(t, fallback, fc.Path)
+	assert.False(t, fc.FromEnv)
+	v, _ := fc.Get("core.editor")
+	assert.Equal(t, "vim", v)
+
+	t.Setenv("GPTEST_FILE_CONFIG", selected)
+
+	fc, err = NewFileConfigs("GPTEST_FILE_CONFIG", fallback)
+	require.NoError(t, err)
+	assert.Equal(t, selected, fc.Path)
+	assert.True(t, fc.FromEnv)
+	v, _ = fc.Get("core.pager")
+	assert.Equal(t, "less", v)
+	// includes are not processed for explicit files
+	assert.False(t, fc.IsSet("core.editor"))
+
+	require.NoError(t, fc.Set("core.pager", "more"))
+	buf, err := os.ReadFile(selected)
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), "\tpager = more\n")
+}
+
+func TestNewFileConfigsMissingFile(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "new.conf")
+	t.Setenv("GPTEST_FILE_CONFIG", fn)
+
+	fc, err := NewFileConfigs("GPTEST_FILE_CONFIG", "")
+	require.NoError(t, err)
+	assert.False(t, fc.IsSet("core.editor"))
+
+	require.NoError(t, fc.Set("core.editor", "vim"))
+	buf, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, "[core]\n\teditor = vim\n", string(buf))
+
+	t.Setenv("GPTEST_FILE_CONFIG", "")
+	_, err = NewFileConfigs("GPTEST_FILE_CONFIG", "")
+	require.ErrorIs(t, err, ErrNoConfigFile)
+}
@@ -5,8 +5,6 @@ package gitconfig
 import (
 	"os/exec"
 	"path/filepath"
-
-	"github.com/gopasspw/gopass/pkg/debug"
 )
 
 var systemConfig string
@@ -0,0 +1,287 @@
+package gitconfig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxCachedPatterns limits the number of compiled patterns kept by
+// compilePattern. The cache is cleared when it is full.
+const maxCachedPatterns = 1024
+
+var patternCache = memo[cachedPattern]{max: maxCachedPatterns}
+
+// cachedPattern is the result of compiling a pattern.
+type cachedPattern struct {
+	p   pattern
+	err error
+}
+
+// pattern is a validated wildmatch pattern, see matchPattern.
+type pattern string
+
+// compilePattern validates a wildmatch pattern. Patterns are validated once
+// and cached, so conditions that are evaluated on every load don't pay for
+// it again.
+func compilePattern(p string) (pattern, error) {
+	cp := patternCache.get(p, func(p string) cachedPattern {
+		if err := validatePattern(p); err != nil {
+			return cachedPattern{err: err}
+		}
+
+		return cachedPattern{p: pattern(p)}
+	})
+
+	return cp.p, cp.err
+}
+
+// validatePattern returns an error for patterns that can never match
+// because of a malformed character class. Git's wildmatch silently fails
+// to match them.
+func validatePattern(p string) error {
+	for i := 0; i < len(p); i++ {
+		switch p[i] {
+		case '\\':
+			i++
+		case '[':
+			end, _, err := scanClass(p[i:], 0)
+			if err != nil {
+				return fmt.Errorf("%w in %q", err, p)
+			}
+			i += end - 1
+		}
+	}
+
+	return nil
+}
+
+// scanClass parses the character class at the start of p like git's
+// wildmatch does. It returns the length of the class, including the
+// brackets, and whether c is a member. A "]" right after the opening
+// bracket (or the negation) is a member. Unlike git, reversed ranges like
+// [z-a] are an error.
+func scanClass(p string, c byte) (int, bool, error) { //nolint:cyclop
+	errUnterminated := errors.New("unterminated character class")
+
+	i := 1
+	negated := i < len(p) && (p[i] == '!' || p[i] == '^')
+	if negated {
+		i++
+	}
+
+	matched := false
+	var prev byte
+	for first := true; ; first, i = false, i+1 {
+		if i >= len(p) {
+			return 0, false, errUnterminated
+		}
+		m := p[i]
+		if m == ']' && !first {
+			return i + 1, matched != negated, nil
+		}
+
+		switch {
+		case m == '\\':
+			i++
+			if i >= len(p) {
+				return 0, false, errUnterminated
+			}
+			m = p[i]
+			matched = matched || c == m
+		case m == '-' && prev != 0 && i+1 < len(p) && p[i+1] != ']':
+			i++
+			hi := p[i]
+			if hi == '\\' {
+				i++
+				if i >= len(p) {
+					return 0, false, errUnterminated
+				}
+				hi = p[i]
+			}
+			if hi < prev {
+				return 0, false, fmt.Errorf("invalid range %c-%c", prev, hi)
+			}
+			matched = matched || (c >= prev && c <= hi)
+			m = 0 // a range can not start a range
+		case m == '[' && i+1 < len(p) && p[i+1] == ':':
+			end := strings.IndexByte(p[i+2:], ']')
+			if end < 0 {
+				return 0, false, errUnterminated
+			}
+			if end == 0 || p[i+1+end] != ':' {
+				// not a named class, "[" is a member
+				matched = matched || c == m
+
+				break
+			}
+			fn, found := charClasses[p[i+2:i+1+end]]
+			if !found {
+				return 0, false, fmt.Errorf("unknown character class %q", p[i:i+3+end])
+			}
+			matched = matched || fn(c)
+			i += end + 2
+			m = 0
+		default:
+			matched = matched || c == m
+		}
+		prev = m
+	}
+}
+
+// charClasses are the named character classes like [:alpha:].
+var charClasses = map[string]func(c byte) bool{
+	"alnum":  func(c byte) bool { return isAlpha(c) || isDigit(c) },
+	"alpha":  isAlpha,
+	"blank":  func(c byte) bool { return c == ' ' || c == '\t' },
+	"cntrl":  func(c byte) bool { return c < 0x20 || c == 0x7f },
+	"digit":  isDigit,
+	"graph":  func(c byte) bool { return c > 0x20 && c < 0x7f },
+	"lower":  func(c byte) bool { return c >= 'a' && c <= 'z' },
+	"print":  func(c byte) bool { return c >= 0x20 && c < 0x7f },
+	"punct":  func(c byte) bool { return c > 0x20 && c < 0x7f && !isAlpha(c) && !isDigit(c) },
+	"space":  func(c byte) bool { return c == ' ' || (c >= '\t' && c <= '\r') },
+	"upper":  func(c byte) bool { return c >= 'A' && c <= 'Z' },
+	"xdigit": func(c byte) bool { return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') },
+}
+
+func isAlpha(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// Match reports whether s matches the pattern.
+func (p pattern) Match(s string) bool {
+	return matchPattern(string(p), s) == wildMatch
+}
+
+// results of matchPattern, see git's wildmatch.c.
+const (
+	wildMatch = iota
+	wildNoMatch
+	wildAbortAll
+	wildAbortToStarStar
+)
+
+// matchPattern is a port of dowild from git's wildmatch.c with WM_PATHNAME
+// set, i.e. "/" is a separator:
+//
+//   - "*" matches anything but "/", "?" matches any byte but "/"
+//   - "**" between separators (or at the start or end) matches across
+//     separators, "/**/" also matches a single "/"
+//   - "[...]" matches a byte of a class with ranges, named classes like
+//     [:alpha:] and "!" or "^" for negation, but never "/"
+//   - "\" matches the next byte literally
+//
+// Like git it works on bytes, so "?" matches one byte of a multi-byte
+// character.
+func matchPattern(p, text string) int { //nolint:cyclop
+	for i := 0; i < len(p); i, text = i+1, text[1:] {
+		var t byte
+		if len(text) > 0 {
+			t = text[0]
+		} else if p[i] != '*' {
+			return wildAbortAll
+		}
+
+		switch p[i] {
+		case '?':
+			if t == '/' {
+				return wildNoMatch
+			}
+		case '*':
+			stars := len(p[i:]) - len(strings.TrimLeft(p[i:], "*"))
+			rest := p[i+stars:]
+			matchSlash := false
+			if stars > 1 && (i == 0 || p[i-1] == '/') &&
+				(rest == "" || rest[0] == '/' || strings.HasPrefix(rest, `\/`)) {
+				// "**/" also matches no directory at all
+				if rest != "" && rest[0] == '/' && matchPattern(rest[1:], text) == wildMatch {
+					return wildMatch
+				}
+				matchSlash = true
+			}
+			if rest == "" {
+				// trailing "**" matches everything, "*" only within the
+				// last component
+				if !matchSlash && strings.Contains(text, "/") {
+					return wildNoMatch
+				}
+
+				return wildMatch
+			}
+			if !matchSlash && rest[0] == '/' {
+				// "*/" matches the rest of the component, the slash is
+				// consumed by the loop
+				slash := strings.IndexByte(text, '/')
+				if slash < 0 {
+					return wildNoMatch
+				}
+				i += stars
+				text = text[slash:]
+
+				continue
+			}
+
+			return matchStar(rest, text, matchSlash)
+		case '[':
+			end, matched, err := scanClass(p[i:], t)
+			if err != nil {
+				return wildAbortAll
+			}
+			if !matched || t == '/' {
+				return wildNoMatch
+			}
+			i += end - 1
+		default:
+			c := p[i]
+			if c == '\\' {
+				// a trailing backslash never matches
+				if i++; i >= len(p) {
+					return wildNoMatch
+				}
+				c = p[i]
+			}
+			if t != c {
+				return wildNoMatch
+			}
+		}
+	}
+
+	if len(text) > 0 {
+		return wildNoMatch
+	}
+
+	return wildMatch
+}
+
+// matchStar matches text against the pattern p that follows a "*" (or
+// "**" if matchSlash is set).
+func matchStar(p, text string, matchSlash bool) int {
+	for len(text) > 0 {
+		// advance quickly to the next occurrence of a literal
+		if !isGlobSpecial(p[0]) {
+			lit := p[0]
+			for len(text) > 0 && (matchSlash || text[0] != '/') && text[0] != lit {
+				text = text[1:]
+			}
+			if len(text) == 0 || text[0] != lit {
+				return wildNoMatch
+			}
+		}
+		matched := matchPattern(p, text)
+		if matched != wildNoMatch {
+			if !matchSlash || matched != wildAbortToStarStar {
+				return matched
+			}
+		} else if !matchSlash && text[0] == '/' {
+			return wildAbortToStarStar
+		}
+		text = text[1:]
+	}
+
+	return wildAbortAll
+}
+
+// isGlobSpecial reports whether c has a special meaning in a pattern.
+func isGlobSpecial(c byte) bool {
+	return c == '*' || c == '?' || c == '[' || c == '\\'
+}
@@ -3,7 +3,6 @@ module github.com/gopasspw/gitconfig
 go 1.24.1
 
 require (
-	github.com/gobwas/glob v0.2.3
 	github.com/gopasspw/gopass v1.16.1
 	github.com/stretchr/testify v1.11.1
 )
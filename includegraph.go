@@ -0,0 +1,108 @@
+package gitconfig
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// IncludeNode is an include directive found while loading a config, see
+// Config.Includes. Conditional includes are listed even if their condition
+// does not match, so tools can explain why a file was or was not included.
+//
+// Fields:
+// - Path: The included file, resolved relative to Parent
+// - Parent: The file that contains the directive
+// - Condition: The condition of an includeIf section, e.g. "gitdir:~/work/", empty for include.path
+// - Matched: Whether the condition matched, always true for include.path
+// - Loaded: Whether the file was loaded; matching includes are not loaded if they were loaded before, could not be read (see SkippedIncludes) or are over Configs.MaxIncludes
+type IncludeNode struct {
+	Path      string
+	Parent    string
+	Condition string
+	Matched   bool
+	Loaded    bool
+}
+
+// Conditional reports whether the node is from an includeIf section.
+func (n IncludeNode) Conditional() bool {
+	return n.Condition != ""
+}
+
+// Includes returns the include directives found while loading the config
+// and the files they include, in the order they were processed. Each file
+// is listed with all its include.path values first, then the includeIf
+// sections in the order they appear in the file. The includes of a file
+// follow the includes of the files loaded before it, so the nodes of a
+// Parent always come after the node that loaded it.
+//
+// Example:
+//
+//	c, _ := gitconfig.LoadConfig("~/.gitconfig")
+//	for _, n := range c.Includes() {
+//		fmt.Println(n.Parent, "->", n.Path, n.Condition, n.Matched, n.Loaded)
+//	}
+func (c *Config) Includes() []IncludeNode {
+	if c == nil {
+		return nil
+	}
+
+	return slices.Clone(c.includeGraph)
+}
+
+// Includes returns the include directives of the config in the given scope,
+// see Config.Includes. It returns nil for unknown scopes or scopes that have
+// not been loaded.
+func (cs *Configs) Includes(scope string) []IncludeNode {
+	return cs.scopeConfig(scope).Includes()
+}
+
+// includeDirectives returns the include directives of c with their paths
+// as written: all include.path values, then the paths of the includeIf
+// sections ordered by the line they are defined on. The conditions are
+// evaluated against workdir, see matchSubSection.
+func includeDirectives(c *Config, workdir string) []IncludeNode {
+	paths, _ := c.GetAll("include.path")
+	out := make([]IncludeNode, 0, len(paths))
+	for _, p := range paths {
+		out = append(out, IncludeNode{Path: p, Parent: c.path, Matched: true})
+	}
+
+	candidates := []string{}
+	for k := range c.vars {
+		debug.V(3).Log("found config key: %q", k)
+		// must have the form includeIf.<condition>.path
+		// e.g. includeIf."gitdir:/path/to/group/".path
+		// see https://git-scm.com/docs/git-config#_conditional_includes
+		sec, subsec, key := splitKey(k)
+		if sec != "includeif" || subsec == "" || key != "path" {
+			continue
+		}
+		candidates = append(candidates, k)
+	}
+	// map order is random, use the order of the file instead
+	slices.SortFunc(candidates, func(a, b string) int {
+		return cmp.Or(cmp.Compare(firstLine(c, a), firstLine(c, b)), strings.Compare(a, b))
+	})
+
+	for _, k := range candidates {
+		_, subsec, _ := splitKey(k)
+		matched := matchSubSection(subsec, workdir, c)
+		paths, _ := c.GetAll(k)
+		for _, p := range paths {
+			out = append(out, IncludeNode{Path: p, Parent: c.path, Condition: subsec, Matched: matched})
+		}
+	}
+
+	return out
+}
+
+// firstLine returns the line the key is first defined on, or 0 if it is
+// unknown.
+func firstLine(c *Config, key string) int {
+	if vo := c.origins[key]; len(vo) > 0 {
+		return vo[0].line
+	}
+
+	return 0
+}
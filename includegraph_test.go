@@ -0,0 +1,63 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncludes(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	repo := filepath.Join(td, "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0o644))
+
+	fn := filepath.Join(td, "config")
+	require.NoError(t, os.WriteFile(fn, []byte(`[includeIf "onbranch:feat/"]
+	path = feat.config
+[include]
+	path = a.config
+	path = missing.config
+[includeIf "gitdir:`+filepath.ToSlash(repo)+`/"]
+	path = repo.config
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "a.config"), []byte("[include]\n\tpath = config\n[core]\n\tint = 1\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "repo.config"), []byte("[core]\n\tint = 2\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "feat.config"), []byte("[core]\n\tint = 3\n"), 0o600))
+
+	cfg, err := LoadConfigWithWorkdir(fn, repo)
+	require.NoError(t, err)
+
+	assert.Equal(t, []IncludeNode{
+		{Path: filepath.Join(td, "a.config"), Parent: fn, Matched: true, Loaded: true},
+		{Path: filepath.Join(td, "missing.config"), Parent: fn, Matched: true},
+		{Path: filepath.Join(td, "feat.config"), Parent: fn, Condition: "onbranch:feat/"},
+		{Path: filepath.Join(td, "repo.config"), Parent: fn, Condition: "gitdir:" + filepath.ToSlash(repo) + "/", Matched: true, Loaded: true},
+		// the cycle back to the loaded file is not followed
+		{Path: fn, Parent: filepath.Join(td, "a.config"), Matched: true},
+	}, cfg.Includes())
+	assert.True(t, cfg.Includes()[3].Conditional())
+	assert.False(t, cfg.Includes()[0].Conditional())
+
+	vs, _ := cfg.GetAll("core.int")
+	assert.Equal(t, []string{"1", "2"}, vs)
+
+	// the result is a copy
+	cfg.Includes()[0].Path = "changed"
+	assert.Equal(t, filepath.Join(td, "a.config"), cfg.Includes()[0].Path)
+
+	assert.Empty(t, ParseConfig(strings.NewReader("[include]\n\tpath = a.config\n")).Includes())
+	assert.Nil(t, (*Config)(nil).Includes())
+
+	cs := New()
+	cs.global = cfg
+	assert.Equal(t, cfg.Includes(), cs.Includes(ScopeGlobal))
+	assert.Nil(t, cs.Includes(ScopeLocal))
+	assert.Nil(t, cs.Includes("unknown"))
+}
@@ -0,0 +1,104 @@
+package gitconfig
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+)
+
+// IncludeErrorPolicy controls what happens if an included file can not be
+// read, e.g. because it does not exist or is not readable. Syntax errors
+// in included files are not affected, see Configs.Strict.
+type IncludeErrorPolicy int
+
+const (
+	// IncludeErrorIgnore skips includes that can not be read, like git does.
+	IncludeErrorIgnore IncludeErrorPolicy = iota
+	// IncludeErrorWarn skips them as well but reports a warning for each,
+	// see Config.Warnings and ScopeReport.Issues.
+	IncludeErrorWarn
+	// IncludeErrorFail fails loading the config with an *IncludeError.
+	IncludeErrorFail
+)
+
+// String implements fmt.Stringer.
+func (p IncludeErrorPolicy) String() string {
+	switch p {
+	case IncludeErrorIgnore:
+		return "ignore"
+	case IncludeErrorWarn:
+		return "warn"
+	case IncludeErrorFail:
+		return "fail"
+	default:
+		return "unknown"
+	}
+}
+
+// SkippedInclude is an included file that was skipped because it could not
+// be read.
+//
+// Fields:
+// - Path: The included file
+// - From: The file that includes it
+// - Err: Why it could not be read, e.g. an error wrapping fs.ErrNotExist
+type SkippedInclude struct {
+	Path string
+	From string
+	Err  error
+}
+
+// String implements fmt.Stringer.
+func (s SkippedInclude) String() string {
+	return fmt.Sprintf("skipping include %s: %s", s.Path, s.Err)
+}
+
+// IncludeError is returned when an included file can not be read and the
+// IncludeErrorPolicy is IncludeErrorFail. It wraps ErrIncludeUnreadable as
+// well as the underlying error.
+//
+// Example:
+//
+//	var ierr *gitconfig.IncludeError
+//	if errors.As(err, &ierr) && errors.Is(ierr, fs.ErrNotExist) {
+//		fmt.Println("missing include", ierr.Path, "in", ierr.From)
+//	}
+type IncludeError struct {
+	SkippedInclude
+}
+
+// Error implements the error interface.
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("%s: %s included from %s: %s", ErrIncludeUnreadable, e.Path, e.From, e.Err)
+}
+
+// Unwrap returns ErrIncludeUnreadable and the underlying error.
+func (e *IncludeError) Unwrap() []error {
+	return []error{ErrIncludeUnreadable, e.Err}
+}
+
+// SkippedIncludes returns the included files that were skipped because
+// they could not be read, in the order they were found.
+//
+// Example:
+//
+//	c, _ := gitconfig.LoadConfig("~/.gitconfig")
+//	for _, s := range c.SkippedIncludes() {
+//		fmt.Println(s.Path, s.Err)
+//	}
+func (c *Config) SkippedIncludes() []SkippedInclude {
+	if c == nil {
+		return nil
+	}
+
+	return slices.Clone(c.skippedIncludes)
+}
+
+// isUnreadable reports whether err is an error reading a file, as opposed
+// to an error parsing it.
+func isUnreadable(err error) bool {
+	var pe *fs.PathError
+
+	return errors.As(err, &pe)
+}
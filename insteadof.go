@@ -0,0 +1,69 @@
+package gitconfig
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// RewriteURL applies the url.<base>.insteadOf rules to the URL like git does
+// for fetch URLs. If multiple rules match, the longest prefix wins.
+// The URL is returned unchanged if no rule matches.
+//
+// Example:
+//
+//	// [url "git@github.com:"]
+//	//	insteadOf = https://github.com/
+//	u := cfg.RewriteURL("https://github.com/gopasspw/gopass") // git@github.com:gopasspw/gopass
+func (cs *Configs) RewriteURL(url string) string {
+	if rewritten, ok := cs.rewriteURL(url, "insteadof"); ok {
+		return rewritten
+	}
+
+	return url
+}
+
+// RewritePushURL applies the url.<base>.pushInsteadOf rules to the URL like
+// git does for push URLs. If no pushInsteadOf rule matches, the insteadOf
+// rules are applied as with RewriteURL.
+func (cs *Configs) RewritePushURL(url string) string {
+	if rewritten, ok := cs.rewriteURL(url, "pushinsteadof"); ok {
+		return rewritten
+	}
+
+	return cs.RewriteURL(url)
+}
+
+// rewriteURL replaces the longest prefix of url that matches a
+// url.<base>.<kind> value with its base. On equal length the rule
+// that is read first by git, i.e. from the lowest priority scope, wins.
+func (cs *Configs) rewriteURL(url, kind string) (string, bool) {
+	var base, prefix string
+	var found bool
+
+	scopes := cs.namedScopes()
+	slices.Reverse(scopes)
+	for _, sc := range scopes {
+		if sc.cfg == nil || sc.cfg.vars == nil {
+			continue
+		}
+		for _, k := range slices.Sorted(maps.Keys(sc.cfg.vars)) {
+			sec, subsec, key := splitKey(k)
+			if sec != "url" || subsec == "" || key != kind {
+				continue
+			}
+			for _, p := range sc.cfg.vars[k] {
+				if !strings.HasPrefix(url, p) || (found && len(p) <= len(prefix)) {
+					continue
+				}
+				base, prefix, found = subsec, p, true
+			}
+		}
+	}
+
+	if !found {
+		return "", false
+	}
+
+	return base + strings.TrimPrefix(url, prefix), true
+}
@@ -0,0 +1,45 @@
+package gitconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteURL(t *testing.T) {
+	t.Parallel()
+
+	cs := New()
+	cs.global = ParseConfig(strings.NewReader(`[url "git@github.com:"]
+	insteadOf = https://github.com/
+	insteadOf = gh:
+[url "https://mirror.example.com/"]
+	insteadOf = https://example.com/
+	pushInsteadOf = https://nowhere.example.com/
+`))
+	cs.local = ParseConfig(strings.NewReader(`[url "git@github.com:gopasspw/"]
+	insteadOf = https://github.com/gopasspw/
+[url "ssh://git@example.com/"]
+	pushInsteadOf = https://example.com/
+`))
+
+	for in, want := range map[string]string{
+		"https://github.com/foo/bar":       "git@github.com:foo/bar",
+		"gh:foo/bar":                       "git@github.com:foo/bar",
+		"https://github.com/gopasspw/repo": "git@github.com:gopasspw/repo",
+		"https://example.com/repo.git":     "https://mirror.example.com/repo.git",
+		"https://gitlab.com/repo.git":      "https://gitlab.com/repo.git",
+	} {
+		assert.Equal(t, want, cs.RewriteURL(in), in)
+	}
+
+	for in, want := range map[string]string{
+		"https://example.com/repo.git":         "ssh://git@example.com/repo.git",
+		"https://nowhere.example.com/repo.git": "https://mirror.example.com/repo.git",
+		"https://github.com/foo/bar":           "git@github.com:foo/bar",
+		"https://gitlab.com/repo.git":          "https://gitlab.com/repo.git",
+	} {
+		assert.Equal(t, want, cs.RewritePushURL(in), in)
+	}
+}
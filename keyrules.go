@@ -0,0 +1,192 @@
+package gitconfig
+
+import (
+	"regexp"
+	"strings"
+)
+
+// KeyRules controls which parts of a key are case-insensitive, i.e. how keys
+// are canonicalized when parsing, storing and looking them up, and which
+// variable names are valid. The zero value follows git: section and variable
+// names are case-insensitive, subsections are case-sensitive (except in the
+// deprecated [section.subsection] syntax) and variable names start with a
+// letter followed by letters, digits and "-". Applications that use the
+// format for their own configs can set Configs.KeyRules to change this.
+//
+// Fields:
+// - CaseSensitiveSections: Section names are compared as written
+// - CaseSensitiveNames: Variable names are compared as written
+// - FoldSubsections: Subsection names are case-insensitive as well
+// - Names: If set, variable names must match it instead of git's rule. Names
+// can never be empty or contain ".", "=", quotes, whitespace or comment
+// characters. Lines with other names are skipped and reported by
+// Config.Warnings, Set rejects them with ErrInvalidKey.
+// - MaxKeyLength: Limits the length of a key, including its section and
+// subsection, in bytes. Zero uses the default of 2048
+// - MaxSubsectionLength: Limits the length of a subsection, in bytes. Zero
+// uses the default of 1024
+// - MaxValuesPerKey: Limits the number of values of a multi-valued key. Zero
+// uses the default of 65536
+//
+// Lines exceeding one of the limits are ignored and reported by
+// Config.Warnings, Set and Marshal fail with a *LimitError. A negative limit
+// disables it.
+//
+// Example:
+//
+//	cfg := gitconfig.New()
+//	cfg.KeyRules = gitconfig.KeyRules{CaseSensitiveSections: true, CaseSensitiveNames: true}
+//	cfg.LoadAll(".")
+//	cfg.Get("Core.Editor") // does not find core.editor
+//
+//	// also accept names starting with a digit or containing "_"
+//	cfg.KeyRules = gitconfig.KeyRules{Names: regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)}
+type KeyRules struct {
+	CaseSensitiveSections bool
+	CaseSensitiveNames    bool
+	FoldSubsections       bool
+	Names                 *regexp.Regexp
+
+	MaxKeyLength        int
+	MaxSubsectionLength int
+	MaxValuesPerKey     int
+}
+
+// Canonical returns the canonical form of the key under these rules. It
+// returns an empty string if the key has no section or variable name.
+//
+// Example:
+//
+//	gitconfig.KeyRules{}.Canonical("Remote.Origin.URL") // "remote.Origin.url"
+func (r KeyRules) Canonical(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	section, subsection, name := splitKey(key)
+	if section == "" || name == "" {
+		return ""
+	}
+
+	cSection, cSubsection, cName := r.section(section), r.subsection(subsection), r.name(name)
+	if cSection == section && cSubsection == subsection && cName == name {
+		// already canonical, avoid building the same key again
+		return key
+	}
+
+	if subsection == "" {
+		return cSection + "." + cName
+	}
+
+	return cSection + "." + cSubsection + "." + cName
+}
+
+// section returns the canonical form of a section name.
+func (r KeyRules) section(s string) string {
+	if r.CaseSensitiveSections {
+		return s
+	}
+
+	return strings.ToLower(s)
+}
+
+// subsection returns the canonical form of a subsection name.
+func (r KeyRules) subsection(s string) string {
+	if !r.FoldSubsections {
+		return s
+	}
+
+	return strings.ToLower(s)
+}
+
+// name returns the canonical form of a variable name.
+func (r KeyRules) name(s string) string {
+	if r.CaseSensitiveNames {
+		return s
+	}
+
+	return strings.ToLower(s)
+}
+
+// validName reports whether the variable name, as written, is valid.
+func (r KeyRules) validName(name string) bool {
+	if r.Names == nil {
+		return validKeyName(strings.ToLower(name))
+	}
+
+	return name != "" && !strings.ContainsAny(name, ".=\" \t#;[]") && r.Names.MatchString(name)
+}
+
+// canonicalKey returns the canonical form of the key under the key rules
+// of this config.
+func (c *Config) canonicalKey(key string) string {
+	if c == nil {
+		return canonicalizeKey(key)
+	}
+
+	return c.keys.Canonical(key)
+}
+
+// parseOptions are the settings of a Configs that affect how its files are
+// parsed, including their includes.
+//
+// Fields:
+// - compat: See Configs.SetCompatMode, nil to use the package default
+// - level: See Configs.SetCompatLevel, nil for CompatLegacy
+// - keys: See KeyRules
+type parseOptions struct {
+	compat *bool
+	level  *compatState
+	keys   KeyRules
+
+	failOnCycle   bool               // see Configs.FailOnCircularInclude
+	maxIncludes   int                // see Configs.MaxIncludes
+	maxDepth      int                // see Configs.MaxIncludeDepth
+	maxLineLength int                // see Configs.MaxLineLength
+	includeErrors IncludeErrorPolicy // see Configs.IncludeErrors
+	branch        string             // the branch for onbranch conditions, see SetBranch
+	repo          repoEnv            // see Configs.GitEnv
+}
+
+// branchFor returns the branch onbranch conditions are matched against:
+// the one set with SetBranch or else the one checked out in workdir.
+func (o parseOptions) branchFor(workdir string) string {
+	if o.branch != "" {
+		return o.branch
+	}
+
+	return readGitBranch(workdir, o.repo)
+}
+
+// includeLimit returns the maximum number of files pulled in through
+// includes, zero if there is no limit.
+func (o parseOptions) includeLimit() int {
+	return limitOrDefault(o.maxIncludes, defaultMaxIncludes)
+}
+
+// includeDepthLimit returns how deeply includes may be nested, zero if
+// there is no limit.
+func (o parseOptions) includeDepthLimit() int {
+	return limitOrDefault(o.maxDepth, defaultMaxIncludeDepth)
+}
+
+// parseOptions returns the parse options for the scopes of cs.
+func (cs *Configs) parseOptions() parseOptions {
+	return parseOptions{
+		compat:        cs.compatMode,
+		level:         cs.compatLevel,
+		keys:          cs.KeyRules,
+		failOnCycle:   cs.FailOnCircularInclude,
+		maxIncludes:   cs.MaxIncludes,
+		maxDepth:      cs.MaxIncludeDepth,
+		maxLineLength: cs.MaxLineLength,
+		includeErrors: cs.IncludeErrors,
+		branch:        cs.branch,
+		repo:          cs.repo,
+	}
+}
+
+// canonicalKey returns the canonical form of the key under cs.KeyRules.
+func (cs *Configs) canonicalKey(key string) string {
+	return cs.KeyRules.Canonical(key)
+}
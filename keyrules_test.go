@@ -0,0 +1,116 @@
+package gitconfig
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRulesCanonical(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		rules KeyRules
+		in    string
+		out   string
+	}{
+		{KeyRules{}, "Core.Editor", "core.editor"},
+		{KeyRules{}, "Remote.Origin.URL", "remote.Origin.url"},
+		{KeyRules{}, "core", ""},
+		{KeyRules{CaseSensitiveSections: true}, "Core.Editor", "Core.editor"},
+		{KeyRules{CaseSensitiveNames: true}, "Core.Editor", "core.Editor"},
+		{KeyRules{FoldSubsections: true}, "Remote.Origin.URL", "remote.origin.url"},
+		{KeyRules{CaseSensitiveSections: true, CaseSensitiveNames: true}, "Remote.Origin.URL", "Remote.Origin.URL"},
+	} {
+		assert.Equal(t, tc.out, tc.rules.Canonical(tc.in), "%+v %s", tc.rules, tc.in)
+	}
+}
+
+func TestConfigsKeyRules(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+	t.Setenv("GPTEST_KEYRULES_CONFIG_COUNT", "1")
+	t.Setenv("GPTEST_KEYRULES_CONFIG_KEY_0", "App.Mode")
+	t.Setenv("GPTEST_KEYRULES_CONFIG_VALUE_0", "env")
+
+	local := filepath.Join(td, "local")
+	require.NoError(t, os.WriteFile(local, []byte("[Core]\n\tEditor = vim\n[core]\n\teditor = nano\n[remote \"Origin\"]\n\tURL = u\n"), 0o600))
+
+	c := New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_KEYRULES_CONFIG"
+	c.KeyRules = KeyRules{CaseSensitiveSections: true, CaseSensitiveNames: true}
+	c.LoadAll(td)
+
+	assert.Equal(t, "vim", c.Get("Core.Editor"))
+	assert.Equal(t, "nano", c.Get("core.editor"))
+	assert.Empty(t, c.Get("core.Editor"))
+	assert.Equal(t, "u", c.Get("remote.Origin.URL"))
+	assert.Equal(t, "env", c.Get("App.Mode"))
+	assert.Empty(t, c.Get("app.mode"))
+
+	// writes go to the section with the same spelling
+	require.NoError(t, c.SetLocal("Core.Pager", "less"))
+	require.NoError(t, c.SetLocal("core.pager", "more"))
+	require.NoError(t, c.UnsetLocal("Core.Editor"))
+	buf, err := os.ReadFile(local)
+	require.NoError(t, err)
+	assert.Equal(t, "[Core]\n\tPager = less\n[core]\n\tpager = more\n\teditor = nano\n[remote \"Origin\"]\n\tURL = u\n", string(buf))
+
+	c.Reload()
+	assert.Equal(t, "less", c.Get("Core.Pager"))
+	assert.Equal(t, "more", c.Get("core.pager"))
+
+	// folded subsections
+	c = New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_KEYRULES_CONFIG"
+	c.KeyRules = KeyRules{FoldSubsections: true}
+	c.LoadAll(td)
+	assert.Equal(t, "u", c.Get("remote.origin.url"))
+	assert.Equal(t, "u", c.Get("REMOTE.ORIGIN.URL"))
+	assert.Equal(t, "env", c.Get("app.mode"))
+}
+
+func TestKeyRulesNames(t *testing.T) {
+	t.Parallel()
+
+	in := "[tool]\n\t2fa = on\n\tmy_key = x\n\tok = y\n\tbad$name = z\n"
+
+	// git's rules skip the names and report them
+	c := ParseConfig(strings.NewReader(in))
+	assert.Equal(t, []string{"tool.ok"}, slices.Sorted(maps.Keys(c.vars)))
+	ws := c.Warnings()
+	require.Len(t, ws, 3)
+	assert.Equal(t, `invalid key "2fa"`, ws[0].Reason)
+
+	rules := KeyRules{Names: regexp.MustCompile(`^[a-z0-9_]+$`)}
+	c, err := parseConfig(strings.NewReader(in), parseOptions{keys: rules})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tool.2fa", "tool.my_key", "tool.ok"}, slices.Sorted(maps.Keys(c.vars)))
+	ws = c.Warnings()
+	require.Len(t, ws, 1)
+	assert.Equal(t, Warning{Line: 5, Reason: `invalid key "bad$name"`}, ws[0])
+
+	c.noWrites = true
+	require.NoError(t, c.Set("tool.3rd_party", "1"))
+	assert.Equal(t, "1", c.vars["tool.3rd_party"][0])
+	require.ErrorIs(t, c.Set("tool.Upper", "1"), ErrInvalidKey)
+	require.ErrorIs(t, c.Set("tool.a b", "1"), ErrInvalidKey)
+
+	// the structure of a line is never up to the pattern
+	rules = KeyRules{Names: regexp.MustCompile(`.*`)}
+	for _, name := range []string{"", "a=b", "a b", "a#b", `a"b`} {
+		assert.False(t, rules.validName(name), name)
+	}
+	assert.True(t, rules.validName("α"))
+}
@@ -0,0 +1,150 @@
+package gitconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The defaults of the limits of Configs.
+const (
+	defaultMaxIncludes     = 100
+	defaultMaxIncludeDepth = 10 // like git
+	defaultMaxLineLength   = 16 << 20
+
+	defaultMaxKeyLength        = 2048
+	defaultMaxSubsectionLength = 1024
+	defaultMaxValuesPerKey     = 1 << 16
+)
+
+// limitOrDefault returns the limit n, def if n is zero or zero if n is
+// negative, i.e. the limit is disabled.
+func limitOrDefault(n, def int) int {
+	switch {
+	case n == 0:
+		return def
+	case n < 0:
+		return 0
+	default:
+		return n
+	}
+}
+
+// LimitError is returned when a key exceeds KeyRules.MaxKeyLength or
+// KeyRules.MaxSubsectionLength or a key would get more than
+// KeyRules.MaxValuesPerKey values.
+//
+// Fields:
+// - Key: The offending key, as given
+// - Limit: What was limited, e.g. "key length"
+// - Max: The configured limit
+//
+// Example:
+//
+//	var lerr *gitconfig.LimitError
+//	if errors.As(cfg.Set(key, value), &lerr) {
+//		fmt.Printf("%s: %s is limited to %d\n", lerr.Key, lerr.Limit, lerr.Max)
+//	}
+type LimitError struct {
+	Key   string
+	Limit string
+	Max   int
+}
+
+// Error implements the error interface.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s: %s is limited to %d", e.Key, ErrLimitExceeded, e.Limit, e.Max)
+}
+
+// Unwrap returns ErrLimitExceeded.
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// checkKeyLimits checks the key against MaxKeyLength and MaxSubsectionLength.
+func (r KeyRules) checkKeyLimits(key string) error {
+	if limit := limitOrDefault(r.MaxKeyLength, defaultMaxKeyLength); limit > 0 && len(key) > limit {
+		return &LimitError{Key: key, Limit: "key length", Max: limit}
+	}
+
+	_, subsection, _ := splitKey(key)
+
+	return r.checkSubsectionLimit(key, subsection)
+}
+
+// checkSubsectionLimit checks a subsection against MaxSubsectionLength.
+func (r KeyRules) checkSubsectionLimit(key, subsection string) error {
+	if limit := limitOrDefault(r.MaxSubsectionLength, defaultMaxSubsectionLength); limit > 0 && len(subsection) > limit {
+		return &LimitError{Key: key, Limit: "subsection length", Max: limit}
+	}
+
+	return nil
+}
+
+// checkValuesLimit checks the number of values of a key against MaxValuesPerKey.
+func (r KeyRules) checkValuesLimit(key string, n int) error {
+	if limit := limitOrDefault(r.MaxValuesPerKey, defaultMaxValuesPerKey); limit > 0 && n > limit {
+		return &LimitError{Key: key, Limit: "number of values", Max: limit}
+	}
+
+	return nil
+}
+
+// issueText describes the error for a parse issue.
+func (e *LimitError) issueText() string {
+	return fmt.Sprintf("%s exceeds the limit of %d", e.Limit, e.Max)
+}
+
+// IncludeDepthError is returned when includes are nested deeper than
+// Configs.MaxIncludeDepth. It wraps ErrIncludeDepthExceeded.
+//
+// Fields:
+// - Chain: The files from the loaded file to the first one beyond the limit
+// - Max: The configured limit
+//
+// Example:
+//
+//	var derr *gitconfig.IncludeDepthError
+//	if errors.As(err, &derr) {
+//		fmt.Println(strings.Join(derr.Chain, " -> "))
+//	}
+type IncludeDepthError struct {
+	Chain []string
+	Max   int
+}
+
+// Error implements the error interface.
+func (e *IncludeDepthError) Error() string {
+	return fmt.Sprintf("%s: more than %d nested includes: %s", ErrIncludeDepthExceeded, e.Max, strings.Join(e.Chain, " -> "))
+}
+
+// Unwrap returns ErrIncludeDepthExceeded.
+func (e *IncludeDepthError) Unwrap() error {
+	return ErrIncludeDepthExceeded
+}
+
+// CircularIncludeError is returned when the includes of a config form a
+// cycle and Configs.FailOnCircularInclude is set. By default such includes
+// are skipped. It wraps ErrCircularInclude.
+//
+// Fields:
+// - Cycle: The files of the cycle, starting and ending with the same file
+//
+// Example:
+//
+//	var cerr *gitconfig.CircularIncludeError
+//	if errors.As(err, &cerr) {
+//		fmt.Println(strings.Join(cerr.Cycle, " -> ")) // e.g. a -> b -> a
+//	}
+type CircularIncludeError struct {
+	Cycle []string
+}
+
+// Error implements the error interface.
+func (e *CircularIncludeError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrCircularInclude, strings.Join(e.Cycle, " -> "))
+}
+
+// Unwrap returns ErrCircularInclude.
+func (e *CircularIncludeError) Unwrap() error {
+	return ErrCircularInclude
+}
@@ -0,0 +1,163 @@
+package gitconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits(t *testing.T) {
+	t.Parallel()
+
+	keys := KeyRules{MaxKeyLength: 20, MaxSubsectionLength: 8, MaxValuesPerKey: 2}
+	in := `[core]
+	editor = vim
+	averyveryverylongname = 1
+[remote "origin"]
+	url = a
+	url = b
+	url = c
+[remote "toolongname"]
+	url = d
+[user]
+	name = e
+`
+	c, err := parseConfig(strings.NewReader(in), parseOptions{keys: keys})
+	require.NoError(t, err)
+	assert.Equal(t, "vim", c.vars["core.editor"][0])
+	assert.NotContains(t, c.vars, "core.averyveryverylongname")
+	assert.Equal(t, []string{"a", "b"}, c.vars["remote.origin.url"])
+	assert.NotContains(t, c.vars, "remote.toolongname.url")
+	assert.Equal(t, []string{"e"}, c.vars["user.name"])
+
+	msgs := make([]string, 0, len(c.issues))
+	for _, pi := range c.issues {
+		msgs = append(msgs, pi.String())
+	}
+	assert.Equal(t, []string{
+		"line 3: key length exceeds the limit of 20",
+		"line 7: number of values exceeds the limit of 2",
+		"line 8: subsection length exceeds the limit of 8",
+		"line 9: key in an ignored section",
+	}, msgs)
+
+	// the default limits apply to strict parsing as well
+	_, err = ParseConfigStrict(strings.NewReader("[core]\n\t" + strings.Repeat("a", 3000) + " = 1\n"))
+	require.ErrorIs(t, err, ErrParse)
+
+	// the same limits apply to Set and Marshal
+	c.noWrites = true
+	var lerr *LimitError
+	err = c.Set("core.averyveryverylongname", "1")
+	require.ErrorAs(t, err, &lerr)
+	require.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Equal(t, LimitError{Key: "core.averyveryverylongname", Limit: "key length", Max: 20}, *lerr)
+	assert.Equal(t, "core.averyveryverylongname: limit exceeded: key length is limited to 20", err.Error())
+
+	require.ErrorIs(t, c.Set("a.toolongname.b", "1"), ErrLimitExceeded)
+	require.ErrorIs(t, c.replaceAll("remote.origin.url", []string{"a", "b", "c"}), ErrLimitExceeded)
+	require.NoError(t, c.replaceAll("remote.origin.url", []string{"x", "y"}))
+	assert.Equal(t, []string{"x", "y"}, c.vars["remote.origin.url"])
+
+	// the defaults are far above
+	c = ParseConfig(strings.NewReader(in))
+	assert.Empty(t, c.issues)
+	c.noWrites = true
+	require.NoError(t, c.Set("core.averyveryverylongname", "1"))
+	require.ErrorIs(t, c.Set(strings.Repeat("a", 3000)+".b", "1"), ErrLimitExceeded)
+
+	// a negative value disables the limits
+	c, err = parseConfig(strings.NewReader(in), parseOptions{keys: KeyRules{MaxKeyLength: -1, MaxSubsectionLength: -1, MaxValuesPerKey: -1}})
+	require.NoError(t, err)
+	c.noWrites = true
+	require.NoError(t, c.Set(strings.Repeat("a", 3000)+".b", "1"))
+	assert.Equal(t, []string{"a", "b", "c"}, c.vars["remote.origin.url"])
+	assert.Equal(t, []string{"d"}, c.vars["remote.toolongname.url"])
+}
+
+func TestIncludeDepth(t *testing.T) {
+	t.Parallel()
+
+	// chain writes n files where every file includes the next one. The
+	// last one includes the first one if cyclic is set.
+	chain := func(n int, cyclic bool) string {
+		td := t.TempDir()
+		for i := range n {
+			content := fmt.Sprintf("[depth]\n\tc%d = true\n", i)
+			switch {
+			case i < n-1:
+				content += fmt.Sprintf("[include]\n\tpath = c%d\n", i+1)
+			case cyclic:
+				content += "[include]\n\tpath = c0\n"
+			}
+			require.NoError(t, os.WriteFile(filepath.Join(td, fmt.Sprintf("c%d", i)), []byte(content), 0o600))
+		}
+
+		return filepath.Join(td, "c0")
+	}
+
+	// git allows 10 levels of nested includes
+	c, err := LoadConfig(chain(11, false))
+	require.NoError(t, err)
+	assert.True(t, c.IsSet("depth.c10"))
+
+	fn := chain(12, false)
+	_, err = LoadConfig(fn)
+	require.ErrorIs(t, err, ErrIncludeDepthExceeded)
+	var derr *IncludeDepthError
+	require.ErrorAs(t, err, &derr)
+	assert.Equal(t, 10, derr.Max)
+	require.Len(t, derr.Chain, 12)
+	assert.Equal(t, fn, derr.Chain[0])
+	assert.Equal(t, filepath.Join(filepath.Dir(fn), "c1"), derr.Chain[1])
+	assert.Contains(t, err.Error(), "c0 -> ")
+
+	// cycles are not nested includes
+	c, err = LoadConfig(chain(3, true))
+	require.NoError(t, err)
+	assert.True(t, c.IsSet("depth.c2"))
+
+	// the limit can be changed or disabled
+	_, err = loadConfigs(chain(4, false), "", parseOptions{maxDepth: 2})
+	require.ErrorAs(t, err, &derr)
+	assert.Equal(t, 2, derr.Max)
+	c, err = loadConfigs(fn, "", parseOptions{maxDepth: -1})
+	require.NoError(t, err)
+	assert.True(t, c.IsSet("depth.c11"))
+}
+
+func TestConfigsMaxIncludeDepth(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	local := "[include]\n\tpath = a.config\n"
+	require.NoError(t, os.WriteFile(filepath.Join(td, "local"), []byte(local), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "a.config"), []byte("[include]\n\tpath = b.config\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "b.config"), []byte("[inc]\n\tkey = b\n"), 0o600))
+
+	c := New()
+	c.SystemConfig = ""
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_CONFIG"
+	c.LoadAll(td)
+	assert.Equal(t, "b", c.Get("inc.key"))
+
+	c.MaxIncludeDepth = 1
+	c.Reload()
+	assert.Empty(t, c.Get("inc.key"))
+	sr, ok := c.LoadReport().Scope(ScopeLocal)
+	require.True(t, ok)
+	assert.Contains(t, sr.Error, ErrIncludeDepthExceeded.Error())
+	assert.True(t, sr.ReadOnly)
+
+	// the file is not overwritten
+	require.NoError(t, c.SetLocal("inc.key", "changed"))
+	buf, err := os.ReadFile(filepath.Join(td, "local"))
+	require.NoError(t, err)
+	assert.Equal(t, local, string(buf))
+}
@@ -0,0 +1,236 @@
+package gitconfig
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// LoadFailure classifies why a scope could not be loaded, see ScopeReport.
+type LoadFailure string
+
+const (
+	// LoadFailureMissing indicates that no config file exists for the scope.
+	LoadFailureMissing LoadFailure = "missing"
+	// LoadFailurePermission indicates a config file that exists but can not be read.
+	LoadFailurePermission LoadFailure = "permission"
+	// LoadFailureParse indicates a config file that can not be parsed, e.g. in strict mode.
+	LoadFailureParse LoadFailure = "parse"
+	// LoadFailureOther indicates any other error.
+	LoadFailureOther LoadFailure = "other"
+)
+
+// ScopeReport describes the outcome of loading a single scope.
+//
+// Fields:
+// - Scope: Name of the scope (system, global, local, worktree or env)
+// - Path: The file that was loaded, or that will be written to if none was found
+// - Attempted: All locations that were tried, in order
+// - Found: If a config was found and loaded for this scope
+// - Skipped: If loading was disabled (e.g. by <EnvPrefix>_NOSYSTEM)
+// - Error: Any error other than a missing file
+// - Failure: Why the scope could not be loaded, empty if it was loaded or skipped
+// - Fatal: If the failure is a hard failure, see LoadReport.Err
+// - Issues: Lines that were ignored while parsing the config and its includes
+// - Includes: Number of included files that were loaded
+// - IncludeLimitReached: If further includes were skipped because of Configs.MaxIncludes
+// - SkippedIncludes: Included files that were skipped because they could not be read
+// - ReadOnly: If changes to this scope can not be persisted
+type ScopeReport struct {
+	Scope     string   `json:"scope"`
+	Path      string   `json:"path,omitempty"`
+	Attempted []string `json:"attempted"`
+	Found     bool     `json:"found"`
+	Skipped   bool     `json:"skipped,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	Issues    []string `json:"issues,omitempty"`
+	Includes  int      `json:"includes"`
+	ReadOnly  bool     `json:"readonly"`
+
+	Failure LoadFailure `json:"failure,omitempty"`
+	Fatal   bool        `json:"fatal,omitempty"`
+
+	IncludeLimitReached bool     `json:"include_limit_reached,omitempty"`
+	SkippedIncludes     []string `json:"skipped_includes,omitempty"`
+
+	err error
+}
+
+// LoadReport summarizes the last LoadAll (or Reload) call. It is meant to
+// help diagnosing why a configuration is not applied as expected.
+type LoadReport struct {
+	Scopes []ScopeReport `json:"scopes"`
+}
+
+// LoadReport returns a summary of the last LoadAll or Reload call.
+//
+// Example:
+//
+//	cfg := New()
+//	cfg.LoadAll(".")
+//	fmt.Println(cfg.LoadReport())
+func (cs *Configs) LoadReport() LoadReport {
+	return cs.report
+}
+
+// Err returns the errors of all scopes that failed hard, or nil. Parse
+// errors are hard failures in strict mode (see Configs.Strict), permission
+// errors if Configs.FailOnPermissionDenied is set, circular includes if
+// Configs.FailOnCircularInclude is set and unreadable includes if
+// Configs.IncludeErrors is IncludeErrorFail. Other failures only make
+// the scope unavailable.
+//
+// Example:
+//
+//	cfg := New()
+//	cfg.FailOnPermissionDenied = true
+//	cfg.LoadAll(".")
+//	if err := cfg.LoadReport().Err(); err != nil {
+//		log.Fatal(err)
+//	}
+func (r LoadReport) Err() error {
+	var errs []error
+	for _, sr := range r.Scopes {
+		if sr.Fatal && sr.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sr.Scope, sr.err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Scope returns the report for the named scope, if any.
+func (r LoadReport) Scope(name string) (ScopeReport, bool) {
+	for _, sr := range r.Scopes {
+		if strings.EqualFold(sr.Scope, name) {
+			return sr, true
+		}
+	}
+
+	return ScopeReport{}, false
+}
+
+// String implements fmt.Stringer and renders one line per scope, followed
+// by any parse issues.
+func (r LoadReport) String() string {
+	var sb strings.Builder
+
+	for _, sr := range r.Scopes {
+		status := "missing"
+		switch {
+		case sr.Skipped:
+			status = "skipped"
+		case sr.Error != "":
+			status = "error: " + sr.Error
+		case sr.Found:
+			status = "loaded"
+		}
+
+		fmt.Fprintf(&sb, "%s: %s", sr.Scope, status)
+		if sr.Path != "" {
+			fmt.Fprintf(&sb, " (%s)", sr.Path)
+		}
+		if sr.Includes > 0 {
+			fmt.Fprintf(&sb, ", %d includes", sr.Includes)
+		}
+		if sr.IncludeLimitReached {
+			sb.WriteString(" (limit reached)")
+		}
+		if sr.ReadOnly {
+			sb.WriteString(", read-only")
+		}
+		sb.WriteString("\n")
+
+		for _, issue := range sr.Issues {
+			fmt.Fprintf(&sb, "  %s\n", issue)
+		}
+	}
+
+	return sb.String()
+}
+
+// add records the result of loading a scope from the given locations.
+func (r *LoadReport) add(scope string, attempted []string, c *Config, err error) {
+	sr := ScopeReport{
+		Scope:     scope,
+		Attempted: attempted,
+		Found:     err == nil && c != nil,
+	}
+	if len(attempted) > 0 {
+		sr.Path = attempted[0]
+	}
+	if err != nil && (!errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrIncludeUnreadable)) {
+		sr.Error = err.Error()
+		sr.err = err
+	}
+	sr.Failure = classifyLoadError(err, c)
+	if c != nil {
+		if c.path != "" {
+			sr.Path = c.path
+		}
+		sr.Includes = len(c.includes)
+		sr.IncludeLimitReached = c.includeLimitReached
+		for _, s := range c.skippedIncludes {
+			sr.SkippedIncludes = append(sr.SkippedIncludes, s.Path)
+		}
+		for _, issue := range c.issues {
+			sr.Issues = append(sr.Issues, issue.String())
+		}
+	}
+
+	r.Scopes = append(r.Scopes, sr)
+}
+
+// classifyLoadError returns why a scope could not be loaded, if at all.
+func classifyLoadError(err error, c *Config) LoadFailure {
+	switch {
+	case err == nil && c != nil:
+		return ""
+	case errors.Is(err, ErrIncludeUnreadable):
+		// a missing or unreadable include does not make the scope missing
+		return LoadFailureOther
+	case err == nil, errors.Is(err, fs.ErrNotExist):
+		return LoadFailureMissing
+	case errors.Is(err, fs.ErrPermission):
+		return LoadFailurePermission
+	case errors.Is(err, ErrParse), errors.Is(err, ErrNotAConfigFile):
+		return LoadFailureParse
+	default:
+		return LoadFailureOther
+	}
+}
+
+// finish fills in the details that are only known after all scopes have
+// been loaded, e.g. the final path and write status of each scope, and
+// which failures are fatal.
+func (r *LoadReport) finish(cs *Configs) {
+	for i := range r.Scopes {
+		switch r.Scopes[i].Failure {
+		case LoadFailureParse:
+			r.Scopes[i].Fatal = cs.Strict
+		case LoadFailurePermission:
+			r.Scopes[i].Fatal = cs.FailOnPermissionDenied
+		default:
+			// only returned if FailOnCircularInclude is set or
+			// IncludeErrors is IncludeErrorFail
+			r.Scopes[i].Fatal = errors.Is(r.Scopes[i].err, ErrCircularInclude) ||
+				errors.Is(r.Scopes[i].err, ErrIncludeUnreadable)
+		}
+	}
+
+	for _, sc := range cs.namedScopes() {
+		if sc.cfg == nil {
+			continue
+		}
+		for i := range r.Scopes {
+			if r.Scopes[i].Scope != sc.name {
+				continue
+			}
+			if sc.cfg.path != "" {
+				r.Scopes[i].Path = sc.cfg.path
+			}
+			r.Scopes[i].ReadOnly = sc.cfg.readonly || sc.cfg.noWrites
+		}
+	}
+}
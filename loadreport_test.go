@@ -0,0 +1,302 @@
+package gitconfig
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadReport(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	c := New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.GlobalConfig = "global"
+	c.LocalConfig = "local"
+	c.WorktreeConfig = "worktree"
+	c.EnvPrefix = "GPTEST_CONFIG"
+
+	require.NoError(t, os.WriteFile(c.SystemConfig, []byte("[system]\n\tkey = system\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "local"), []byte(`[local]
+	key = local
+	1nvalid = x
+[include]
+	path = inc.config
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "inc.config"), []byte("[inc]\n\tkey = inc\n[]\n"), 0o600))
+	t.Setenv("GPTEST_CONFIG_COUNT", "1")
+	t.Setenv("GPTEST_CONFIG_KEY_0", "env.key")
+	t.Setenv("GPTEST_CONFIG_VALUE_0", "env")
+
+	c.LoadAll(td)
+	r := c.LoadReport()
+
+	require.Len(t, r.Scopes, 5)
+
+	sys, ok := r.Scope("system")
+	require.True(t, ok)
+	assert.True(t, sys.Found)
+	assert.True(t, sys.ReadOnly)
+	assert.Equal(t, c.SystemConfig, sys.Path)
+
+	global, ok := r.Scope("global")
+	require.True(t, ok)
+	assert.False(t, global.Found)
+	assert.Len(t, global.Attempted, 2)
+	assert.Empty(t, global.Error)
+
+	local, ok := r.Scope("local")
+	require.True(t, ok)
+	assert.True(t, local.Found)
+	assert.False(t, local.ReadOnly)
+	assert.Equal(t, 1, local.Includes)
+	assert.Equal(t, []string{
+		filepath.Join(td, "local") + `:3: invalid key "1nvalid"`,
+		filepath.Join(td, "inc.config") + ":3: empty section header",
+	}, local.Issues)
+
+	worktree, ok := r.Scope("worktree")
+	require.True(t, ok)
+	assert.False(t, worktree.Found)
+	assert.Equal(t, filepath.Join(td, "worktree"), worktree.Path)
+
+	env, ok := r.Scope("env")
+	require.True(t, ok)
+	assert.True(t, env.Found)
+	assert.True(t, env.ReadOnly)
+
+	_, ok = r.Scope("unknown")
+	assert.False(t, ok)
+
+	assert.Contains(t, r.String(), "local: loaded ("+filepath.Join(td, "local")+"), 1 includes\n")
+	assert.Contains(t, r.String(), "worktree: missing")
+
+	// system can be disabled
+	t.Setenv("GPTEST_CONFIG_NOSYSTEM", "true")
+	c.Reload()
+	sys, ok = c.LoadReport().Scope("system")
+	require.True(t, ok)
+	assert.True(t, sys.Skipped)
+	assert.Contains(t, c.LoadReport().String(), "system: skipped")
+}
+
+func TestLoadReportIncludeLimit(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	fn := filepath.Join(td, "local")
+	require.NoError(t, os.WriteFile(fn, []byte("[include]\n\tpath = a.config\n\tpath = b.config\n\tpath = c.config\n"), 0o600))
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, os.WriteFile(filepath.Join(td, name+".config"), []byte("[inc]\n\tkey = "+name+"\n"), 0o600))
+	}
+
+	c := New()
+	c.SystemConfig = ""
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_CONFIG"
+	c.MaxIncludes = 2
+	c.LoadAll(td)
+
+	assert.Equal(t, []string{"a", "b"}, c.GetAll("inc.key"))
+
+	local, ok := c.LoadReport().Scope("local")
+	require.True(t, ok)
+	assert.Equal(t, 2, local.Includes)
+	assert.True(t, local.IncludeLimitReached)
+	require.Len(t, local.Issues, 1)
+	assert.Contains(t, local.Issues[0], "include limit of 2 files reached")
+	assert.Contains(t, c.LoadReport().String(), "local: loaded ("+fn+"), 2 includes (limit reached)")
+
+	// the default is far above
+	c.MaxIncludes = 0
+	c.Reload()
+	assert.Equal(t, []string{"a", "b", "c"}, c.GetAll("inc.key"))
+
+	// a negative value disables the limit
+	c.MaxIncludes = -1
+	c.Reload()
+	assert.Equal(t, []string{"a", "b", "c"}, c.GetAll("inc.key"))
+}
+
+func TestLoadReportCircularInclude(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	// local -> a -> b -> a, and a diamond local -> c, a -> c which is no cycle
+	fn := filepath.Join(td, "local")
+	require.NoError(t, os.WriteFile(fn, []byte("[include]\n\tpath = a.config\n\tpath = c.config\n[inc]\n\tkey = local\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "a.config"), []byte("[include]\n\tpath = b.config\n\tpath = c.config\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "b.config"), []byte("[include]\n\tpath = a.config\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "c.config"), []byte("[inc]\n\tkey = c\n"), 0o600))
+
+	c := New()
+	c.SystemConfig = ""
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_CONFIG"
+	c.LoadAll(td)
+
+	// skipped by default
+	assert.Equal(t, []string{"local", "c"}, c.GetAll("inc.key"))
+	require.NoError(t, c.LoadReport().Err())
+
+	c = New()
+	c.SystemConfig = ""
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_CONFIG"
+	c.FailOnCircularInclude = true
+	c.LoadAll(td)
+	assert.Empty(t, c.GetAll("inc.key"))
+
+	err := c.LoadReport().Err()
+	require.ErrorIs(t, err, ErrCircularInclude)
+	var cerr *CircularIncludeError
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, []string{filepath.Join(td, "a.config"), filepath.Join(td, "b.config"), filepath.Join(td, "a.config")}, cerr.Cycle)
+	assert.Contains(t, err.Error(), "a.config -> "+filepath.Join(td, "b.config")+" -> ")
+
+	local, ok := c.LoadReport().Scope("local")
+	require.True(t, ok)
+	assert.Equal(t, LoadFailureOther, local.Failure)
+	assert.True(t, local.Fatal)
+	assert.True(t, local.ReadOnly)
+
+	// the file is not overwritten
+	before, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	require.NoError(t, c.SetLocal("inc.key", "changed"))
+	after, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, string(before), string(after))
+}
+
+func TestLoadReportIncludeErrors(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	fn := filepath.Join(td, "local")
+	require.NoError(t, os.WriteFile(fn, []byte("[include]\n\tpath = missing.config\n[inc]\n\tkey = local\n"), 0o600))
+
+	c := New()
+	c.SystemConfig = ""
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_CONFIG"
+	c.IncludeErrors = IncludeErrorWarn
+	c.LoadAll(td)
+
+	// skipped with a warning
+	assert.Equal(t, "local", c.Get("inc.key"))
+	require.NoError(t, c.LoadReport().Err())
+	local, ok := c.LoadReport().Scope("local")
+	require.True(t, ok)
+	assert.Equal(t, []string{filepath.Join(td, "missing.config")}, local.SkippedIncludes)
+	require.Len(t, local.Issues, 1)
+	assert.Contains(t, local.Issues[0], "missing.config")
+
+	c = New()
+	c.SystemConfig = ""
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_CONFIG"
+	c.IncludeErrors = IncludeErrorFail
+	c.LoadAll(td)
+	assert.Empty(t, c.Get("inc.key"))
+
+	err := c.LoadReport().Err()
+	require.ErrorIs(t, err, ErrIncludeUnreadable)
+	local, ok = c.LoadReport().Scope("local")
+	require.True(t, ok)
+	assert.Equal(t, LoadFailureOther, local.Failure)
+	assert.True(t, local.Fatal)
+	assert.True(t, local.ReadOnly)
+
+	// the file is not overwritten
+	require.NoError(t, c.SetLocal("inc.key", "changed"))
+	buf, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, "[include]\n\tpath = missing.config\n[inc]\n\tkey = local\n", string(buf))
+}
+
+func TestClassifyLoadError(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{}
+	for _, tc := range []struct {
+		err  error
+		c    *Config
+		want LoadFailure
+	}{
+		{nil, c, ""},
+		{nil, nil, LoadFailureMissing},
+		{&fs.PathError{Op: "open", Path: "config", Err: fs.ErrNotExist}, nil, LoadFailureMissing},
+		{&fs.PathError{Op: "open", Path: "config", Err: fs.ErrPermission}, nil, LoadFailurePermission},
+		{&ParseError{Line: 1}, nil, LoadFailureParse},
+		{fmt.Errorf("config: %w", ErrNotAConfigFile), nil, LoadFailureParse},
+		{errors.New("boom"), nil, LoadFailureOther},
+	} {
+		assert.Equal(t, tc.want, classifyLoadError(tc.err, tc.c), tc.err)
+	}
+}
+
+func TestLoadReportErr(t *testing.T) {
+	t.Parallel()
+
+	denied := &fs.PathError{Op: "open", Path: "/etc/gitconfig", Err: fs.ErrPermission}
+	broken := &ParseError{Path: "/repo/.git/config", Line: 2, Reason: "invalid key"}
+
+	var r LoadReport
+	r.add(ScopeSystem, []string{"/etc/gitconfig"}, nil, denied)
+	r.add(ScopeLocal, []string{"/repo/.git/config"}, nil, broken)
+	r.add(ScopeGlobal, []string{"/home/user/.gitconfig"}, nil, fs.ErrNotExist)
+
+	// nothing is fatal by default
+	cs := New()
+	r.finish(cs)
+	require.NoError(t, r.Err())
+	sr, _ := r.Scope(ScopeSystem)
+	assert.Equal(t, LoadFailurePermission, sr.Failure)
+	sr, _ = r.Scope(ScopeGlobal)
+	assert.Equal(t, LoadFailureMissing, sr.Failure)
+	assert.Empty(t, sr.Error)
+
+	cs.FailOnPermissionDenied = true
+	r.finish(cs)
+	err := r.Err()
+	require.ErrorIs(t, err, fs.ErrPermission)
+	assert.NotErrorIs(t, err, ErrParse)
+	assert.Contains(t, err.Error(), "system: open /etc/gitconfig")
+
+	cs.Strict = true
+	r.finish(cs)
+	require.ErrorIs(t, r.Err(), ErrParse)
+}
+
+func TestLoadReportPermissionDenied(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("Permission test not reliable on Windows or as root")
+	}
+
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	system := filepath.Join(td, "system")
+	require.NoError(t, os.WriteFile(system, []byte("[core]\n\teditor = vim\n"), 0o000))
+
+	c := New()
+	c.SystemConfig = system
+	c.EnvPrefix = "GPTEST_PERMISSION_CONFIG"
+	c.FailOnPermissionDenied = true
+	c.LoadAll("")
+
+	sr, ok := c.LoadReport().Scope(ScopeSystem)
+	require.True(t, ok)
+	assert.Equal(t, LoadFailurePermission, sr.Failure)
+	assert.True(t, sr.Fatal)
+	require.ErrorIs(t, c.LoadReport().Err(), fs.ErrPermission)
+}
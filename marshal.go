@@ -0,0 +1,200 @@
+package gitconfig
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Marshal creates a new in-memory Config from the tagged fields of the struct
+// v (or pointer to it). See Config.ApplyStruct for the mapping rules.
+//
+// Example:
+//
+//	c, err := gitconfig.Marshal(settings)
+//	editor, _ := c.Get("core.editor")
+func Marshal(v any) (*Config, error) {
+	c := &Config{
+		noWrites: true,
+		vars:     make(map[string][]string, 16),
+	}
+
+	if err := c.ApplyStruct(v); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ApplyStruct writes the tagged fields of the struct v (or pointer to it)
+// into the config, using the same `gitconfig` tags as Unmarshal. Unrelated
+// keys, comments and formatting are preserved and the file is written
+// only once.
+//
+// Values are written in git-canonical form: bools as true/false, integers
+// in decimal and durations in Go syntax (e.g. "1m30s"). Slices replace all
+// values of a multivar; an empty slice removes the key. Fields tagged with
+// the omitempty option (e.g. `gitconfig:"core.editor,omitempty"`) are
+// skipped if they hold the zero value.
+//
+// The error wraps ErrUnsupportedType if v or one of the tagged fields can
+// not be mapped. Nothing is changed in that case.
+func (c *Config) ApplyStruct(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: ApplyStruct needs a struct, got %T", ErrUnsupportedType, v)
+	}
+
+	values := make(map[string][]string, 16)
+	order := make([]string, 0, 16)
+	if err := marshalStruct(rv, "", c.keys, values, &order); err != nil {
+		return err
+	}
+
+	return c.transaction(func() error {
+		for _, k := range order {
+			if err := c.replaceAll(k, values[k]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func marshalStruct(rv reflect.Value, prefix string, keys KeyRules, values map[string][]string, order *[]string) error {
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		tag, omitEmpty := parseTag(sf.Tag.Get(tagName))
+		if tag == "" || tag == "-" || !sf.IsExported() {
+			continue
+		}
+
+		key := keys.Canonical(joinKey(prefix, tag))
+		fv := rv.Field(i)
+
+		if sf.Type.Kind() == reflect.Struct {
+			if err := marshalStruct(fv, joinKey(prefix, tag), keys, values, order); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if key == "" {
+			return fmt.Errorf("%w: %s", ErrInvalidKey, joinKey(prefix, tag))
+		}
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		var vs []string
+		if sf.Type.Kind() == reflect.Slice {
+			if omitEmpty && fv.Len() == 0 {
+				continue
+			}
+			vs = make([]string, 0, fv.Len())
+			for j := range fv.Len() {
+				s, err := formatValue(fv.Index(j), key)
+				if err != nil {
+					return err
+				}
+				vs = append(vs, s)
+			}
+		} else {
+			s, err := formatValue(fv, key)
+			if err != nil {
+				return err
+			}
+			vs = []string{s}
+		}
+
+		if _, found := values[key]; !found {
+			*order = append(*order, key)
+		}
+		values[key] = vs
+	}
+
+	return nil
+}
+
+// formatValue serializes a field value in git-canonical form.
+func formatValue(fv reflect.Value, key string) (string, error) {
+	if fv.Type() == durationType {
+		return time.Duration(fv.Int()).String(), nil
+	}
+
+	switch fv.Kind() { //nolint:exhaustive
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	default:
+		return "", fmt.Errorf("%s: %w: %s", key, ErrUnsupportedType, fv.Type())
+	}
+}
+
+// replaceAll replaces all values of the key with the given values,
+// keeping the position of the key if it is already present.
+func (c *Config) replaceAll(key string, values []string) error {
+	if current, found := c.GetAll(key); found && slices.Equal(current, values) {
+		return nil
+	}
+
+	if err := validateKey(key, c.keys); err != nil {
+		return err
+	}
+	if err := c.keys.checkKeyLimits(key); err != nil {
+		return err
+	}
+	if err := c.keys.checkValuesLimit(key, len(values)); err != nil {
+		return err
+	}
+	for _, v := range values {
+		if err := validateValue(v, c.unescapeValues()); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	if len(values) == 1 && c.CountValues(key) <= 1 {
+		return c.Set(key, values[0])
+	}
+	spelling := key
+	key = c.canonicalKey(key)
+
+	if err := c.Unset(key); err != nil {
+		return err
+	}
+	if len(values) == 0 || c.readonly {
+		return nil
+	}
+
+	// insertValue adds new values right after the section header,
+	// so insert them in reverse to keep their order.
+	for i := len(values) - 1; i >= 0; i-- {
+		if err := c.insertValue(spelling, values[i], false); err != nil {
+			return err
+		}
+	}
+	if c.vars == nil {
+		c.vars = make(map[string][]string, 16)
+	}
+	c.vars[key] = slices.Clone(values)
+	c.resetCoercions()
+	c.setOrigin(key, valueOrigin{path: c.path, raw: c.writtenRaw(values[0], false)})
+	for _, v := range values[1:] {
+		c.origins[key] = append(c.origins[key], valueOrigin{path: c.path, raw: c.writtenRaw(v, false)})
+	}
+
+	return nil
+}
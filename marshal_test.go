@@ -0,0 +1,106 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type marshalTestSettings struct {
+	Core struct {
+		Editor string `gitconfig:"editor"`
+		Bare   bool   `gitconfig:"bare"`
+		Pager  string `gitconfig:"pager,omitempty"`
+	} `gitconfig:"core"`
+	Origin struct {
+		Fetch []string `gitconfig:"fetch"`
+	} `gitconfig:"remote.origin"`
+	Timeout time.Duration `gitconfig:"gopass.timeout"`
+	Depth   uint          `gitconfig:"gopass.depth"`
+	Ignored string
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+
+	var s marshalTestSettings
+	s.Core.Editor = "vim"
+	s.Origin.Fetch = []string{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"}
+	s.Timeout = 90 * time.Second
+	s.Depth = 3
+
+	c, err := Marshal(s)
+	require.NoError(t, err)
+
+	v, _ := c.Get("core.editor")
+	assert.Equal(t, "vim", v)
+	v, _ = c.Get("core.bare")
+	assert.Equal(t, "false", v)
+	assert.False(t, c.IsSet("core.pager"))
+	vs, _ := c.GetAll("remote.origin.fetch")
+	assert.Equal(t, s.Origin.Fetch, vs)
+	v, _ = c.Get("gopass.timeout")
+	assert.Equal(t, "1m30s", v)
+
+	// round trip
+	var out marshalTestSettings
+	require.NoError(t, c.Unmarshal(&out))
+	assert.Equal(t, s, out)
+
+	_, err = Marshal("foo")
+	require.ErrorIs(t, err, ErrUnsupportedType)
+	_, err = Marshal(struct {
+		Ratio float64 `gitconfig:"core.ratio"`
+	}{})
+	require.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestApplyStruct(t *testing.T) {
+	t.Parallel()
+
+	fn := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(fn, []byte(`# managed by hand
+[core]
+	editor = nano
+	autocrlf = input
+[remote "origin"]
+	url = https://example.com/repo.git
+	fetch = +refs/heads/main:refs/remotes/origin/main
+`), 0o600))
+
+	c, err := LoadConfig(fn)
+	require.NoError(t, err)
+
+	var s marshalTestSettings
+	s.Core.Editor = "vim"
+	s.Core.Bare = true
+	s.Origin.Fetch = []string{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"}
+	s.Timeout = time.Minute
+	require.NoError(t, c.ApplyStruct(&s))
+
+	buf, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, `# managed by hand
+[core]
+	bare = true
+	editor = vim
+	autocrlf = input
+[remote "origin"]
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	fetch = +refs/tags/*:refs/tags/*
+	url = https://example.com/repo.git
+[gopass]
+	depth = 0
+	timeout = 1m0s
+`, string(buf))
+
+	reloaded, err := LoadConfig(fn)
+	require.NoError(t, err)
+	var out marshalTestSettings
+	require.NoError(t, reloaded.Unmarshal(&out))
+	assert.Equal(t, s, out)
+}
@@ -0,0 +1,91 @@
+package gitconfig
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"unsafe"
+)
+
+// LoadConfigMapped loads a gitconfig from the given path by mapping the file
+// into memory instead of reading it line by line.
+//
+// This is intended for very large, machine-generated configs. The lines are
+// parsed directly from the mapping and only the resulting keys and values are
+// copied. The raw representation is not retained, so the returned config is
+// read-only: Set and Unset are silently ignored. Includes are not processed.
+//
+// On platforms without mmap support the file is read into memory instead.
+func LoadConfigMapped(fn string) (*Config, error) {
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() //nolint:errcheck
+
+	data, unmap, err := mapFile(fh)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := unmap(); err != nil {
+			debug.V(1).Log("failed to unmap %s: %s", fn, err)
+		}
+	}()
+
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return nil, fmt.Errorf("%s: %w: NUL byte in line %d", fn, ErrNotAConfigFile, bytes.Count(data[:i], []byte{'\n'})+1)
+	}
+
+	c := &Config{
+		path:     fn,
+		readonly: true,
+		vars:     make(map[string][]string, 42),
+		origins:  make(map[string][]valueOrigin, 42),
+	}
+
+	t := newTokenizer(c.unescapeValues(), func(l docLine) {
+		if l.kind != lineKeyValue {
+			return
+		}
+		// the line views point into the mapping, so anything we keep
+		// must be copied before the mapping is released.
+		fk := strings.Clone(l.key)
+		c.vars[fk] = append(c.vars[fk], strings.Clone(l.value))
+		c.origins[fk] = append(c.origins[fk], valueOrigin{path: fn, line: l.line, bare: l.bare, raw: strings.Clone(rawIfDifferent(l.rawValue(), l.value)), comment: strings.Clone(l.commentText())})
+	})
+
+	for len(data) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			line, data = data, nil
+		}
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+
+		t.feed(bytesView(line))
+	}
+	t.flush()
+
+	// the offending lines point into the mapping, too
+	c.issues = make([]parseIssue, 0, len(t.issues))
+	for _, pi := range t.issues {
+		c.issues = append(c.issues, parseIssue{path: fn, line: pi.line, msg: strings.Clone(pi.msg), text: strings.Clone(pi.text)})
+	}
+
+	debug.V(3).Log("processed mapped config %s: %d keys", fn, len(c.vars))
+
+	return c, nil
+}
+
+// bytesView returns a string referencing the given bytes without copying them.
+// The result must not be retained after the underlying memory is released.
+func bytesView(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+
+	return unsafe.String(&b[0], len(b))
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package gitconfig
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile falls back to reading the whole file on platforms without
+// mmap support.
+func mapFile(fh *os.File) ([]byte, func() error, error) {
+	data, err := io.ReadAll(fh)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() error { return nil }, nil
+}
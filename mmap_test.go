@@ -0,0 +1,97 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigMapped(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	fn := filepath.Join(td, "config")
+	require.NoError(t, os.WriteFile(fn, []byte(`# comment
+[core]
+	editor = vim
+	pager = "less -R" # trailing
+[remote "origin"]
+	url = https://example.com/repo.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	fetch = +refs/tags/*:refs/tags/*
+[url "git@example.com:"]
+	insteadOf = https://example.com/`), 0o600))
+
+	cfg, err := LoadConfigMapped(fn)
+	require.NoError(t, err)
+
+	v, ok := cfg.Get("core.editor")
+	assert.True(t, ok)
+	assert.Equal(t, "vim", v)
+
+	v, ok = cfg.Get("core.pager")
+	assert.True(t, ok)
+	assert.Equal(t, "less -R", v)
+
+	vs, ok := cfg.GetAll("remote.origin.fetch")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"}, vs)
+
+	v, ok = cfg.Get("url.git@example.com:.insteadof")
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com/", v)
+
+	assert.False(t, cfg.IsEmpty())
+	assert.Empty(t, cfg.Warnings())
+
+	// mapped configs are read-only
+	require.NoError(t, cfg.Set("core.editor", "nano"))
+	v, _ = cfg.Get("core.editor")
+	assert.Equal(t, "vim", v)
+}
+
+func TestLoadConfigMappedWarnings(t *testing.T) {
+	t.Parallel()
+
+	fn := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(fn, []byte("[core]\n\teditor = vim\n\tpager = \"less\\q\"\n"), 0o600))
+
+	want, err := LoadConfig(fn)
+	require.NoError(t, err)
+	require.NotEmpty(t, want.Warnings())
+
+	cfg, err := LoadConfigMapped(fn)
+	require.NoError(t, err)
+	assert.Equal(t, want.Warnings(), cfg.Warnings())
+}
+
+func TestLoadConfigMappedEmpty(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	fn := filepath.Join(td, "config")
+	require.NoError(t, os.WriteFile(fn, nil, 0o600))
+
+	cfg, err := LoadConfigMapped(fn)
+	require.NoError(t, err)
+	assert.False(t, cfg.IsSet("core.editor"))
+
+	_, err = LoadConfigMapped(filepath.Join(td, "missing"))
+	require.Error(t, err)
+}
+
+func TestLoadConfigMappedBOM(t *testing.T) {
+	t.Parallel()
+
+	fn := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(fn, []byte("\ufeff[core]\n\teditor = vim\n"), 0o600))
+
+	c, err := LoadConfigMapped(fn)
+	require.NoError(t, err)
+	v, ok := c.Get("core.editor")
+	assert.True(t, ok)
+	assert.Equal(t, "vim", v)
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package gitconfig
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the given file read-only into memory. The returned function
+// must be called to release the mapping once the data is no longer used.
+func mapFile(fh *os.File) ([]byte, func() error, error) {
+	fi, err := fh.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if fi.Size() == 0 {
+		return nil, func() error { return nil }, nil
+	}
+
+	data, err := syscall.Mmap(int(fh.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
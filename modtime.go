@@ -0,0 +1,183 @@
+package gitconfig
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"os"
+	"slices"
+	"time"
+)
+
+// LastLoaded returns when the scope was last loaded by LoadAll or Reload.
+// It returns false for unknown scopes, scopes whose file could not be
+// loaded and the preset scope, which is never loaded from a file.
+//
+// Example:
+//
+//	if t, ok := cfg.LastLoaded(gitconfig.ScopeGlobal); ok {
+//		fmt.Printf("global config loaded at %s\n", t.Format(time.RFC3339))
+//	}
+func (cs *Configs) LastLoaded(scope string) (time.Time, bool) {
+	c := cs.scopeConfig(scope)
+	if c == nil || c.loadedAt.IsZero() {
+		return time.Time{}, false
+	}
+
+	return c.loadedAt, true
+}
+
+// FileModTime returns the current modification time of the file backing the
+// scope. If the file includes other files, the newest modification time of
+// all of them is returned. It returns false if the scope is not backed by a
+// file or the file does not exist (anymore).
+//
+// Together with LastLoaded it allows to implement custom staleness policies:
+//
+//	mod, _ := cfg.FileModTime(gitconfig.ScopeLocal)
+//	if loaded, ok := cfg.LastLoaded(gitconfig.ScopeLocal); ok && mod.After(loaded) {
+//		cfg.Reload()
+//	}
+func (cs *Configs) FileModTime(scope string) (time.Time, bool) {
+	c := cs.scopeConfig(scope)
+	if c == nil || c.path == "" {
+		return time.Time{}, false
+	}
+
+	fi, err := os.Stat(c.path)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	latest := fi.ModTime()
+	for _, p := range c.includes {
+		if fi, err := os.Stat(p); err == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+
+	return latest, true
+}
+
+// fileStamp is what ChangedSinceLoad compares to detect a change of a file.
+type fileStamp struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// statFile returns the current stamp of the file.
+func statFile(fn string) fileStamp {
+	fi, err := os.Stat(fn)
+	if err != nil {
+		return fileStamp{}
+	}
+
+	return fileStamp{exists: true, size: fi.Size(), modTime: fi.ModTime()}
+}
+
+// stampFiles records the stamps of the file of the config, its includes and
+// any other candidate locations the config could be loaded from.
+func (c *Config) stampFiles(candidates ...string) {
+	if c == nil || c.path == "" {
+		return
+	}
+
+	c.stamps = make(map[string]fileStamp, 1+len(c.includes)+len(candidates))
+	for _, fn := range append(append([]string{c.path}, c.includes...), candidates...) {
+		if fn != "" {
+			c.stamps[fn] = statFile(fn)
+		}
+	}
+}
+
+// ChangedSinceLoad reports whether any of the files read by the last LoadAll
+// or Reload, including the included ones, was changed, created or removed
+// since then. It also returns the changed scopes, in order of priority.
+// Changes made through this Configs are not reported.
+//
+// Unlike Subscribe this does not reload anything, it only compares the size
+// and modification time of the files, so it is cheap enough to call before a
+// CLI exits:
+//
+//	if changed, scopes := cfg.ChangedSinceLoad(); changed {
+//		fmt.Printf("config (%s) changed since start, rerun to pick up changes\n", strings.Join(scopes, ", "))
+//	}
+func (cs *Configs) ChangedSinceLoad() (bool, []string) {
+	var changed []string
+	for _, s := range cs.namedScopes() {
+		if s.cfg == nil || s.cfg.stamps == nil {
+			continue
+		}
+		for fn, st := range s.cfg.stamps {
+			if cur := statFile(fn); cur.exists != st.exists || cur.size != st.size || !cur.modTime.Equal(st.modTime) {
+				debug.V(2).Log("[%s] %s config %s changed since load", cs.Name, s.name, fn)
+				changed = append(changed, s.name)
+
+				break
+			}
+		}
+	}
+
+	return len(changed) > 0, changed
+}
+
+// Fingerprint returns a hash over the effective configuration, i.e. every
+// key with the values GetAll returns for it, and over the size and
+// modification time of the files it was loaded from, including the
+// included ones. It is stable across runs, so applications can store it to
+// cheaply detect that the configuration changed and invalidate caches
+// derived from it:
+//
+//	if fp := cfg.Fingerprint(); fp != cached.Fingerprint {
+//		rebuild(cfg)
+//	}
+//
+// Touching a file changes the fingerprint even if its content is the same.
+func (cs *Configs) Fingerprint() string {
+	h := sha256.New()
+
+	for _, k := range cs.Keys() {
+		writeField(h, k)
+		vs := cs.getAllExcept(k, nil)
+		writeInt(h, int64(len(vs)))
+		for _, v := range vs {
+			writeField(h, v)
+		}
+	}
+
+	files := make([]string, 0, 8)
+	for _, sc := range cs.namedScopes() {
+		if sc.cfg == nil || sc.cfg.path == "" {
+			continue
+		}
+		files = append(files, sc.cfg.path)
+		files = append(files, sc.cfg.includes...)
+	}
+	slices.Sort(files)
+	for _, fn := range slices.Compact(files) {
+		st := statFile(fn)
+		writeField(h, fn)
+		if !st.exists {
+			writeInt(h, -1)
+
+			continue
+		}
+		writeInt(h, st.size)
+		writeInt(h, st.modTime.UnixNano())
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeField writes s with its length, so different splits of the same
+// bytes into keys and values hash differently.
+func writeField(h hash.Hash, s string) {
+	writeInt(h, int64(len(s)))
+	_, _ = h.Write([]byte(s))
+}
+
+func writeInt(h hash.Hash, n int64) {
+	_, _ = h.Write(binary.BigEndian.AppendUint64(nil, uint64(n))) //nolint:gosec
+}
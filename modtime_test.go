@@ -0,0 +1,147 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastLoadedAndFileModTime(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	local := filepath.Join(td, "local")
+	inc := filepath.Join(td, "included")
+	require.NoError(t, os.WriteFile(local, []byte("[include]\n\tpath = included\n[core]\n\teditor = vim\n"), 0o600))
+	require.NoError(t, os.WriteFile(inc, []byte("[core]\n\tpager = less\n"), 0o600))
+
+	old := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	newer := old.Add(time.Hour)
+	require.NoError(t, os.Chtimes(local, old, old))
+	require.NoError(t, os.Chtimes(inc, newer, newer))
+
+	c := New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_MODTIME_CONFIG"
+
+	before := time.Now()
+	c.LoadAll(td)
+
+	loaded, ok := c.LastLoaded(ScopeLocal)
+	require.True(t, ok)
+	assert.False(t, loaded.Before(before))
+	_, ok = c.LastLoaded(ScopeEnv)
+	assert.True(t, ok)
+
+	// the newest of the file and its includes
+	mod, ok := c.FileModTime(ScopeLocal)
+	require.True(t, ok)
+	assert.True(t, newer.Equal(mod), mod)
+
+	for _, scope := range []string{ScopeSystem, ScopePreset, ScopeEnv, "unknown"} {
+		_, ok := c.FileModTime(scope)
+		assert.False(t, ok, scope)
+	}
+	for _, scope := range []string{ScopeSystem, ScopePreset, "unknown"} {
+		_, ok := c.LastLoaded(scope)
+		assert.False(t, ok, scope)
+	}
+
+	// a change is detected by comparing both
+	require.NoError(t, os.Chtimes(local, time.Now().Add(time.Hour), time.Now().Add(time.Hour)))
+	mod, _ = c.FileModTime(ScopeLocal)
+	assert.True(t, mod.After(loaded))
+
+	c.Reload()
+	reloaded, _ := c.LastLoaded(ScopeLocal)
+	assert.False(t, reloaded.Before(loaded))
+}
+
+func TestChangedSinceLoad(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	local := filepath.Join(td, "local")
+	inc := filepath.Join(td, "included")
+	require.NoError(t, os.WriteFile(local, []byte("[include]\n\tpath = included\n[core]\n\teditor = vim\n"), 0o600))
+	require.NoError(t, os.WriteFile(inc, []byte("[core]\n\tpager = less\n"), 0o600))
+
+	c := New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.GlobalConfig = "global"
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_CHANGED_CONFIG"
+	c.LoadAll(td)
+
+	changed, scopes := c.ChangedSinceLoad()
+	assert.False(t, changed)
+	assert.Empty(t, scopes)
+
+	// own writes are not a change
+	require.NoError(t, c.SetLocal("core.editor", "nano"))
+	changed, _ = c.ChangedSinceLoad()
+	assert.False(t, changed)
+
+	// changing an included file is
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(inc, later, later))
+	// and so is creating a file that did not exist
+	require.NoError(t, os.WriteFile(filepath.Join(td, "global"), []byte("[user]\n\tname = me\n"), 0o600))
+
+	changed, scopes = c.ChangedSinceLoad()
+	assert.True(t, changed)
+	assert.Equal(t, []string{ScopeLocal, ScopeGlobal}, scopes)
+
+	c.Reload()
+	changed, _ = c.ChangedSinceLoad()
+	assert.False(t, changed)
+
+	// removing a file is a change as well
+	require.NoError(t, os.Remove(local))
+	changed, scopes = c.ChangedSinceLoad()
+	assert.True(t, changed)
+	assert.Equal(t, []string{ScopeLocal}, scopes)
+}
+
+func TestFingerprint(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	local := filepath.Join(td, "local")
+	inc := filepath.Join(td, "included")
+	require.NoError(t, os.WriteFile(local, []byte("[include]\n\tpath = included\n[core]\n\teditor = vim\n"), 0o600))
+	require.NoError(t, os.WriteFile(inc, []byte("[core]\n\tpager = less\n"), 0o600))
+
+	load := func() *Configs {
+		c := New()
+		c.SystemConfig = ""
+		c.LocalConfig = "local"
+		c.EnvPrefix = "GPTEST_FINGERPRINT_CONFIG"
+		c.NoWrites = true
+		c.LoadAll(td)
+
+		return c
+	}
+
+	c := load()
+	fp := c.Fingerprint()
+	assert.Len(t, fp, 64)
+	assert.Equal(t, fp, c.Fingerprint())
+
+	// stable across loads
+	assert.Equal(t, fp, load().Fingerprint())
+
+	// values changed in memory
+	require.NoError(t, c.SetLocal("core.editor", "nano"))
+	assert.NotEqual(t, fp, c.Fingerprint())
+
+	// and touched files change it
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(inc, later, later))
+	assert.NotEqual(t, fp, load().Fingerprint())
+}
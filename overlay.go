@@ -0,0 +1,51 @@
+package gitconfig
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// LoadConfigFromReader reads a config from r into the overlay scope, which
+// takes precedence over all other scopes. The overlay is read-only and
+// includes are not processed, since relative include paths have no base.
+// It is kept by Reload and replaced by the next call, subscribers are
+// notified about the changes (see Subscribe). In strict mode (see
+// Configs.Strict) a config with syntax errors is rejected and the previous
+// overlay is kept.
+//
+// Example:
+//
+//	cfg := gitconfig.New()
+//	cfg.LoadAll(".")
+//	if err := cfg.LoadConfigFromReader(strings.NewReader("[core]\n\teditor = vim\n")); err != nil { ... }
+func (cs *Configs) LoadConfigFromReader(r io.Reader) error {
+	c, err := parseConfig(r, cs.parseOptions())
+	if err != nil {
+		return fmt.Errorf("failed to read overlay config: %w", err)
+	}
+	if cs.Strict {
+		if err := c.parseError(); err != nil {
+			return err
+		}
+	}
+	c.readonly = true
+	c.noWrites = true
+
+	cs.loadMu.Lock()
+	defer cs.loadMu.Unlock()
+
+	return cs.notifying(func() error {
+		cs.overlay = c
+		debug.V(1).Log("[%s] loaded overlay config with %d keys", cs.Name, len(c.vars))
+
+		return nil
+	})
+}
+
+// LoadConfigFromStdin reads the overlay scope from standard input, see
+// LoadConfigFromReader. It allows pipelines like
+// `generate-config | mytool --config-stdin` without temporary files.
+func (cs *Configs) LoadConfigFromStdin() error {
+	return cs.LoadConfigFromReader(os.Stdin)
+}
@@ -0,0 +1,49 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigFromReader(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	require.NoError(t, os.WriteFile(filepath.Join(td, "local"), []byte("[core]\n\teditor = vim\n\tpager = less\n"), 0o600))
+
+	c := New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_OVERLAY_CONFIG"
+	c.LoadAll(td)
+
+	var changes []Change
+	c.Subscribe("core.", func(cs []Change) { changes = append(changes, cs...) })
+
+	require.NoError(t, c.LoadConfigFromReader(strings.NewReader("[include]\n\tpath = local\n[core]\n\teditor = nano\n")))
+	assert.Equal(t, "nano", c.Get("core.editor"))
+	assert.Equal(t, "less", c.Get("core.pager"))
+	assert.Equal(t, []Change{{Kind: ChangeUpdated, Key: "core.editor", Before: "vim", After: "nano"}}, changes)
+
+	// includes are not processed
+	v, ok := c.GetFrom("include.path", ScopeOverlay)
+	assert.True(t, ok)
+	assert.Equal(t, "local", v)
+
+	// the overlay is read-only and survives a reload
+	require.NoError(t, c.scopeConfig(ScopeOverlay).Set("core.editor", "ed"))
+	c.Reload()
+	assert.Equal(t, "nano", c.Get("core.editor"))
+
+	// strict mode rejects invalid input and keeps the overlay
+	c.Strict = true
+	require.ErrorIs(t, c.LoadConfigFromReader(strings.NewReader("[core]\n\t1nvalid = x\n")), ErrParse)
+	assert.Equal(t, "nano", c.Get("core.editor"))
+
+	require.ErrorIs(t, c.LoadConfigFromReader(strings.NewReader("\x00")), ErrNotAConfigFile)
+}
@@ -0,0 +1,121 @@
+package gitconfig
+
+import (
+	"maps"
+	"slices"
+)
+
+// KeyInfo describes a key known to an application.
+//
+// Fields:
+// - Key: The key, e.g. "core.autosync"
+// - Default: The default value, used if no scope sets the key
+// - Description: A short, human readable description
+// - Type: The type of the value (see ValueType), empty for strings
+// - Deprecated: If the key should no longer be used
+// - Replacement: The key to use instead of a deprecated one, if any
+type KeyInfo struct {
+	Key         string    `json:"key"`
+	Default     string    `json:"default,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Type        ValueType `json:"type,omitempty"`
+	Deprecated  bool      `json:"deprecated,omitempty"`
+	Replacement string    `json:"replacement,omitempty"`
+}
+
+// Presets is a set of known keys with their defaults and metadata. It
+// extends the plain Preset config, e.g. to generate help output.
+type Presets struct {
+	keys map[string]KeyInfo
+}
+
+// NewPresets creates a new set of presets from the given keys.
+//
+// Example:
+//
+//	p := gitconfig.NewPresets(
+//		gitconfig.KeyInfo{Key: "core.autosync", Default: "true", Type: gitconfig.TypeBool, Description: "Sync after changes"},
+//		gitconfig.KeyInfo{Key: "core.autoimport", Deprecated: true, Replacement: "core.autosync"},
+//	)
+//	cfg.SetPresets(p)
+func NewPresets(keys ...KeyInfo) *Presets {
+	p := &Presets{
+		keys: make(map[string]KeyInfo, len(keys)),
+	}
+	for _, ki := range keys {
+		p.Add(ki)
+	}
+
+	return p
+}
+
+// Add adds or replaces a key.
+func (p *Presets) Add(ki KeyInfo) {
+	p.keys[canonicalizeKey(ki.Key)] = ki
+}
+
+// Lookup returns the metadata of a key.
+func (p *Presets) Lookup(key string) (KeyInfo, bool) {
+	if p == nil {
+		return KeyInfo{}, false
+	}
+
+	ki, found := p.keys[canonicalizeKey(key)]
+
+	return ki, found
+}
+
+// Keys returns the metadata of all keys, sorted by key.
+func (p *Presets) Keys() []KeyInfo {
+	if p == nil {
+		return nil
+	}
+
+	out := make([]KeyInfo, 0, len(p.keys))
+	for _, k := range slices.Sorted(maps.Keys(p.keys)) {
+		out = append(out, p.keys[k])
+	}
+
+	return out
+}
+
+// Config returns a read-only config with the default values of all keys
+// that have one. It can be used as Configs.Preset.
+func (p *Presets) Config() *Config {
+	defaults := make(map[string]string, len(p.keys))
+	for k, ki := range p.keys {
+		if ki.Default != "" {
+			defaults[k] = ki.Default
+		}
+	}
+
+	return NewFromMap(defaults)
+}
+
+// SetPresets uses the defaults of p as Preset scope, marks deprecated keys
+// (see Deprecate) and makes the metadata available to DescribeKey.
+func (cs *Configs) SetPresets(p *Presets) {
+	cs.presets = p
+	cs.Preset = p.Config()
+
+	for _, ki := range p.Keys() {
+		if ki.Deprecated {
+			cs.Deprecate(Deprecation{Key: ki.Key, Replacement: ki.Replacement, Message: ki.Description})
+		}
+	}
+}
+
+// DescribeKey returns the metadata of the key from the presets set with
+// SetPresets. Keys that are deprecated with Deprecate but not part of the
+// presets are described as well.
+func (cs *Configs) DescribeKey(key string) (KeyInfo, bool) {
+	if ki, found := cs.presets.Lookup(key); found {
+		return ki, true
+	}
+
+	if d, found := cs.Deprecation(key); found {
+		return KeyInfo{Key: d.Key, Description: d.Message, Deprecated: true, Replacement: d.Replacement}, true
+	}
+
+	return KeyInfo{}, false
+}
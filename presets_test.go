@@ -0,0 +1,59 @@
+package gitconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresets(t *testing.T) {
+	t.Parallel()
+
+	p := NewPresets(
+		KeyInfo{Key: "presetstest.autoSync", Default: "true", Type: TypeBool, Description: "Sync after changes"},
+		KeyInfo{Key: "presetstest.editor", Description: "Editor to use"},
+		KeyInfo{Key: "presetstest.autoimport", Deprecated: true, Replacement: "presetstest.autosync"},
+	)
+
+	cs := New()
+	cs.local = ParseConfig(strings.NewReader("[presetstest]\n\teditor = vim\n\tautoimport = false\n"))
+	var reported []Deprecation
+	cs.OnDeprecated = func(d Deprecation) { reported = append(reported, d) }
+	cs.SetPresets(p)
+
+	b, ok := cs.GetBool("presetstest.autosync")
+	assert.True(t, ok)
+	assert.True(t, b)
+	assert.Equal(t, "vim", cs.Get("presetstest.editor"))
+
+	ki, found := cs.DescribeKey("presetstest.AUTOSYNC")
+	require.True(t, found)
+	assert.Equal(t, KeyInfo{Key: "presetstest.autoSync", Default: "true", Type: TypeBool, Description: "Sync after changes"}, ki)
+
+	ki, found = cs.DescribeKey("presetstest.autoimport")
+	require.True(t, found)
+	assert.True(t, ki.Deprecated)
+
+	cs.Deprecate(Deprecation{Key: "presetstest.legacy", Message: "gone"})
+	ki, found = cs.DescribeKey("presetstest.legacy")
+	require.True(t, found)
+	assert.Equal(t, KeyInfo{Key: "presetstest.legacy", Description: "gone", Deprecated: true}, ki)
+
+	_, found = cs.DescribeKey("presetstest.unknown")
+	assert.False(t, found)
+
+	assert.Equal(t, "false", cs.Get("presetstest.autoimport"))
+	require.Len(t, reported, 1)
+	assert.Equal(t, "presetstest.autosync", reported[0].Replacement)
+
+	keys := p.Keys()
+	require.Len(t, keys, 3)
+	assert.Equal(t, "presetstest.autoimport", keys[0].Key)
+
+	var nilPresets *Presets
+	assert.Nil(t, nilPresets.Keys())
+	_, found = New().DescribeKey("presetstest.editor")
+	assert.False(t, found)
+}
@@ -0,0 +1,260 @@
+package gitconfig
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// valueOrigin records where and how a value was defined.
+type valueOrigin struct {
+	path string
+	line int
+	bare bool   // defined as a bare key without "="
+	raw  string // the value as written, empty if it is the same as the value (see GetRaw)
+
+	comment string // the inline comment, without its delimiter (see GetComment)
+}
+
+// rawIfDifferent returns raw, or "" if it is the same as value. Most values
+// are written as is, so this avoids keeping a second copy of them.
+func rawIfDifferent(raw, value string) string {
+	if raw == value {
+		return ""
+	}
+
+	return raw
+}
+
+// setOrigin records the origin of a newly added key.
+func (c *Config) setOrigin(key string, vo valueOrigin) {
+	if c.origins == nil {
+		c.origins = make(map[string][]valueOrigin, 16)
+	}
+	c.origins[key] = []valueOrigin{vo}
+}
+
+// origin returns where the value at index i of key was defined. If nothing
+// was recorded (e.g. for presets or values from the environment) only the
+// path of the config is known.
+func (c *Config) origin(key string, i int) valueOrigin {
+	if vo := c.origins[key]; i >= 0 && i < len(vo) {
+		return vo[i]
+	}
+
+	return valueOrigin{path: c.path}
+}
+
+// isBare reports whether the value Get returns for key was defined as a
+// bare key without "=".
+func (c *Config) isBare(key string) bool {
+	return c.origin(key, c.valueIndex(len(c.vars[key]))).bare
+}
+
+func cloneOrigins(in map[string][]valueOrigin) map[string][]valueOrigin {
+	if in == nil {
+		return nil
+	}
+
+	out := make(map[string][]valueOrigin, len(in))
+	for k, vo := range in {
+		out[k] = slices.Clone(vo)
+	}
+
+	return out
+}
+
+// Provenance describes where the effective value of a key comes from.
+//
+// Fields:
+// - Key: The canonical key
+// - Value: The effective value, i.e. what Get returns
+// - Scope: The scope that provides the value
+// - Path: The file that defines the value, if any (may be an included file)
+// - Line: The line in Path, 0 if unknown (e.g. for values set at runtime)
+// - Modified: The last modification time of Path, zero if unknown
+type Provenance struct {
+	Key      string    `json:"key"`
+	Value    string    `json:"value"`
+	Scope    string    `json:"scope"`
+	Path     string    `json:"path,omitempty"`
+	Line     int       `json:"line,omitempty"`
+	Modified time.Time `json:"modified,omitzero"`
+}
+
+// ProvenanceFormat is an output format for ExportProvenance.
+type ProvenanceFormat string
+
+const (
+	// ProvenanceJSON writes a JSON array of Provenance objects.
+	ProvenanceJSON ProvenanceFormat = "json"
+	// ProvenanceCSV writes CSV with a header row.
+	ProvenanceCSV ProvenanceFormat = "csv"
+)
+
+// Provenance returns the provenance of every effective key, sorted by key.
+// It is meant for compliance audits where it matters which file
+// configures what.
+func (cs *Configs) Provenance() []Provenance {
+	keys := make(map[string]struct{}, 64)
+	for _, sc := range cs.namedScopes() {
+		if sc.cfg == nil {
+			continue
+		}
+		for k := range sc.cfg.vars {
+			keys[k] = struct{}{}
+		}
+	}
+
+	mtimes := make(map[string]time.Time, 8)
+	out := make([]Provenance, 0, len(keys))
+	for _, k := range slices.Sorted(maps.Keys(keys)) {
+		for _, sc := range cs.namedScopes() {
+			if sc.cfg == nil {
+				continue
+			}
+			vs := sc.cfg.vars[k]
+			if len(vs) < 1 {
+				continue
+			}
+
+			i := sc.cfg.valueIndex(len(vs))
+			vo := sc.cfg.origin(k, i)
+			out = append(out, Provenance{
+				Key:      k,
+				Value:    vs[i],
+				Scope:    sc.name,
+				Path:     vo.path,
+				Line:     vo.line,
+				Modified: modTime(mtimes, vo.path),
+			})
+
+			break
+		}
+	}
+
+	return out
+}
+
+// GetWithOrigin returns the effective value of the key together with its
+// provenance, i.e. the scope and the file and line that define it, like
+// git config --show-scope --show-origin does. Values from included files
+// report the included file. Returns false if the key is not set.
+//
+// Example:
+//
+//	if p, ok := cfg.GetWithOrigin("core.editor"); ok {
+//		fmt.Printf("%s\t%s:%d\t%s\n", p.Scope, p.Path, p.Line, p.Value)
+//	}
+func (cs *Configs) GetWithOrigin(key string) (Provenance, bool) {
+	cfg, v, found := cs.lookupConfig(key)
+	if !found {
+		return Provenance{}, false
+	}
+
+	p := Provenance{Key: cfg.canonicalKey(key), Value: v}
+	p.Path, p.Line = cfg.lastOrigin(p.Key)
+	p.Modified = modTime(map[string]time.Time{}, p.Path)
+	for _, sc := range cs.namedScopes() {
+		if sc.cfg == cfg {
+			p.Scope = sc.name
+
+			break
+		}
+	}
+
+	return p, true
+}
+
+// GetWithOrigin returns the value Get returns for the key together with the
+// file and line that define it. The file is an included file if the value
+// comes from an include. The line is 0 if the value is not backed by a line
+// (e.g. values added by Set since loading).
+//
+// Example:
+//
+//	v, o, ok := cfg.GetWithOrigin("core.editor")
+//	fmt.Printf("%s:%d\t%s\n", o.Path, o.Line, v)
+func (c *Config) GetWithOrigin(key string) (string, Origin, bool) {
+	v, found := c.Get(key)
+	if !found {
+		return "", Origin{}, false
+	}
+
+	var o Origin
+	o.Path, o.Line = c.lastOrigin(c.canonicalKey(key))
+
+	return v, o, true
+}
+
+// lastOrigin returns the file and line of the value Get returns for the
+// canonical key.
+func (c *Config) lastOrigin(key string) (string, int) {
+	vo := c.origin(key, c.valueIndex(len(c.vars[key])))
+
+	return vo.path, vo.line
+}
+
+// modTime returns the modification time of the file, caching the result.
+func modTime(cache map[string]time.Time, path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	if t, found := cache[path]; found {
+		return t
+	}
+
+	var t time.Time
+	if fi, err := os.Stat(path); err == nil {
+		t = fi.ModTime()
+	}
+	cache[path] = t
+
+	return t
+}
+
+// ExportProvenance writes the provenance of every effective key to w in
+// the given format.
+//
+// Example:
+//
+//	err := cfg.ExportProvenance(os.Stdout, gitconfig.ProvenanceCSV)
+func (cs *Configs) ExportProvenance(w io.Writer, format ProvenanceFormat) error {
+	entries := cs.Provenance()
+
+	switch format {
+	case ProvenanceJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(entries)
+	case ProvenanceCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"key", "value", "scope", "path", "line", "modified"}); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			var line, modified string
+			if e.Line > 0 {
+				line = strconv.Itoa(e.Line)
+			}
+			if !e.Modified.IsZero() {
+				modified = e.Modified.UTC().Format(time.RFC3339)
+			}
+			if err := cw.Write([]string{e.Key, e.Value, e.Scope, e.Path, line, modified}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+
+		return cw.Error()
+	default:
+		return fmt.Errorf("%w: unknown provenance format %q", ErrInvalidValue, format)
+	}
+}
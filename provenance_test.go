@@ -0,0 +1,101 @@
+package gitconfig
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvenance(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	require.NoError(t, os.WriteFile(filepath.Join(td, "local"), []byte(`[core]
+	editor = vim
+	pager = less
+[include]
+	path = inc.config
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "inc.config"), []byte("# included\n[user]\n\tname = John\n"), 0o600))
+
+	c := New()
+	c.SystemConfig = ""
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_PROVENANCE"
+	c.NoWrites = true
+	t.Setenv("GPTEST_PROVENANCE_COUNT", "1")
+	t.Setenv("GPTEST_PROVENANCE_KEY_0", "core.editor")
+	t.Setenv("GPTEST_PROVENANCE_VALUE_0", "nano")
+	c.LoadAll(td)
+	require.NoError(t, c.SetLocal("core.autocrlf", "input"))
+
+	entries := c.Provenance()
+	require.Len(t, entries, 5)
+
+	byKey := map[string]Provenance{}
+	for _, e := range entries {
+		byKey[e.Key] = e
+	}
+
+	assert.Equal(t, Provenance{Key: "core.editor", Value: "nano", Scope: "env"}, byKey["core.editor"])
+
+	pager := byKey["core.pager"]
+	assert.Equal(t, "local", pager.Scope)
+	assert.Equal(t, filepath.Join(td, "local"), pager.Path)
+	assert.Equal(t, 3, pager.Line)
+	assert.False(t, pager.Modified.IsZero())
+
+	name := byKey["user.name"]
+	assert.Equal(t, filepath.Join(td, "inc.config"), name.Path)
+	assert.Equal(t, 3, name.Line)
+
+	autocrlf := byKey["core.autocrlf"]
+	assert.Equal(t, filepath.Join(td, "local"), autocrlf.Path)
+	assert.Equal(t, 0, autocrlf.Line)
+
+	p, ok := c.GetWithOrigin("User.Name")
+	require.True(t, ok)
+	assert.Equal(t, name, p)
+
+	p, ok = c.GetWithOrigin("core.editor")
+	require.True(t, ok)
+	assert.Equal(t, byKey["core.editor"], p)
+
+	_, ok = c.GetWithOrigin("core.missing")
+	assert.False(t, ok)
+
+	v, o, ok := c.local.GetWithOrigin("user.name")
+	require.True(t, ok)
+	assert.Equal(t, "John", v)
+	assert.Equal(t, Origin{Path: filepath.Join(td, "inc.config"), Line: 3}, o)
+
+	v, o, ok = c.local.GetWithOrigin("core.pager")
+	require.True(t, ok)
+	assert.Equal(t, "less", v)
+	assert.Equal(t, Origin{Path: filepath.Join(td, "local"), Line: 3}, o)
+
+	_, _, ok = c.local.GetWithOrigin("core.missing")
+	assert.False(t, ok)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, c.ExportProvenance(buf, ProvenanceJSON))
+	var decoded []Provenance
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Len(t, decoded, 5)
+
+	buf.Reset()
+	require.NoError(t, c.ExportProvenance(buf, ProvenanceCSV))
+	rows, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 6)
+	assert.Equal(t, []string{"key", "value", "scope", "path", "line", "modified"}, rows[0])
+	assert.Equal(t, []string{"core.editor", "nano", "env", "", "", ""}, rows[2])
+
+	require.ErrorIs(t, c.ExportProvenance(buf, "xml"), ErrInvalidValue)
+}
@@ -0,0 +1,204 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Workdir returns the working directory passed to the last LoadAll, as an
+// absolute path with all symlinks resolved. It returns an empty string if
+// no workdir was given.
+//
+// The gitdir conditions of includeIf sections are evaluated against this
+// path (and GitDir), so callers that need to predict which includes apply
+// should use it instead of the path they passed in.
+//
+// Example:
+//
+//	cfg := gitconfig.New()
+//	cfg.LoadAll(".")
+//	fmt.Println(cfg.Workdir()) // e.g. /home/user/src/repo
+func (cs *Configs) Workdir() string {
+	return resolveDir(cs.workdir)
+}
+
+// GitDir returns the git directory of the working directory passed to the
+// last LoadAll, as an absolute path with all symlinks resolved. For a linked
+// worktree this is the per-worktree directory below .git/worktrees of the
+// main repository. It returns an empty string if there is no workdir or it
+// is not part of a git repository.
+// If Configs.GitEnv is set, GIT_DIR takes precedence over the workdir.
+func (cs *Configs) GitDir() string {
+	return resolveDir(cs.repo.gitDirFor(expandHome(cs.workdir)))
+}
+
+// repoEnv holds the locations of the repository set by git's environment
+// variables, see Configs.GitEnv. Empty fields are found from the workdir.
+type repoEnv struct {
+	gitDir    string // GIT_DIR, the git directory of the worktree
+	commonDir string // GIT_COMMON_DIR, the directory shared by all worktrees
+	workTree  string // GIT_WORK_TREE, used if no workdir is given
+}
+
+// readRepoEnv reads GIT_DIR, GIT_COMMON_DIR and GIT_WORK_TREE. Like in git,
+// relative paths are relative to the current directory.
+func readRepoEnv() repoEnv {
+	abs := func(name string) string {
+		p := os.Getenv(name)
+		if p == "" {
+			return ""
+		}
+		p = expandHome(p)
+		if a, err := filepath.Abs(p); err == nil {
+			return a
+		}
+
+		return filepath.Clean(p)
+	}
+
+	return repoEnv{
+		gitDir:    abs("GIT_DIR"),
+		commonDir: abs("GIT_COMMON_DIR"),
+		workTree:  abs("GIT_WORK_TREE"),
+	}
+}
+
+// gitDirFor returns GIT_DIR if it is set and the git directory of the
+// worktree at workdir otherwise, see worktreeGitDir.
+func (e repoEnv) gitDirFor(workdir string) string {
+	if e.gitDir != "" {
+		return e.gitDir
+	}
+
+	return worktreeGitDir(workdir)
+}
+
+// commonDirFor returns GIT_COMMON_DIR if it is set and the common directory
+// of gitDir otherwise, see commonGitDir.
+func (e repoEnv) commonDirFor(gitDir string) string {
+	if e.commonDir != "" {
+		return e.commonDir
+	}
+
+	return commonGitDir(gitDir)
+}
+
+// resolveDir returns the canonical form of a directory, see canonicalPath
+// and expandHome. Empty paths stay empty.
+func resolveDir(dir string) string {
+	if dir == "" {
+		return ""
+	}
+
+	return canonicalPath(expandHome(dir))
+}
+
+// expandHome expands a leading "~" to the home directory, see expandPath.
+// The path is returned as it is if that fails.
+func expandHome(dir string) string {
+	if !strings.HasPrefix(dir, "~") {
+		return dir
+	}
+
+	p, err := expandPath(dir)
+	if err != nil {
+		debug.V(1).Log("can not expand %q: %s", dir, err)
+
+		return dir
+	}
+
+	return p
+}
+
+// repoConfigPath returns the location of the local (shared) or worktree
+// config file name, relative to workdir. A name below ".git" (e.g.
+// ".git/config") is looked up in the git directory .git points to if it is
+// a file, as in linked worktrees and submodules, or in GIT_DIR if it is set
+// in env: the local config in the common directory of all worktrees (see
+// repoEnv.commonDirFor) and the worktree config in the git directory of
+// the worktree itself.
+func repoConfigPath(workdir, name string, shared bool, env repoEnv) string {
+	p := filepath.Join(workdir, name)
+
+	rest, found := strings.CutPrefix(filepath.ToSlash(filepath.Clean(name)), ".git/")
+	if !found {
+		return p
+	}
+	if fi, err := os.Stat(filepath.Join(workdir, ".git")); env.gitDir == "" && (err != nil || fi.IsDir()) {
+		return p
+	}
+
+	gitDir := env.gitDirFor(workdir)
+	if gitDir == "" {
+		return p
+	}
+	if shared {
+		gitDir = env.commonDirFor(gitDir)
+	}
+
+	return filepath.Join(gitDir, filepath.FromSlash(rest))
+}
+
+// conditionDirs returns the paths the gitdir conditions of includeIf
+// sections are matched against: the workdir as given, its resolved form
+// (see Configs.Workdir) and the resolved git directory (see Configs.GitDir).
+// The git directory is gitDir if it is set (e.g. from GIT_DIR) and found
+// from the workdir otherwise. A leading "~" is expanded to the home
+// directory first.
+// Like git we try the unresolved path as well, so conditions written with a
+// symlinked path keep working.
+func conditionDirs(workdir, gitDir string) []string {
+	workdir = expandHome(workdir)
+	if gitDir == "" {
+		gitDir = worktreeGitDir(workdir)
+	}
+
+	dirs := []string{}
+	if workdir != "" {
+		dirs = append(dirs, workdir)
+	}
+	for _, d := range []string{resolveDir(workdir), resolveDir(gitDir)} {
+		if d != "" && !slices.Contains(dirs, d) {
+			dirs = append(dirs, d)
+		}
+	}
+
+	return dirs
+}
+
+// caseInsensitiveFS reports whether dir is on a case-insensitive
+// filesystem. Like git on such filesystems, gitdir conditions are then
+// matched case-insensitively even without the /i suffix. The filesystem is
+// probed by looking up the last path component with its case swapped. If
+// that is not possible, e.g. because dir does not exist or its name has no
+// letters, the platform default is used: case-insensitive on Windows and
+// macOS.
+func caseInsensitiveFS(dir string) bool {
+	dir = filepath.Clean(dir)
+	base := filepath.Base(dir)
+	swapped := strings.Map(swapCase, base)
+	fi, err := os.Stat(dir)
+	if err != nil || swapped == base {
+		return runtime.GOOS == "windows" || runtime.GOOS == "darwin"
+	}
+
+	other, err := os.Stat(filepath.Join(filepath.Dir(dir), swapped))
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(fi, other)
+}
+
+// swapCase turns upper case letters into lower case ones and vice versa.
+func swapCase(r rune) rune {
+	if unicode.IsUpper(r) {
+		return unicode.ToLower(r)
+	}
+
+	return unicode.ToUpper(r)
+}
@@ -0,0 +1,153 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkdirGitDir(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	resolved, err := filepath.EvalSymlinks(td)
+	require.NoError(t, err)
+
+	repo := filepath.Join(resolved, "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0o600))
+	link := filepath.Join(resolved, "link")
+	require.NoError(t, os.Symlink(repo, link))
+
+	// the global config only includes the file if the condition is
+	// evaluated against the resolved git directory
+	require.NoError(t, os.WriteFile(filepath.Join(td, "work"), []byte("[user]\n\temail = work@example.com\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "global"), []byte("[includeIf \"gitdir:"+filepath.Join(repo, ".git")+"\"]\n\tpath = work\n"), 0o600))
+
+	c := New()
+	assert.Empty(t, c.Workdir())
+	assert.Empty(t, c.GitDir())
+
+	c.SystemConfig = filepath.Join(td, "system")
+	c.GlobalConfig = "global"
+	c.EnvPrefix = "GPTEST_REPO_CONFIG"
+	c.LoadAll(link + "/")
+
+	assert.Equal(t, repo, c.Workdir())
+	assert.Equal(t, filepath.Join(repo, ".git"), c.GitDir())
+	assert.Equal(t, "work@example.com", c.Get("user.email"))
+
+	// not a repository
+	c.LoadAll(td)
+	assert.Equal(t, resolved, c.Workdir())
+	assert.Empty(t, c.GitDir())
+	assert.Empty(t, c.Get("user.email"))
+}
+
+func TestConditionDirs(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, conditionDirs("", ""))
+
+	td := t.TempDir()
+	resolved, err := filepath.EvalSymlinks(td)
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(resolved, ".git"), 0o700))
+
+	link := filepath.Join(resolved, "link")
+	require.NoError(t, os.Symlink(resolved, link))
+
+	assert.Equal(t, []string{link, resolved, filepath.Join(resolved, ".git")}, conditionDirs(link, ""))
+	assert.Equal(t, []string{resolved, filepath.Join(resolved, ".git")}, conditionDirs(resolved, ""))
+}
+
+func TestGitdirTilde(t *testing.T) {
+	td := t.TempDir()
+	home, err := filepath.EvalSymlinks(td)
+	require.NoError(t, err)
+	t.Setenv("GOPASS_HOMEDIR", home)
+
+	repo := filepath.Join(home, "projects", "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0o700))
+
+	fn := filepath.Join(home, "config")
+	require.NoError(t, os.WriteFile(filepath.Join(home, "work"), []byte("[user]\n\temail = work@example.com\n"), 0o600))
+	require.NoError(t, os.WriteFile(fn, []byte("[includeIf \"gitdir:~/projects/\"]\n\tpath = work\n"), 0o600))
+
+	for _, wd := range []string{repo, "~/projects/repo"} {
+		c, err := LoadConfigWithWorkdir(fn, wd)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"work@example.com"}, c.vars["user.email"], wd)
+	}
+
+	c, err := LoadConfigWithWorkdir(fn, filepath.Join(home, "elsewhere"))
+	require.NoError(t, err)
+	assert.NotContains(t, c.vars, "user.email")
+
+	assert.Equal(t, filepath.ToSlash(home)+"/projects/", resolveGitdirPattern("~/projects/", nil))
+	assert.Equal(t, filepath.ToSlash(home)+"/projects", resolveGitdirPattern("~/projects", nil))
+
+	cs := New()
+	cs.workdir = "~/projects/repo"
+	assert.Equal(t, repo, cs.Workdir())
+	assert.Equal(t, filepath.Join(repo, ".git"), cs.GitDir())
+}
+
+func TestGitdirSymlinkedPattern(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	resolved, err := filepath.EvalSymlinks(td)
+	require.NoError(t, err)
+
+	repo := filepath.Join(resolved, "src", "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0o700))
+	link := filepath.Join(resolved, "link")
+	require.NoError(t, os.Symlink(filepath.Join(resolved, "src"), link))
+
+	assert.Equal(t, filepath.ToSlash(resolved)+"/src/*/", resolvePatternBase(filepath.ToSlash(link)+"/*/"))
+	assert.Equal(t, "**/link/", resolvePatternBase("**/link/"))
+	assert.Equal(t, "/does/not/exist/", resolvePatternBase("/does/not/exist/"))
+
+	// the pattern uses the symlink, the workdir the resolved path
+	fn := filepath.Join(resolved, "config")
+	require.NoError(t, os.WriteFile(filepath.Join(resolved, "inc"), []byte("[user]\n\tname = linked\n"), 0o600))
+	require.NoError(t, os.WriteFile(fn, []byte("[includeIf \"gitdir:"+filepath.ToSlash(link)+"/repo/\"]\n\tpath = inc\n"), 0o600))
+
+	c, err := LoadConfigWithWorkdir(fn, repo)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"linked"}, c.vars["user.name"])
+}
+
+func TestCaseInsensitiveFS(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	dir := filepath.Join(td, "Repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0o700))
+
+	// probe the filesystem the way the test expects it to behave
+	_, err := os.Stat(filepath.Join(td, "rEPO"))
+	insensitive := err == nil
+	assert.Equal(t, insensitive, caseInsensitiveFS(dir))
+	assert.Equal(t, insensitive, caseInsensitiveFS(filepath.Join(dir, ".git")))
+
+	// both spellings exist on a case-sensitive filesystem
+	if !insensitive {
+		require.NoError(t, os.Mkdir(filepath.Join(td, "rEPO"), 0o700))
+		assert.False(t, caseInsensitiveFS(dir))
+	}
+
+	// a gitdir condition without /i matches a different case only on a
+	// case-insensitive filesystem
+	fn := filepath.Join(td, "config")
+	require.NoError(t, os.WriteFile(filepath.Join(td, "inc"), []byte("[user]\n\tname = folded\n"), 0o600))
+	require.NoError(t, os.WriteFile(fn, []byte("[includeIf \"gitdir:**/REPO/\"]\n\tpath = inc\n"), 0o600))
+
+	c, err := LoadConfigWithWorkdir(fn, dir)
+	require.NoError(t, err)
+	assert.Equal(t, insensitive, c.IsSet("user.name"))
+}
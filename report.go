@@ -0,0 +1,110 @@
+package gitconfig
+
+// ChangeKind describes the kind of modification applied to a key.
+type ChangeKind string
+
+const (
+	// ChangeAdded indicates a key that did not exist before.
+	ChangeAdded ChangeKind = "added"
+	// ChangeUpdated indicates a key whose value was replaced.
+	ChangeUpdated ChangeKind = "updated"
+	// ChangeRemoved indicates a key that was deleted.
+	ChangeRemoved ChangeKind = "removed"
+)
+
+// Change describes a single modification of a key.
+type Change struct {
+	Kind   ChangeKind `json:"kind"`
+	Key    string     `json:"key"`
+	Before string     `json:"before,omitempty"`
+	After  string     `json:"after,omitempty"`
+}
+
+// FileChanges groups the changes applied to a single config file.
+// Path is empty for configs that are not backed by a file (e.g. env).
+type FileChanges struct {
+	Path    string   `json:"path"`
+	Added   []Change `json:"added,omitempty"`
+	Updated []Change `json:"updated,omitempty"`
+	Removed []Change `json:"removed,omitempty"`
+}
+
+// Len returns the number of changes to this file.
+func (fc FileChanges) Len() int {
+	return len(fc.Added) + len(fc.Updated) + len(fc.Removed)
+}
+
+// ChangeReport describes the modifications applied by a bulk operation
+// like Converge. It can be serialized to JSON for logging or CI output.
+//
+// Example:
+//
+//	report, err := cfg.Converge(desired, "global", ConvergeOptions{})
+//	if err != nil { ... }
+//	buf, _ := json.MarshalIndent(report, "", "  ")
+//	fmt.Println(string(buf))
+type ChangeReport struct {
+	Files []FileChanges `json:"files"`
+}
+
+// IsEmpty returns true if the report contains no changes.
+func (r *ChangeReport) IsEmpty() bool {
+	return r.Len() == 0
+}
+
+// Len returns the total number of changes across all files.
+func (r *ChangeReport) Len() int {
+	if r == nil {
+		return 0
+	}
+
+	var n int
+	for _, fc := range r.Files {
+		n += fc.Len()
+	}
+
+	return n
+}
+
+// Changes returns all changes across all files in the order they were recorded
+// per file and kind.
+func (r *ChangeReport) Changes() []Change {
+	if r == nil {
+		return nil
+	}
+
+	out := make([]Change, 0, r.Len())
+	for _, fc := range r.Files {
+		out = append(out, fc.Added...)
+		out = append(out, fc.Updated...)
+		out = append(out, fc.Removed...)
+	}
+
+	return out
+}
+
+// add records a change for the file at path.
+func (r *ChangeReport) add(path string, ch Change) {
+	idx := -1
+	for i, fc := range r.Files {
+		if fc.Path == path {
+			idx = i
+
+			break
+		}
+	}
+	if idx < 0 {
+		r.Files = append(r.Files, FileChanges{Path: path})
+		idx = len(r.Files) - 1
+	}
+
+	fc := &r.Files[idx]
+	switch ch.Kind {
+	case ChangeAdded:
+		fc.Added = append(fc.Added, ch)
+	case ChangeUpdated:
+		fc.Updated = append(fc.Updated, ch)
+	case ChangeRemoved:
+		fc.Removed = append(fc.Removed, ch)
+	}
+}
@@ -0,0 +1,43 @@
+package gitconfig
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeReport(t *testing.T) {
+	t.Parallel()
+
+	var empty *ChangeReport
+	assert.True(t, empty.IsEmpty())
+	assert.Nil(t, empty.Changes())
+
+	r := &ChangeReport{}
+	r.add("/a", Change{Kind: ChangeRemoved, Key: "core.old", Before: "x"})
+	r.add("/a", Change{Kind: ChangeAdded, Key: "core.new", After: "y"})
+	r.add("/b", Change{Kind: ChangeUpdated, Key: "user.name", Before: "a", After: "b"})
+
+	assert.False(t, r.IsEmpty())
+	assert.Equal(t, 3, r.Len())
+	require.Len(t, r.Files, 2)
+	assert.Equal(t, 2, r.Files[0].Len())
+	assert.Equal(t, []Change{
+		{Kind: ChangeAdded, Key: "core.new", After: "y"},
+		{Kind: ChangeRemoved, Key: "core.old", Before: "x"},
+		{Kind: ChangeUpdated, Key: "user.name", Before: "a", After: "b"},
+	}, r.Changes())
+
+	buf, err := json.Marshal(r)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"files":[
+		{"path":"/a","added":[{"kind":"added","key":"core.new","after":"y"}],"removed":[{"kind":"removed","key":"core.old","before":"x"}]},
+		{"path":"/b","updated":[{"kind":"updated","key":"user.name","before":"a","after":"b"}]}
+	]}`, string(buf))
+
+	var decoded ChangeReport
+	require.NoError(t, json.Unmarshal(buf, &decoded))
+	assert.Equal(t, r.Changes(), decoded.Changes())
+}
@@ -0,0 +1,104 @@
+package gitconfig
+
+import (
+	"slices"
+	"strings"
+)
+
+// The names of the scopes, see ScopesByPriority.
+const (
+	// ScopeOverlay holds values read from a stream, e.g. standard input (see Configs.LoadConfigFromReader).
+	ScopeOverlay = "overlay"
+	// ScopeEnv holds values from environment variables (e.g. GIT_CONFIG_COUNT).
+	ScopeEnv = "env"
+	// ScopeWorktree is the per-worktree config (e.g. .git/config.worktree).
+	ScopeWorktree = "worktree"
+	// ScopeLocal is the per-repository config (e.g. .git/config).
+	ScopeLocal = "local"
+	// ScopeGlobal is the per-user config (e.g. ~/.gitconfig).
+	ScopeGlobal = "global"
+	// ScopeSystem is the system-wide config (e.g. /etc/gitconfig).
+	ScopeSystem = "system"
+	// ScopePreset holds the defaults set by the application (see Configs.Preset).
+	ScopePreset = "preset"
+)
+
+// scopesByPriority is the order in which all lookups consult the scopes.
+var scopesByPriority = []string{
+	ScopeOverlay,
+	ScopeEnv,
+	ScopeWorktree,
+	ScopeLocal,
+	ScopeGlobal,
+	ScopeSystem,
+	ScopePreset,
+}
+
+// ScopesByPriority returns the names of all scopes, from the highest to the
+// lowest priority. Get, GetAll, Keys and all other lookups of Configs
+// consult the scopes in exactly this order, so code that layers its own
+// logic on top (e.g. caching or metrics) should iterate them the same way.
+//
+// Example:
+//
+//	for _, scope := range gitconfig.ScopesByPriority() {
+//		if v, ok := cfg.GetFrom("core.editor", scope); ok {
+//			fmt.Printf("%s: %s\n", scope, v)
+//		}
+//	}
+func ScopesByPriority() []string {
+	return slices.Clone(scopesByPriority)
+}
+
+// namedScope is a config together with the name of its scope.
+type namedScope struct {
+	name string
+	cfg  *Config
+}
+
+// namedScopes returns all scopes in decreasing order of priority.
+func (cs *Configs) namedScopes() []namedScope {
+	out := make([]namedScope, 0, len(scopesByPriority))
+	for _, name := range scopesByPriority {
+		out = append(out, namedScope{name, cs.scopeConfig(name)})
+	}
+
+	return out
+}
+
+// scopes returns the configs of all scopes in decreasing order of priority.
+// Some of them may be nil.
+func (cs *Configs) scopes() []*Config {
+	out := make([]*Config, 0, len(scopesByPriority))
+	for _, name := range scopesByPriority {
+		out = append(out, cs.scopeConfig(name))
+	}
+
+	return out
+}
+
+// scopeConfig returns the config of the given scope, or nil if the scope
+// is unknown or not set.
+func (cs *Configs) scopeConfig(scope string) *Config {
+	var cfg *Config
+	switch strings.ToLower(scope) {
+	case ScopeOverlay:
+		cfg = cs.overlay
+	case ScopeEnv:
+		cfg = cs.env
+	case ScopeWorktree:
+		cfg = cs.worktree
+	case ScopeLocal:
+		cfg = cs.local
+	case ScopeGlobal:
+		cfg = cs.global
+	case ScopeSystem:
+		cfg = cs.system
+	case ScopePreset:
+		cfg = cs.Preset
+	default:
+		debug.V(3).Log("[%s] unknown config scope %s", cs.Name, scope)
+	}
+
+	return cfg
+}
@@ -0,0 +1,49 @@
+package gitconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopesByPriority(t *testing.T) {
+	t.Parallel()
+
+	scopes := ScopesByPriority()
+	assert.Equal(t, []string{ScopeOverlay, ScopeEnv, ScopeWorktree, ScopeLocal, ScopeGlobal, ScopeSystem, ScopePreset}, scopes)
+
+	// callers get their own copy
+	scopes[0] = "changed"
+	assert.Equal(t, ScopeOverlay, ScopesByPriority()[0])
+}
+
+func TestScopesByPriorityMatchesLookups(t *testing.T) {
+	t.Parallel()
+
+	c := New()
+	c.overlay = ParseConfig(strings.NewReader(""))
+	c.env = ParseConfig(strings.NewReader(""))
+	c.worktree = ParseConfig(strings.NewReader(""))
+	c.local = ParseConfig(strings.NewReader(""))
+	c.global = ParseConfig(strings.NewReader(""))
+	c.system = ParseConfig(strings.NewReader(""))
+	c.Preset = ParseConfig(strings.NewReader(""))
+
+	// set the key in every scope, starting with the lowest priority, and
+	// check that the scope that was set last always wins
+	scopes := ScopesByPriority()
+	for i := len(scopes) - 1; i >= 0; i-- {
+		cfg := c.scopeConfig(scopes[i])
+		cfg.noWrites = true
+		cfg.vars["core.scope"] = []string{scopes[i]}
+
+		assert.Equal(t, scopes[i], c.Get("core.scope"))
+		assert.Equal(t, []string{scopes[i]}, c.GetAll("core.scope"))
+	}
+
+	for i, sc := range c.namedScopes() {
+		assert.Equal(t, scopes[i], sc.name)
+		assert.Same(t, c.scopeConfig(sc.name), sc.cfg)
+	}
+}
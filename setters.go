@@ -0,0 +1,102 @@
+package gitconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SetBool sets the key to the given boolean in git-canonical form,
+// i.e. "true" or "false".
+func (c *Config) SetBool(key string, value bool) error {
+	return c.Set(key, strconv.FormatBool(value))
+}
+
+// SetInt sets the key to the given integer in git-canonical form,
+// i.e. a plain decimal number without unit suffix.
+func (c *Config) SetInt(key string, value int64) error {
+	return c.Set(key, strconv.FormatInt(value, 10))
+}
+
+// SetPath sets the key to the given path. The path is validated before
+// writing: it must not be empty, must not contain newlines or NUL bytes
+// and a leading tilde must be expandable.
+func (c *Config) SetPath(key, value string) error {
+	if err := validatePath(value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	return c.Set(key, value)
+}
+
+// SetLocalBool sets a boolean key in the local config. See Config.SetBool.
+func (cs *Configs) SetLocalBool(key string, value bool) error {
+	return cs.SetLocal(key, strconv.FormatBool(value))
+}
+
+// SetGlobalBool sets a boolean key in the global config. See Config.SetBool.
+func (cs *Configs) SetGlobalBool(key string, value bool) error {
+	return cs.SetGlobal(key, strconv.FormatBool(value))
+}
+
+// SetEnvBool sets a boolean key in the env config. See Config.SetBool.
+func (cs *Configs) SetEnvBool(key string, value bool) error {
+	return cs.SetEnv(key, strconv.FormatBool(value))
+}
+
+// SetLocalInt sets an integer key in the local config. See Config.SetInt.
+func (cs *Configs) SetLocalInt(key string, value int64) error {
+	return cs.SetLocal(key, strconv.FormatInt(value, 10))
+}
+
+// SetGlobalInt sets an integer key in the global config. See Config.SetInt.
+func (cs *Configs) SetGlobalInt(key string, value int64) error {
+	return cs.SetGlobal(key, strconv.FormatInt(value, 10))
+}
+
+// SetEnvInt sets an integer key in the env config. See Config.SetInt.
+func (cs *Configs) SetEnvInt(key string, value int64) error {
+	return cs.SetEnv(key, strconv.FormatInt(value, 10))
+}
+
+// SetLocalPath sets a path key in the local config. See Config.SetPath.
+func (cs *Configs) SetLocalPath(key, value string) error {
+	if err := validatePath(value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	return cs.SetLocal(key, value)
+}
+
+// SetGlobalPath sets a path key in the global config. See Config.SetPath.
+func (cs *Configs) SetGlobalPath(key, value string) error {
+	if err := validatePath(value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	return cs.SetGlobal(key, value)
+}
+
+// SetEnvPath sets a path key in the env config. See Config.SetPath.
+func (cs *Configs) SetEnvPath(key, value string) error {
+	if err := validatePath(value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	return cs.SetEnv(key, value)
+}
+
+// validatePath checks that a value can be stored and read back as a path.
+func validatePath(p string) error {
+	if strings.TrimSpace(p) == "" {
+		return fmt.Errorf("%w: empty path", ErrInvalidValue)
+	}
+	if strings.ContainsAny(p, "\n\x00") {
+		return fmt.Errorf("%w: path %q contains invalid characters", ErrInvalidValue, p)
+	}
+	if _, err := expandPath(p); err != nil {
+		return err
+	}
+
+	return nil
+}
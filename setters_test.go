@@ -0,0 +1,51 @@
+package gitconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigTypedSetters(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader("[core]\n\tbare = yes\n"))
+	c.noWrites = true
+
+	require.NoError(t, c.SetBool("core.bare", false))
+	require.NoError(t, c.SetInt("core.bigfilethreshold", 512<<20))
+	require.NoError(t, c.SetPath("core.hookspath", "~/hooks"))
+
+	v, _ := c.Get("core.bare")
+	assert.Equal(t, "false", v)
+	v, _ = c.Get("core.bigfilethreshold")
+	assert.Equal(t, "536870912", v)
+	v, _ = c.Get("core.hookspath")
+	assert.Equal(t, "~/hooks", v)
+
+	require.ErrorIs(t, c.SetPath("core.hookspath", ""), ErrInvalidValue)
+	require.ErrorIs(t, c.SetPath("core.hookspath", "foo\nbar"), ErrInvalidValue)
+	require.ErrorIs(t, c.SetPath("core.hookspath", "~nosuchuser-gitconfig/hooks"), ErrInvalidValue)
+	v, _ = c.Get("core.hookspath")
+	assert.Equal(t, "~/hooks", v)
+}
+
+func TestConfigsTypedSetters(t *testing.T) {
+	t.Parallel()
+
+	cs := New()
+
+	require.NoError(t, cs.SetEnvBool("core.bare", true))
+	require.NoError(t, cs.SetEnvInt("pack.windowmemory", 1<<10))
+	require.NoError(t, cs.SetEnvPath("core.excludesfile", "/etc/ignore"))
+
+	assert.Equal(t, "true", cs.Get("core.bare"))
+	assert.Equal(t, "1024", cs.Get("pack.windowmemory"))
+	assert.Equal(t, "/etc/ignore", cs.Get("core.excludesfile"))
+
+	require.ErrorIs(t, cs.SetEnvPath("core.excludesfile", ""), ErrInvalidValue)
+	require.ErrorIs(t, cs.SetLocalBool("core.bare", true), ErrWorkdirNotSet)
+	require.ErrorIs(t, cs.SetLocalPath("core.hookspath", "\x00"), ErrInvalidValue)
+}
@@ -0,0 +1,100 @@
+package gitconfig
+
+import (
+	"fmt"
+	"io"
+)
+
+// ParseError describes a line that could not be parsed in strict mode.
+// It wraps ErrParse.
+//
+// Fields:
+// - Path: The file that contains the line, empty if parsed from a reader
+// - Line: The line number, starting at 1
+// - Text: The offending line
+// - Reason: Why the line is invalid
+type ParseError struct {
+	Path   string
+	Line   int
+	Text   string
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	loc := fmt.Sprintf("line %d", e.Line)
+	if e.Path != "" {
+		loc = fmt.Sprintf("%s:%d", e.Path, e.Line)
+	}
+
+	return fmt.Sprintf("%s: %s: %s: %q", loc, ErrParse, e.Reason, e.Text)
+}
+
+// Unwrap returns ErrParse.
+func (e *ParseError) Unwrap() error {
+	return ErrParse
+}
+
+// ParseConfigStrict is like ParseConfig but fails on the first line that
+// ParseConfig would ignore, i.e. malformed section headers, invalid keys
+// and unterminated quoted values. It also rejects escape sequences git does
+// not know, like "\q", which ParseConfig keeps as they are. The reason
+// includes the column of the sequence. Keys beyond the size limits (see
+// KeyRules) fail as well. Binary content is rejected with
+// ErrNotAConfigFile.
+//
+// Example:
+//
+//	c, err := gitconfig.ParseConfigStrict(r)
+//	var perr *gitconfig.ParseError
+//	if errors.As(err, &perr) {
+//		fmt.Printf("line %d: %s\n", perr.Line, perr.Reason)
+//	}
+func ParseConfigStrict(r io.Reader) (*Config, error) {
+	c, err := parseConfig(r, parseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.parseError(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// parseError returns the first syntax error found while parsing the config
+// and its includes, if any.
+func (c *Config) parseError() error {
+	for _, pi := range c.issues {
+		// issues without a line, e.g. the include limit, are not syntax errors
+		if pi.line < 1 {
+			continue
+		}
+
+		return &ParseError{
+			Path:   pi.path,
+			Line:   pi.line,
+			Text:   pi.text,
+			Reason: pi.msg,
+		}
+	}
+
+	return nil
+}
+
+// loadConfig loads the config for a single scope, see LoadConfig. In strict
+// mode a config with syntax errors is rejected with a *ParseError.
+// Conditional includes are evaluated against the workdir of the last
+// LoadAll, see Configs.Workdir and Configs.GitDir.
+func (cs *Configs) loadConfig(fn string) (*Config, error) {
+	c, err := loadConfigs(fn, cs.workdir, cs.parseOptions())
+	if err != nil || !cs.Strict {
+		return c, err
+	}
+
+	if err := c.parseError(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,91 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfigStrict(t *testing.T) {
+	t.Parallel()
+
+	c, err := ParseConfigStrict(strings.NewReader("[core]\n\tbare\n\tmsg = \"a\nb\"\n[remote \"origin\"] # comment\n\turl = x\n"))
+	require.NoError(t, err)
+	v, _ := c.Get("remote.origin.url")
+	assert.Equal(t, "x", v)
+
+	for in, want := range map[string]ParseError{
+		"[core]\n\t1nvalid = x\n":           {Line: 2, Text: "\t1nvalid = x", Reason: `invalid key "1nvalid"`},
+		"[core]\n\tok = 1\n[core\n":         {Line: 3, Text: "[core", Reason: "invalid section header"},
+		"[]\n":                              {Line: 1, Text: "[]", Reason: "empty section header"},
+		"[core]\n\tmsg = \"open\n\tx = 1\n": {Line: 2, Text: "\tmsg = \"open", Reason: "unterminated quoted value"},
+		"[core]\n\tpath = C:\\qux\n":        {Line: 2, Text: "\tpath = C:\\qux", Reason: `invalid escape sequence "\\q" at column 11`},
+	} {
+		c, err := ParseConfigStrict(strings.NewReader(in))
+		require.ErrorIs(t, err, ErrParse, in)
+		assert.Nil(t, c)
+
+		var perr *ParseError
+		require.ErrorAs(t, err, &perr, in)
+		assert.Equal(t, want, *perr, in)
+	}
+
+	assert.Equal(t, `line 2: parse error: invalid key "1nvalid": "\t1nvalid = x"`, (&ParseError{Line: 2, Text: "\t1nvalid = x", Reason: `invalid key "1nvalid"`}).Error())
+	assert.Equal(t, `/tmp/config:1: parse error: empty section header: "[]"`, (&ParseError{Path: "/tmp/config", Line: 1, Text: "[]", Reason: "empty section header"}).Error())
+}
+
+func TestInvalidEscapes(t *testing.T) {
+	t.Parallel()
+
+	in := "[core]\n\tpath = \"C:\\qux\\n\" # \\x in a comment\n\tok = a\\\\q\n"
+
+	// the lenient parser keeps the sequence and warns about it
+	c := ParseConfig(strings.NewReader(in))
+	v, _ := c.Get("core.path")
+	assert.Equal(t, "C:\\qux\n", v)
+	v, _ = c.Get("core.ok")
+	assert.Equal(t, `a\q`, v)
+	assert.Equal(t, []Warning{{Line: 2, Reason: `invalid escape sequence "\\q" at column 12`}}, c.Warnings())
+
+	_, err := ParseConfigStrict(strings.NewReader(in))
+	require.ErrorIs(t, err, ErrParse)
+
+	assert.Equal(t, []int{1, 4}, invalidEscapes(`a\qb\z\n "\\" ; \y`))
+}
+
+func TestConfigsStrict(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	broken := "[local]\n\tkey = local\n\t1nvalid = x\n"
+	require.NoError(t, os.WriteFile(filepath.Join(td, "local"), []byte(broken), 0o600))
+
+	c := New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_STRICT_CONFIG"
+
+	// lenient mode ignores the invalid line
+	c.LoadAll(td)
+	assert.Equal(t, "local", c.Get("local.key"))
+
+	c.Strict = true
+	c.Reload()
+	assert.Empty(t, c.Get("local.key"))
+
+	local, ok := c.LoadReport().Scope("local")
+	require.True(t, ok)
+	assert.False(t, local.Found)
+	assert.True(t, local.ReadOnly)
+	assert.Equal(t, filepath.Join(td, "local")+`:3: parse error: invalid key "1nvalid": "\t1nvalid = x"`, local.Error)
+
+	// the broken file is not overwritten
+	require.NoError(t, c.SetLocal("local.other", "value"))
+	buf, err := os.ReadFile(filepath.Join(td, "local"))
+	require.NoError(t, err)
+	assert.Equal(t, broken, string(buf))
+}
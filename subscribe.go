@@ -0,0 +1,118 @@
+package gitconfig
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+type subscription struct {
+	prefix string
+	fn     func(changes []Change)
+}
+
+// Subscribe registers fn to be called whenever the effective value of a key
+// starting with prefix changes. An empty prefix matches all keys.
+//
+// Changes are detected for modifications made through this Configs instance,
+// i.e. the Set*, Unset* and Converge methods as well as LoadAll and Reload.
+// All changes caused by one such call are delivered in a single batch, sorted
+// by key. Before and After contain the effective value, i.e. the value Get
+// would return. The callback is invoked synchronously after the operation
+// completed.
+//
+// The returned function removes the subscription.
+//
+// Example:
+//
+//	unsubscribe := cfg.Subscribe("mounts.", func(changes []Change) {
+//		for _, ch := range changes {
+//			fmt.Printf("%s: %q -> %q\n", ch.Key, ch.Before, ch.After)
+//		}
+//	})
+//	defer unsubscribe()
+func (cs *Configs) Subscribe(prefix string, fn func(changes []Change)) func() {
+	s := &subscription{
+		prefix: prefix,
+		fn:     fn,
+	}
+	cs.subs = append(cs.subs, s)
+
+	return func() {
+		cs.subs = slices.DeleteFunc(cs.subs, func(o *subscription) bool {
+			return o == s
+		})
+	}
+}
+
+// notifying runs fn and notifies all subscribers about changed effective
+// values. Subscribers are notified even if fn fails, since it might have
+// applied some changes already.
+func (cs *Configs) notifying(fn func() error) error {
+	if len(cs.subs) == 0 {
+		return fn()
+	}
+
+	before := cs.effectiveValues()
+	err := fn()
+	changes := diffValues(before, cs.effectiveValues())
+
+	if len(changes) == 0 {
+		return err
+	}
+
+	for _, s := range slices.Clone(cs.subs) {
+		batch := make([]Change, 0, len(changes))
+		for _, ch := range changes {
+			if strings.HasPrefix(ch.Key, s.prefix) {
+				batch = append(batch, ch)
+			}
+		}
+		if len(batch) > 0 {
+			s.fn(batch)
+		}
+	}
+
+	return err
+}
+
+// effectiveValues returns the value Get would return for every known key.
+func (cs *Configs) effectiveValues() map[string]string {
+	keys := cs.Keys()
+	out := make(map[string]string, len(keys))
+	for _, k := range keys {
+		if v, found := cs.lookup(k); found {
+			out[k] = v
+		}
+	}
+
+	return out
+}
+
+// diffValues returns the changes between two sets of values, sorted by key.
+func diffValues(before, after map[string]string) []Change {
+	changes := make([]Change, 0, 8)
+
+	for _, k := range slices.Sorted(maps.Keys(after)) {
+		v := after[k]
+		old, found := before[k]
+		switch {
+		case !found:
+			changes = append(changes, Change{Kind: ChangeAdded, Key: k, After: v})
+		case old != v:
+			changes = append(changes, Change{Kind: ChangeUpdated, Key: k, Before: old, After: v})
+		}
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(before)) {
+		if _, found := after[k]; !found {
+			changes = append(changes, Change{Kind: ChangeRemoved, Key: k, Before: before[k]})
+		}
+	}
+
+	slices.SortStableFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return changes
+}
@@ -0,0 +1,89 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	c := New()
+	c.SystemConfig = filepath.Join(td, "system")
+	c.GlobalConfig = ""
+	c.LocalConfig = "local"
+	c.EnvPrefix = "GPTEST_CONFIG"
+	c.NoWrites = true
+
+	localPath := filepath.Join(td, c.LocalConfig)
+	require.NoError(t, os.WriteFile(localPath, []byte(`[mounts]
+	path = /tmp/a
+[core]
+	editor = vim
+`), 0o600))
+
+	var batches [][]Change
+	unsubscribe := c.Subscribe("mounts.", func(changes []Change) {
+		batches = append(batches, changes)
+	})
+
+	var all int
+	c.Subscribe("", func(changes []Change) {
+		all += len(changes)
+	})
+
+	c.LoadAll(td)
+	require.Len(t, batches, 1)
+	assert.Equal(t, []Change{{Kind: ChangeAdded, Key: "mounts.path", After: "/tmp/a"}}, batches[0])
+	assert.Equal(t, 2, all)
+
+	// changes outside of the prefix are not delivered
+	require.NoError(t, c.SetLocal("core.editor", "nano"))
+	assert.Len(t, batches, 1)
+	assert.Equal(t, 3, all)
+
+	// env overrides change the effective value
+	require.NoError(t, c.SetEnv("mounts.path", "/tmp/env"))
+	require.Len(t, batches, 2)
+	assert.Equal(t, []Change{{Kind: ChangeUpdated, Key: "mounts.path", Before: "/tmp/a", After: "/tmp/env"}}, batches[1])
+
+	// changes shadowed by env are not delivered
+	require.NoError(t, c.SetLocal("mounts.path", "/tmp/b"))
+	assert.Len(t, batches, 2)
+
+	// converge delivers one batch
+	_, err := c.Converge(map[string]string{"mounts.foo": "1", "mounts.bar": "2"}, "local", ConvergeOptions{})
+	require.NoError(t, err)
+	require.Len(t, batches, 3)
+	assert.Equal(t, []Change{
+		{Kind: ChangeAdded, Key: "mounts.bar", After: "2"},
+		{Kind: ChangeAdded, Key: "mounts.foo", After: "1"},
+	}, batches[2])
+
+	require.NoError(t, c.UnsetLocal("mounts.foo"))
+	require.Len(t, batches, 4)
+	assert.Equal(t, []Change{{Kind: ChangeRemoved, Key: "mounts.foo", Before: "1"}}, batches[3])
+
+	unsubscribe()
+	require.NoError(t, c.UnsetLocal("mounts.bar"))
+	assert.Len(t, batches, 4)
+}
+
+func TestDiffValues(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []Change{
+		{Kind: ChangeRemoved, Key: "a", Before: "1"},
+		{Kind: ChangeUpdated, Key: "b", Before: "2", After: "3"},
+		{Kind: ChangeAdded, Key: "c", After: "4"},
+	}, diffValues(
+		map[string]string{"a": "1", "b": "2", "d": "5"},
+		map[string]string{"b": "3", "c": "4", "d": "5"},
+	))
+	assert.Empty(t, diffValues(map[string]string{"a": "1"}, map[string]string{"a": "1"}))
+}
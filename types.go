@@ -0,0 +1,573 @@
+package gitconfig
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetBool returns the value of the key interpreted as a boolean, following
+// git's rules for --type=bool:
+//
+//   - true, yes, on and 1 are true (case-insensitive)
+//   - false, no, off and 0 are false (case-insensitive)
+//   - any other integer is true if it is not zero
+//   - an empty value (e.g. a bare key without "=") is true
+//
+// Returns (value, true) if the key is found and is a valid boolean,
+// (false, false) otherwise.
+//
+// Example:
+//
+//	if v, ok := cfg.GetBool("core.bare"); ok && v {
+//	  fmt.Println("bare repository")
+//	}
+func (c *Config) GetBool(key string) (bool, bool) {
+	v, found := c.Get(key)
+	if !found {
+		return false, false
+	}
+	if c.isBare(key) {
+		return true, true
+	}
+
+	b, err := coerce(c, key, TypeBool, v, c.parseBool)
+	if err != nil {
+		debug.V(1).Log("invalid boolean for %s: %s", key, err)
+
+		return false, false
+	}
+
+	return b, true
+}
+
+// GetBool returns the value for the given key from the first scope that
+// contains it, interpreted as a boolean. See Config.GetBool for the rules.
+//
+// Returns (false, false) if the key is not found or the value from the
+// highest priority scope is not a valid boolean.
+func (cs *Configs) GetBool(key string) (bool, bool) {
+	cfg, v, found := cs.lookupConfig(key)
+	if !found {
+		return false, false
+	}
+	if cfg.isBare(key) {
+		return true, true
+	}
+
+	b, err := coerce(cfg, key, TypeBool, v, cfg.parseBool)
+	if err != nil {
+		debug.V(1).Log("[%s] invalid boolean for %s: %s", cs.Name, key, err)
+
+		return false, false
+	}
+
+	return b, true
+}
+
+// GetInt returns the value of the key interpreted as an integer, following
+// git's rules for --type=int. An optional unit suffix of k, m or g
+// (case-insensitive) scales the value by 1024, 1024^2 or 1024^3.
+//
+// Returns (value, true, nil) if the key is found and valid, (0, false, nil)
+// if the key is not set and (0, true, err) if the value is not a valid integer.
+// The error wraps ErrInvalidValue.
+//
+// Example:
+//
+//	limit, found, err := cfg.GetInt("core.bigFileThreshold")
+func (c *Config) GetInt(key string) (int64, bool, error) {
+	v, found := c.Get(key)
+	if !found {
+		return 0, false, nil
+	}
+
+	n, err := coerce(c, key, TypeInt, v, parseInt)
+	if err != nil {
+		return 0, true, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return n, true, nil
+}
+
+// GetInt returns the value for the given key from the first scope that
+// contains it, interpreted as an integer. See Config.GetInt for the rules.
+func (cs *Configs) GetInt(key string) (int64, bool, error) {
+	cfg, v, found := cs.lookupConfig(key)
+	if !found {
+		return 0, false, nil
+	}
+
+	n, err := coerce(cfg, key, TypeInt, v, parseInt)
+	if err != nil {
+		return 0, true, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return n, true, nil
+}
+
+// GetPath returns the value of the key interpreted as a path, following
+// git's rules for --type=path:
+//
+//   - a leading "~/" (or a value of just "~") is replaced by the home
+//     directory of the current user
+//   - a leading "~user/" is replaced by the home directory of that user
+//   - any other value, including relative paths, is returned as-is
+//
+// The home directory is determined without relying on $HOME, so this
+// also works on Windows.
+//
+// Returns (path, true, nil) if the key is found, ("", false, nil) if not set and
+// ("", true, err) if the path can not be expanded (e.g. unknown user).
+//
+// Example:
+//
+//	hooks, found, err := cfg.GetPath("core.hooksPath")
+func (c *Config) GetPath(key string) (string, bool, error) {
+	v, found := c.Get(key)
+	if !found {
+		return "", false, nil
+	}
+
+	p, err := expandPath(v)
+	if err != nil {
+		return "", true, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return p, true, nil
+}
+
+// GetPath returns the value for the given key from the first scope that
+// contains it, interpreted as a path. See Config.GetPath for the rules.
+func (cs *Configs) GetPath(key string) (string, bool, error) {
+	v, found := cs.lookup(key)
+	if !found {
+		return "", false, nil
+	}
+
+	p, err := expandPath(v)
+	if err != nil {
+		return "", true, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return p, true, nil
+}
+
+// GetDuration returns the value of the key interpreted as a duration.
+// Values use Go duration syntax (e.g. "30s", "1h30m") or are plain
+// integers, which are taken as seconds.
+//
+// Returns (value, true, nil) if the key is found and valid, (0, false, nil)
+// if the key is not set and (0, true, err) if the value is not a valid duration.
+func (c *Config) GetDuration(key string) (time.Duration, bool, error) {
+	v, found := c.Get(key)
+	if !found {
+		return 0, false, nil
+	}
+
+	d, err := coerce(c, key, typeDuration, v, parseDuration)
+	if err != nil {
+		return 0, true, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return d, true, nil
+}
+
+// GetDuration returns the value for the given key interpreted as a duration.
+// See Config.GetDuration for the accepted syntax.
+//
+// Like Get, the value is taken from the first scope that contains the key.
+// If that value is invalid an error is returned; lower priority scopes are
+// not consulted.
+//
+// Example:
+//
+//	timeout, found, err := cfg.GetDuration("core.timeout")
+//	if err != nil { ... }
+//	if !found {
+//		timeout = 10 * time.Second
+//	}
+func (cs *Configs) GetDuration(key string) (time.Duration, bool, error) {
+	cfg, v, found := cs.lookupConfig(key)
+	if !found {
+		return 0, false, nil
+	}
+
+	d, err := coerce(cfg, key, typeDuration, v, parseDuration)
+	if err != nil {
+		return 0, true, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return d, true, nil
+}
+
+// GetExpiry returns the value of the key interpreted as an expiry date,
+// like git config --type=expiry-date does for keys like gc.pruneExpire.
+//
+// Accepted are relative dates ("2.weeks.ago", "3 days ago"), absolute dates
+// in ISO 8601 or RFC 2822 format, unix timestamps prefixed with "@" and the
+// special values "never"/"false" (the zero time.Time, i.e. never expire) and
+// "now"/"all" (the current time, i.e. everything is expired).
+//
+// Returns (value, true, nil) if the key is found and valid, (zero, false, nil)
+// if the key is not set and (zero, true, err) if the value is not a valid date.
+//
+// Example:
+//
+//	expire, found, err := cfg.GetExpiry("gc.pruneExpire")
+func (c *Config) GetExpiry(key string) (time.Time, bool, error) {
+	v, found := c.Get(key)
+	if !found {
+		return time.Time{}, false, nil
+	}
+
+	t, err := parseExpiryTime(v)
+	if err != nil {
+		return time.Time{}, true, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return t, true, nil
+}
+
+// GetExpiry returns the value for the given key from the first scope that
+// contains it, interpreted as an expiry date. See Config.GetExpiry for the
+// accepted syntax.
+func (cs *Configs) GetExpiry(key string) (time.Time, bool, error) {
+	v, found := cs.lookup(key)
+	if !found {
+		return time.Time{}, false, nil
+	}
+
+	t, err := parseExpiryTime(v)
+	if err != nil {
+		return time.Time{}, true, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return t, true, nil
+}
+
+// GetURL returns the value of the key interpreted as a URL, e.g. for
+// remote.<name>.url or http.proxy. Besides URLs with a scheme it accepts
+// the forms git accepts for remotes:
+//
+//   - scp-like "[user@]host:path", returned with the "ssh" scheme; the path
+//     is kept as written, i.e. relative to the home directory on the host
+//   - "host:port" without a path, returned with the "http" scheme like curl
+//     does for proxies
+//   - absolute local paths, returned with the "file" scheme
+//
+// Returns (url, true, nil) if the key is found and valid, (nil, false, nil)
+// if the key is not set and (nil, true, err) if the value is not a valid
+// URL, e.g. a URL without a host or a relative path. The error wraps
+// ErrInvalidValue.
+//
+// Example:
+//
+//	u, found, err := cfg.GetURL("remote.origin.url")
+//	if err == nil && found {
+//		fmt.Println(u.Hostname())
+//	}
+func (c *Config) GetURL(key string) (*url.URL, bool, error) {
+	v, found := c.Get(key)
+	if !found {
+		return nil, false, nil
+	}
+
+	u, err := parseURL(v)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return u, true, nil
+}
+
+// GetURL returns the value for the given key from the first scope that
+// contains it, interpreted as a URL. See Config.GetURL for the accepted
+// forms.
+func (cs *Configs) GetURL(key string) (*url.URL, bool, error) {
+	v, found := cs.lookup(key)
+	if !found {
+		return nil, false, nil
+	}
+
+	u, err := parseURL(v)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return u, true, nil
+}
+
+// parseURL parses a URL, an scp-like address, a host:port pair or an
+// absolute local path. See Config.GetURL.
+func parseURL(value string) (*url.URL, error) {
+	s := strings.TrimSpace(value)
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty URL", ErrInvalidValue)
+	}
+
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid URL %q: %w", ErrInvalidValue, value, err)
+		}
+		if u.Host == "" && u.Scheme != "file" {
+			return nil, fmt.Errorf("%w: URL %q has no host", ErrInvalidValue, value)
+		}
+
+		return u, nil
+	}
+
+	if hasDriveLetter(s) {
+		// file:///C:/path
+		return &url.URL{Scheme: "file", Path: "/" + strings.ReplaceAll(s, `\`, "/")}, nil
+	}
+	if strings.HasPrefix(s, "/") || filepath.IsAbs(s) {
+		return &url.URL{Scheme: "file", Path: filepath.ToSlash(s)}, nil
+	}
+
+	return parseSCPURL(s, value)
+}
+
+// parseSCPURL parses "[user@]host:path" like git does for remotes without
+// a scheme: there must be a colon before the first slash. The host may be
+// enclosed in brackets, e.g. "[::1]:repo.git" or "[host:22]:repo.git". A path of only digits makes
+// it a "host:port" pair instead.
+func parseSCPURL(s, value string) (*url.URL, error) {
+	var userinfo *url.Userinfo
+	rest := s
+	if at := strings.LastIndex(s[:max(strings.IndexAny(s, ":/"), 0)], "@"); at >= 0 {
+		userinfo = url.User(s[:at])
+		rest = s[at+1:]
+	}
+
+	var host, path string
+	var found bool
+	if strings.HasPrefix(rest, "[") {
+		var bracketed string
+		bracketed, path, found = strings.Cut(rest[1:], "]:")
+		host = bracketed
+		if ip := net.ParseIP(bracketed); ip != nil && ip.To4() == nil {
+			host = "[" + bracketed + "]"
+		}
+	} else {
+		host, path, found = strings.Cut(rest, ":")
+	}
+	if !found || host == "" || strings.Contains(host, "/") {
+		return nil, fmt.Errorf("%w: %q is neither a URL nor an absolute path", ErrInvalidValue, value)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("%w: %q has no path", ErrInvalidValue, value)
+	}
+
+	if _, err := strconv.ParseUint(path, 10, 16); err == nil && userinfo == nil {
+		return &url.URL{Scheme: "http", Host: host + ":" + path}, nil
+	}
+
+	return &url.URL{Scheme: "ssh", User: userinfo, Host: host, Path: path}, nil
+}
+
+// hasDriveLetter reports whether p starts with a Windows drive letter like
+// "C:". Git treats these as local paths, not scp-like addresses.
+func hasDriveLetter(p string) bool {
+	return len(p) >= 2 && p[1] == ':' && (p[0] >= 'a' && p[0] <= 'z' || p[0] >= 'A' && p[0] <= 'Z') &&
+		(len(p) == 2 || p[2] == '/' || p[2] == '\\')
+}
+
+// parseExpiryTime converts an expiry date into a time.Time. See parseExpiry.
+func parseExpiryTime(value string) (time.Time, error) {
+	ts, err := parseExpiry(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch ts {
+	case 0:
+		return time.Time{}, nil
+	case math.MaxInt64:
+		return timeNow(), nil
+	default:
+		return time.Unix(ts, 0), nil
+	}
+}
+
+// parseDuration parses a Go duration or a plain number of seconds.
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.TrimSpace(value)
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n > math.MaxInt64/int64(time.Second) || n < math.MinInt64/int64(time.Second) {
+			return 0, fmt.Errorf("%w: duration %q out of range", ErrInvalidValue, value)
+		}
+
+		return time.Duration(n) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid duration %q", ErrInvalidValue, value)
+	}
+
+	return d, nil
+}
+
+// expandPath expands a leading tilde like git config --type=path does.
+func expandPath(p string) (string, error) {
+	if !strings.HasPrefix(p, "~") {
+		return p, nil
+	}
+
+	name, rest, _ := strings.Cut(p[1:], "/")
+	if name == "" {
+		home := homeDir()
+		if home == "" {
+			return "", fmt.Errorf("%w: can not determine home directory to expand %q", ErrInvalidValue, p)
+		}
+
+		return filepath.Join(home, filepath.FromSlash(rest)), nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: can not expand %q: %w", ErrInvalidValue, p, err)
+	}
+
+	return filepath.Join(u.HomeDir, filepath.FromSlash(rest)), nil
+}
+
+// homeDir returns the home directory a leading "~" expands to. Tests can
+// replace it.
+var homeDir = userHome
+
+// userHome returns the home directory of the current user, see
+// os.UserHomeDir. It falls back to the user database if the environment
+// doesn't provide one, e.g. without $HOME in a minimal container.
+func userHome() string {
+	if home := appHome(); home != "" {
+		return home
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		debug.V(1).Log("failed to lookup current user: %s", err)
+
+		return ""
+	}
+
+	return u.HomeDir
+}
+
+// GetStringDefault returns the value for the given key from the first scope
+// that contains it, including the Preset scope. If the key is not set in
+// any scope def is returned.
+//
+// Example:
+//
+//	editor := cfg.GetStringDefault("core.editor", "vi")
+func (cs *Configs) GetStringDefault(key, def string) string {
+	if v, found := cs.lookup(key); found {
+		return v
+	}
+
+	return def
+}
+
+// GetBoolDefault is like GetBool but returns def if the key is not set
+// in any scope, including the Preset scope, or if the value is not a
+// valid boolean.
+func (cs *Configs) GetBoolDefault(key string, def bool) bool {
+	if b, ok := cs.GetBool(key); ok {
+		return b
+	}
+
+	return def
+}
+
+// GetIntDefault is like GetInt but returns def if the key is not set
+// in any scope, including the Preset scope, or if the value is not a
+// valid integer.
+func (cs *Configs) GetIntDefault(key string, def int64) int64 {
+	n, found, err := cs.GetInt(key)
+	if err != nil {
+		debug.V(1).Log("[%s] using default for %s: %s", cs.Name, key, err)
+
+		return def
+	}
+	if !found {
+		return def
+	}
+
+	return n
+}
+
+// parseBool parses a boolean value like git config --type=bool does.
+// An explicitly empty value is emptyIsTrue, see CompatOptions.EmptyValueIsTrue.
+func parseBool(value string, emptyIsTrue bool) (bool, error) {
+	if strings.TrimSpace(value) == "" {
+		return emptyIsTrue, nil
+	}
+
+	if b, ok := parseBoolText(value); ok {
+		return b, nil
+	}
+
+	if n, err := parseInt(value); err == nil {
+		return n != 0, nil
+	}
+
+	return false, fmt.Errorf("%w: invalid boolean %q", ErrInvalidValue, value)
+}
+
+// parseBoolText parses the textual boolean representations git accepts,
+// i.e. true/yes/on/1 and false/no/off/0. Matching is case-insensitive.
+// The second return value is false if the value is not a boolean word.
+func parseBoolText(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "on", "1":
+		return true, true
+	case "false", "no", "off", "0":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// parseInt parses an integer value like git config --type=int does.
+// An optional unit suffix of k, m or g (case-insensitive) scales the
+// value by 1024, 1024^2 or 1024^3.
+func parseInt(value string) (int64, error) {
+	s := strings.TrimSpace(value)
+	if s == "" {
+		return 0, fmt.Errorf("%w: empty integer", ErrInvalidValue)
+	}
+
+	factor := int64(1)
+	switch s[len(s)-1] {
+	case 'k', 'K':
+		factor = 1 << 10
+	case 'm', 'M':
+		factor = 1 << 20
+	case 'g', 'G':
+		factor = 1 << 30
+	}
+	if factor > 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid integer %q", ErrInvalidValue, value)
+	}
+
+	if n > math.MaxInt64/factor || n < math.MinInt64/factor {
+		return 0, fmt.Errorf("%w: integer %q out of range", ErrInvalidValue, value)
+	}
+
+	return n * factor, nil
+}
@@ -0,0 +1,408 @@
+package gitconfig
+
+import (
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBoolText(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]bool{
+		"true": true, "Yes": true, "ON": true, "1": true,
+		"false": false, "no": false, "Off": false, "0": false,
+	} {
+		v, ok := parseBoolText(in)
+		assert.True(t, ok, in)
+		assert.Equal(t, want, v, in)
+	}
+
+	for _, in := range []string{"", "2", "maybe", "truee"} {
+		_, ok := parseBoolText(in)
+		assert.False(t, ok, in)
+	}
+}
+
+func TestParseInt(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]int64{
+		"0":    0,
+		"42":   42,
+		" -7 ": -7,
+		"10k":  10 * 1024,
+		"5M":   5 * 1024 * 1024,
+		"1g":   1024 * 1024 * 1024,
+		"0x10": 16,
+	} {
+		v, err := parseInt(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, v, in)
+	}
+
+	for _, in := range []string{"", "k", "1.5", "12x", "abc", "9223372036854775807k"} {
+		_, err := parseInt(in)
+		require.ErrorIs(t, err, ErrInvalidValue, in)
+	}
+}
+
+func TestGetBool(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader(`[core]
+	yes = yes
+	on = On
+	one = 1
+	two = 2
+	no = no
+	off = off
+	zero = 0
+	empty =
+	bare
+	invalid = maybe
+`))
+
+	for key, want := range map[string]bool{
+		"core.yes":   true,
+		"core.on":    true,
+		"core.one":   true,
+		"core.two":   true,
+		"core.no":    false,
+		"core.off":   false,
+		"core.zero":  false,
+		"core.empty": true,
+		"core.bare":  true,
+	} {
+		v, ok := c.GetBool(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, want, v, key)
+	}
+
+	for _, key := range []string{"core.invalid", "core.missing"} {
+		v, ok := c.GetBool(key)
+		assert.False(t, ok, key)
+		assert.False(t, v, key)
+	}
+}
+
+func TestConfigsGetBool(t *testing.T) {
+	t.Parallel()
+
+	cs := New()
+	cs.local = ParseConfig(strings.NewReader("[core]\n\tflag = off\n\tbroken = nope\n"))
+	cs.global = ParseConfig(strings.NewReader("[core]\n\tflag = on\n\tother = yes\n\tbroken = yes\n"))
+
+	v, ok := cs.GetBool("core.flag")
+	assert.True(t, ok)
+	assert.False(t, v)
+
+	v, ok = cs.GetBool("core.other")
+	assert.True(t, ok)
+	assert.True(t, v)
+
+	// the highest priority value is invalid, lower scopes are not consulted
+	_, ok = cs.GetBool("core.broken")
+	assert.False(t, ok)
+
+	_, ok = cs.GetBool("core.missing")
+	assert.False(t, ok)
+}
+
+func TestGetInt(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader(`[core]
+	plain = 42
+	kilo = 10k
+	mega = 5M
+	giga = 1g
+	broken = 12x
+`))
+
+	for key, want := range map[string]int64{
+		"core.plain": 42,
+		"core.kilo":  10 << 10,
+		"core.mega":  5 << 20,
+		"core.giga":  1 << 30,
+	} {
+		v, found, err := c.GetInt(key)
+		require.NoError(t, err, key)
+		assert.True(t, found, key)
+		assert.Equal(t, want, v, key)
+	}
+
+	_, found, err := c.GetInt("core.broken")
+	assert.True(t, found)
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	_, found, err = c.GetInt("core.missing")
+	assert.False(t, found)
+	require.NoError(t, err)
+
+	cs := New()
+	cs.local = ParseConfig(strings.NewReader("[core]\n\tsize = 2k\n"))
+	cs.global = ParseConfig(strings.NewReader("[core]\n\tsize = 1\n\tother = x\n"))
+
+	v, found, err := cs.GetInt("core.size")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(2048), v)
+
+	_, found, err = cs.GetInt("core.other")
+	assert.True(t, found)
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	_, found, err = cs.GetInt("core.missing")
+	assert.False(t, found)
+	require.NoError(t, err)
+}
+
+func TestGetPath(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", td)
+
+	c := ParseConfig(strings.NewReader(`[core]
+	hooksPath = ~/hooks
+	home = ~
+	relative = some/dir
+	absolute = /etc/hooks
+	unknown = ~nosuchuser12345/hooks
+`))
+
+	for key, want := range map[string]string{
+		"core.hookspath": filepath.Join(td, "hooks"),
+		"core.home":      td,
+		"core.relative":  "some/dir",
+		"core.absolute":  "/etc/hooks",
+	} {
+		v, found, err := c.GetPath(key)
+		require.NoError(t, err, key)
+		assert.True(t, found, key)
+		assert.Equal(t, want, v, key)
+	}
+
+	_, found, err := c.GetPath("core.unknown")
+	assert.True(t, found)
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	_, found, err = c.GetPath("core.missing")
+	assert.False(t, found)
+	require.NoError(t, err)
+
+	cs := New()
+	cs.global = c
+
+	v, found, err := cs.GetPath("core.hooksPath")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, filepath.Join(td, "hooks"), v)
+}
+
+func TestExpandPathOtherUser(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on windows")
+	}
+
+	u, err := user.Current()
+	if err != nil || u.Username == "" {
+		t.Skip("can not determine current user")
+	}
+
+	p, err := expandPath("~" + u.Username + "/foo")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(u.HomeDir, "foo"), p)
+}
+
+func TestGetURL(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader(`[remote "origin"]
+	url = https://github.com/gopasspw/gitconfig.git
+[remote "scp"]
+	url = git@github.com:gopasspw/gitconfig.git
+[remote "ssh"]
+	url = ssh://git@example.com:2222/srv/repo.git
+[remote "nouser"]
+	url = example.com:repo.git
+[remote "ipv6"]
+	url = [::1]:repo.git
+[remote "port"]
+	url = [example.com:22]:repo.git
+[remote "local"]
+	url = /srv/git/repo.git
+[remote "file"]
+	url = file:///srv/git/repo.git
+[http]
+	proxy = proxy.example.com:3128
+[broken]
+	relative = ../repo.git
+	nohost = https:///path
+	nopath = git@example.com:
+	invalid = http://exa mple.com/
+	empty =
+`))
+
+	for key, want := range map[string]string{
+		"remote.origin.url": "https://github.com/gopasspw/gitconfig.git",
+		"remote.scp.url":    "ssh://git@github.com/gopasspw/gitconfig.git",
+		"remote.ssh.url":    "ssh://git@example.com:2222/srv/repo.git",
+		"remote.nouser.url": "ssh://example.com/repo.git",
+		"remote.ipv6.url":   "ssh://[::1]/repo.git",
+		"remote.port.url":   "ssh://example.com:22/repo.git",
+		"remote.local.url":  "file:///srv/git/repo.git",
+		"remote.file.url":   "file:///srv/git/repo.git",
+		"http.proxy":        "http://proxy.example.com:3128",
+	} {
+		u, found, err := c.GetURL(key)
+		require.NoError(t, err, key)
+		assert.True(t, found, key)
+		assert.Equal(t, want, u.String(), key)
+	}
+
+	// the path of scp-like addresses is kept as written
+	u, _, err := c.GetURL("remote.scp.url")
+	require.NoError(t, err)
+	assert.Equal(t, "git", u.User.Username())
+	assert.Equal(t, "github.com", u.Host)
+	assert.Equal(t, "gopasspw/gitconfig.git", u.Path)
+
+	for _, key := range []string{"broken.relative", "broken.nohost", "broken.nopath", "broken.invalid", "broken.empty"} {
+		_, found, err := c.GetURL(key)
+		assert.True(t, found, key)
+		require.ErrorIs(t, err, ErrInvalidValue, key)
+	}
+
+	_, found, err := c.GetURL("remote.missing.url")
+	assert.False(t, found)
+	require.NoError(t, err)
+
+	u, err = parseURL(`C:\repos\repo.git`)
+	require.NoError(t, err)
+	assert.Equal(t, "file", u.Scheme)
+	assert.Equal(t, "file:///C:/repos/repo.git", u.String())
+
+	cs := New()
+	cs.global = c
+
+	u, found, err = cs.GetURL("remote.origin.url")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "github.com", u.Host)
+}
+
+func TestGetDuration(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader(`[core]
+	timeout = 30s
+	long = 1h30m
+	seconds = 15
+	broken = soon
+`))
+
+	for key, want := range map[string]time.Duration{
+		"core.timeout": 30 * time.Second,
+		"core.long":    90 * time.Minute,
+		"core.seconds": 15 * time.Second,
+	} {
+		v, found, err := c.GetDuration(key)
+		require.NoError(t, err, key)
+		assert.True(t, found, key)
+		assert.Equal(t, want, v, key)
+	}
+
+	_, found, err := c.GetDuration("core.broken")
+	assert.True(t, found)
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	_, err = parseDuration("99999999999999999")
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	cs := New()
+	cs.local = ParseConfig(strings.NewReader("[core]\n\ttimeout = 10m\n"))
+	cs.global = c
+
+	v, found, err := cs.GetDuration("core.timeout")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 10*time.Minute, v)
+
+	_, found, err = cs.GetDuration("core.missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestConfigsGetDefault(t *testing.T) {
+	t.Parallel()
+
+	cs := New()
+	cs.local = ParseConfig(strings.NewReader("[core]\n\tflag = off\n\tsize = 2k\n\tbroken = x\n"))
+	cs.Preset = NewFromMap(map[string]string{
+		"core.editor": "nano",
+		"core.preset": "true",
+		"core.limit":  "10",
+	})
+
+	assert.Equal(t, "nano", cs.GetStringDefault("core.editor", "vi"))
+	assert.Equal(t, "vi", cs.GetStringDefault("core.missing", "vi"))
+
+	assert.False(t, cs.GetBoolDefault("core.flag", true))
+	assert.True(t, cs.GetBoolDefault("core.preset", false))
+	assert.True(t, cs.GetBoolDefault("core.missing", true))
+	assert.True(t, cs.GetBoolDefault("core.broken", true))
+
+	assert.Equal(t, int64(2048), cs.GetIntDefault("core.size", 1))
+	assert.Equal(t, int64(10), cs.GetIntDefault("core.limit", 1))
+	assert.Equal(t, int64(1), cs.GetIntDefault("core.missing", 1))
+	assert.Equal(t, int64(1), cs.GetIntDefault("core.broken", 1))
+}
+
+func TestGetExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = time.Now })
+
+	c := ParseConfig(strings.NewReader(`[gc]
+	pruneexpire = 2.weeks.ago
+	reflogexpire = never
+	worktreepruneexpire = now
+	rerereresolved = Fri, 01 Mar 2024 10:00:00 +0000
+	logexpiry = someday
+`))
+
+	for key, want := range map[string]time.Time{
+		"gc.pruneexpire":         now.Add(-14 * 24 * time.Hour),
+		"gc.reflogexpire":        {},
+		"gc.worktreepruneexpire": now,
+		"gc.rerereresolved":      time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+	} {
+		got, found, err := c.GetExpiry(key)
+		require.NoError(t, err, key)
+		assert.True(t, found, key)
+		assert.True(t, want.Equal(got), "%s: want %s, got %s", key, want, got)
+	}
+
+	_, found, err := c.GetExpiry("gc.logexpiry")
+	assert.True(t, found)
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	_, found, err = c.GetExpiry("gc.missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	cs := New()
+	cs.local = c
+	got, found, err := cs.GetExpiry("gc.pruneexpire")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.True(t, now.Add(-14*24*time.Hour).Equal(got))
+}
@@ -0,0 +1,219 @@
+package gitconfig
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// tagName is the struct tag used by Unmarshal.
+const tagName = "gitconfig"
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// valueSource provides the values Unmarshal reads from.
+type valueSource interface {
+	lookup(key string) (string, bool)
+	getAll(key string) []string
+	// config returns the config the values of key are read from. Its
+	// CompatOptions decide how they are parsed.
+	config(key string) *Config
+}
+
+// configSource adapts a single Config to valueSource.
+type configSource struct{ c *Config }
+
+func (s configSource) lookup(key string) (string, bool) {
+	return s.c.Get(key)
+}
+
+func (s configSource) getAll(key string) []string {
+	vs, _ := s.c.GetAll(key)
+
+	return vs
+}
+
+func (s configSource) config(string) *Config {
+	return s.c
+}
+
+// configsSource adapts Configs to valueSource.
+type configsSource struct{ cs *Configs }
+
+func (s configsSource) lookup(key string) (string, bool) {
+	return s.cs.lookup(key)
+}
+
+func (s configsSource) getAll(key string) []string {
+	return s.cs.GetAll(key)
+}
+
+func (s configsSource) config(key string) *Config {
+	for _, cfg := range s.cs.scopes() {
+		if cfg != nil && cfg.IsSet(key) {
+			return cfg
+		}
+	}
+
+	return nil
+}
+
+// Unmarshal stores the config values in the struct pointed to by v. Fields
+// are mapped to keys with the `gitconfig` struct tag. Fields without a tag
+// are ignored, tag options like omitempty (see Config.ApplyStruct) are ignored
+// as well.
+//
+// Supported field types are strings, bools, signed and unsigned integers,
+// time.Duration and slices of these for multivars. Values are parsed like
+// the typed getters do, e.g. "yes" is a true bool and "1k" is 1024.
+// A nested struct maps a section (or section and subsection): its tag is used
+// as prefix for the tags of its fields. Fields for keys that are not set keep
+// their value, so defaults can be set before calling Unmarshal.
+//
+// The error wraps ErrInvalidValue if a value can not be parsed and
+// ErrUnsupportedType if v or one of the tagged fields can not be mapped.
+//
+// Example:
+//
+//	type Settings struct {
+//		Core struct {
+//			Editor string `gitconfig:"editor"`
+//			Bare   bool   `gitconfig:"bare"`
+//		} `gitconfig:"core"`
+//		Name    string   `gitconfig:"user.name"`
+//		Fetch   []string `gitconfig:"remote.origin.fetch"`
+//	}
+//
+//	var s Settings
+//	err := cfg.Unmarshal(&s)
+func (cs *Configs) Unmarshal(v any) error {
+	return unmarshal(configsSource{cs: cs}, v)
+}
+
+// Unmarshal stores the config values in the struct pointed to by v.
+// See Configs.Unmarshal for details.
+func (c *Config) Unmarshal(v any) error {
+	return unmarshal(configSource{c: c}, v)
+}
+
+func unmarshal(src valueSource, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: Unmarshal needs a non-nil pointer to a struct, got %T", ErrUnsupportedType, v)
+	}
+
+	return unmarshalStruct(src, rv.Elem(), "")
+}
+
+func unmarshalStruct(src valueSource, rv reflect.Value, prefix string) error {
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		tag, _ := parseTag(sf.Tag.Get(tagName))
+		if tag == "" || tag == "-" || !sf.IsExported() {
+			continue
+		}
+
+		key := joinKey(prefix, tag)
+		fv := rv.Field(i)
+
+		if sf.Type.Kind() == reflect.Struct {
+			if err := unmarshalStruct(src, fv, key); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if sf.Type.Kind() == reflect.Slice {
+			vs := src.getAll(key)
+			if vs == nil {
+				continue
+			}
+			out := reflect.MakeSlice(sf.Type, len(vs), len(vs))
+			for j, s := range vs {
+				if err := setValue(src.config(key), out.Index(j), key, s); err != nil {
+					return err
+				}
+			}
+			fv.Set(out)
+
+			continue
+		}
+
+		s, found := src.lookup(key)
+		if !found {
+			continue
+		}
+		if err := setValue(src.config(key), fv, key, s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseTag splits a struct tag into the key and whether the omitempty
+// option is set.
+func parseTag(tag string) (string, bool) {
+	key, opts, _ := strings.Cut(tag, ",")
+
+	return key, slices.Contains(strings.Split(opts, ","), "omitempty")
+}
+
+// joinKey joins a section prefix and a (partial) key.
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
+
+// setValue parses s, a value of c, according to the type of fv and stores it.
+func setValue(c *Config, fv reflect.Value, key, s string) error {
+	if fv.Type() == durationType {
+		d, err := parseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		fv.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch fv.Kind() { //nolint:exhaustive
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := c.parseBool(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := parseInt(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if fv.OverflowInt(n) {
+			return fmt.Errorf("%s: %w: %d overflows %s", key, ErrInvalidValue, n, fv.Type())
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := parseInt(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if n < 0 || fv.OverflowUint(uint64(n)) {
+			return fmt.Errorf("%s: %w: %d overflows %s", key, ErrInvalidValue, n, fv.Type())
+		}
+		fv.SetUint(uint64(n))
+	default:
+		return fmt.Errorf("%s: %w: %s", key, ErrUnsupportedType, fv.Type())
+	}
+
+	return nil
+}
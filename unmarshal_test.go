@@ -0,0 +1,102 @@
+package gitconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type unmarshalTestSettings struct {
+	Core struct {
+		Editor    string `gitconfig:"editor"`
+		Bare      bool   `gitconfig:"bare"`
+		Threshold int64  `gitconfig:"bigFileThreshold"`
+	} `gitconfig:"core"`
+	Origin struct {
+		URL   string   `gitconfig:"url"`
+		Fetch []string `gitconfig:"fetch"`
+	} `gitconfig:"remote.origin"`
+	Name     string        `gitconfig:"user.name"`
+	Timeout  time.Duration `gitconfig:"gopass.timeout"`
+	Depth    uint8         `gitconfig:"gopass.depth"`
+	Missing  string        `gitconfig:"gopass.missing"`
+	Ignored  string
+	internal string `gitconfig:"core.editor"` //nolint:unused
+}
+
+func TestConfigsUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	cs := New()
+	cs.global = ParseConfig(strings.NewReader(`[core]
+	editor = vim
+	bare = yes
+	bigFileThreshold = 1k
+[user]
+	name = John Doe
+[gopass]
+	timeout = 30
+	depth = 3
+`))
+	cs.local = ParseConfig(strings.NewReader(`[core]
+	editor = nano
+[remote "origin"]
+	url = https://example.com/repo.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	fetch = +refs/tags/*:refs/tags/*
+`))
+
+	s := unmarshalTestSettings{Missing: "default"}
+	require.NoError(t, cs.Unmarshal(&s))
+
+	assert.Equal(t, "nano", s.Core.Editor)
+	assert.True(t, s.Core.Bare)
+	assert.Equal(t, int64(1024), s.Core.Threshold)
+	assert.Equal(t, "https://example.com/repo.git", s.Origin.URL)
+	assert.Equal(t, []string{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"}, s.Origin.Fetch)
+	assert.Equal(t, "John Doe", s.Name)
+	assert.Equal(t, 30*time.Second, s.Timeout)
+	assert.Equal(t, uint8(3), s.Depth)
+	assert.Equal(t, "default", s.Missing)
+	assert.Empty(t, s.Ignored)
+	assert.Empty(t, s.internal)
+}
+
+func TestConfigUnmarshalErrors(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader(`[core]
+	bare = maybe
+	depth = 300
+	size = -1
+	ratio = 0.5
+`))
+
+	var s unmarshalTestSettings
+	require.ErrorIs(t, c.Unmarshal(s), ErrUnsupportedType)
+	require.ErrorIs(t, c.Unmarshal(nil), ErrUnsupportedType)
+	require.ErrorIs(t, c.Unmarshal(&s), ErrInvalidValue)
+
+	var overflow struct {
+		Depth int8 `gitconfig:"core.depth"`
+	}
+	require.ErrorIs(t, c.Unmarshal(&overflow), ErrInvalidValue)
+
+	var negative struct {
+		Size uint `gitconfig:"core.size"`
+	}
+	require.ErrorIs(t, c.Unmarshal(&negative), ErrInvalidValue)
+
+	var unsupported struct {
+		Ratio float64 `gitconfig:"core.ratio"`
+	}
+	require.ErrorIs(t, c.Unmarshal(&unsupported), ErrUnsupportedType)
+
+	var bools struct {
+		Flags []bool `gitconfig:"core.bare"`
+	}
+	require.ErrorIs(t, c.Unmarshal(&bools), ErrInvalidValue)
+}
@@ -0,0 +1,214 @@
+package gitconfig
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// defaultPorts are the well known ports that are implied if a URL doesn't
+// specify one. They are used to compare URLs like git does.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+	"ftps":  "990",
+	"git":   "9418",
+	"ssh":   "22",
+}
+
+// normalizedURL is a URL split into the parts used for urlmatch comparisons.
+type normalizedURL struct {
+	scheme string
+	user   string
+	host   string
+	port   string
+	path   string
+}
+
+// parseMatchURL parses and normalizes a URL like git's url_normalize does:
+// scheme and host are lowercased, default ports are made explicit and the
+// path is cleaned and always starts with a slash.
+func parseMatchURL(raw string) (normalizedURL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return normalizedURL{}, fmt.Errorf("%w: invalid URL %q: %w", ErrInvalidValue, raw, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return normalizedURL{}, fmt.Errorf("%w: invalid URL %q: scheme and host are required", ErrInvalidValue, raw)
+	}
+
+	nu := normalizedURL{
+		scheme: strings.ToLower(u.Scheme),
+		host:   strings.ToLower(u.Hostname()),
+		port:   u.Port(),
+		path:   path.Clean("/" + u.EscapedPath()),
+	}
+	if u.User != nil {
+		nu.user = u.User.Username()
+	}
+	if nu.port == "" {
+		nu.port = defaultPorts[nu.scheme]
+	}
+
+	return nu, nil
+}
+
+// urlMatch describes how well a config URL matches a URL. Better matches have
+// a longer host, then a longer path and then a matching user name.
+type urlMatch struct {
+	host int
+	path int
+	user bool
+}
+
+// better returns true if m is a more specific match than o. Ties are
+// resolved in favor of o, i.e. the match that was found first.
+func (m urlMatch) better(o urlMatch) bool {
+	if m.host != o.host {
+		return m.host > o.host
+	}
+	if m.path != o.path {
+		return m.path > o.path
+	}
+
+	return m.user && !o.user
+}
+
+// matchURL checks if the config URL pattern matches the URL and returns how
+// specific the match is. Following git's rules the scheme and port must be
+// equal, the host must match with "*" matching a single host name component,
+// the pattern path must be a prefix of the URL path on a path component
+// boundary and a user name in the pattern must match the user of the URL.
+func matchURL(pattern, u normalizedURL) (urlMatch, bool) {
+	if pattern.scheme != u.scheme || pattern.port != u.port {
+		return urlMatch{}, false
+	}
+
+	pLabels := strings.Split(pattern.host, ".")
+	uLabels := strings.Split(u.host, ".")
+	if len(pLabels) != len(uLabels) {
+		return urlMatch{}, false
+	}
+	for i := range pLabels {
+		if pLabels[i] != "*" && pLabels[i] != uLabels[i] {
+			return urlMatch{}, false
+		}
+	}
+
+	if pattern.path != "/" && u.path != pattern.path && !strings.HasPrefix(u.path, pattern.path+"/") {
+		return urlMatch{}, false
+	}
+
+	if pattern.user != "" && pattern.user != u.user {
+		return urlMatch{}, false
+	}
+
+	m := urlMatch{
+		host: len(pattern.host),
+		user: pattern.user != "",
+	}
+	if pattern.path != "/" {
+		m.path = len(pattern.path)
+	}
+
+	return m, true
+}
+
+// urlMatchCandidate is the best value found so far for a single key.
+type urlMatchCandidate struct {
+	value string
+	match urlMatch
+	url   bool // the value is from a URL specific subsection
+}
+
+// GetURLMatch resolves the configuration for a URL like
+// git config --get-urlmatch does. The name is either a section (e.g. "http")
+// or a single key (e.g. "http.proxy").
+//
+// For every key in the section the value from the subsection whose URL
+// matches the given URL best is used, falling back to the value without a
+// subsection (e.g. "http.proxy"). Matching follows git's rules: scheme and
+// port must match exactly, "*" matches a single host name component and
+// the longest matching host, then path, then user name wins. The best match
+// wins regardless of the scope it is defined in, ties are resolved by scope
+// priority.
+//
+// The result maps keys without the URL (e.g. "http.sslverify") to values.
+// It is empty if nothing matches. The error wraps ErrInvalidValue if the URL
+// can not be parsed.
+//
+// Example:
+//
+//	vals, err := cfg.GetURLMatch("http", "https://user@example.com/repo.git")
+//	proxy := vals["http.proxy"]
+func (cs *Configs) GetURLMatch(name, rawURL string) (map[string]string, error) {
+	return urlMatchValues(name, rawURL, cs.namedScopes())
+}
+
+// GetURLMatch resolves the configuration for a URL like
+// git config --get-urlmatch does. See Configs.GetURLMatch for details.
+func (c *Config) GetURLMatch(name, rawURL string) (map[string]string, error) {
+	return urlMatchValues(name, rawURL, []namedScope{{name: "config", cfg: c}})
+}
+
+// urlMatchValues implements GetURLMatch for the given scopes in priority order.
+func urlMatchValues(name, rawURL string, scopes []namedScope) (map[string]string, error) {
+	u, err := parseMatchURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	section, wantKey, _ := strings.Cut(strings.ToLower(name), ".")
+	if section == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, name)
+	}
+
+	best := make(map[string]urlMatchCandidate, 8)
+	for _, sc := range scopes {
+		if sc.cfg == nil || sc.cfg.vars == nil {
+			continue
+		}
+
+		for _, k := range slices.Sorted(maps.Keys(sc.cfg.vars)) {
+			sec, subsec, key := splitKey(k)
+			if sec != section || (wantKey != "" && key != wantKey) {
+				continue
+			}
+			vs := sc.cfg.vars[k]
+			if len(vs) < 1 {
+				continue
+			}
+
+			cand := urlMatchCandidate{value: vs[0]}
+			if subsec != "" {
+				pattern, err := parseMatchURL(subsec)
+				if err != nil {
+					continue
+				}
+				m, ok := matchURL(pattern, u)
+				if !ok {
+					continue
+				}
+				cand.match = m
+				cand.url = true
+			}
+
+			fk := section + "." + key
+			cur, found := best[fk]
+			if !found || (cand.url && !cur.url) || (cand.url && cur.url && cand.match.better(cur.match)) {
+				best[fk] = cand
+			}
+		}
+	}
+
+	out := make(map[string]string, len(best))
+	for k, cand := range best {
+		out[k] = cand.value
+	}
+
+	return out, nil
+}
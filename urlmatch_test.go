@@ -0,0 +1,96 @@
+package gitconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchURL(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		pattern string
+		url     string
+		match   bool
+	}{
+		{"https://example.com", "https://example.com/repo.git", true},
+		{"https://example.com/", "https://EXAMPLE.com:443/repo.git", true},
+		{"https://example.com:8443", "https://example.com/repo.git", false},
+		{"http://example.com", "https://example.com/repo.git", false},
+		{"https://*.example.com", "https://git.example.com/repo.git", true},
+		{"https://*.example.com", "https://example.com/repo.git", false},
+		{"https://*.example.com", "https://a.b.example.com/repo.git", false},
+		{"https://example.com/org", "https://example.com/org/repo.git", true},
+		{"https://example.com/org", "https://example.com/org", true},
+		{"https://example.com/org", "https://example.com/organization/repo.git", false},
+		{"https://example.com/org/", "https://example.com/org/repo.git", true},
+		{"https://user@example.com", "https://user@example.com/repo.git", true},
+		{"https://user@example.com", "https://other@example.com/repo.git", false},
+		{"https://user@example.com", "https://example.com/repo.git", false},
+		{"https://example.com", "https://user@example.com/repo.git", true},
+	} {
+		p, err := parseMatchURL(tc.pattern)
+		require.NoError(t, err, tc.pattern)
+		u, err := parseMatchURL(tc.url)
+		require.NoError(t, err, tc.url)
+
+		_, ok := matchURL(p, u)
+		assert.Equal(t, tc.match, ok, "%s vs %s", tc.pattern, tc.url)
+	}
+}
+
+func TestGetURLMatch(t *testing.T) {
+	t.Parallel()
+
+	cs := New()
+	cs.global = ParseConfig(strings.NewReader(`[http]
+	proxy = http://default-proxy
+	sslVerify = true
+[http "https://example.com"]
+	proxy = http://example-proxy
+[http "https://*.example.com"]
+	cookieFile = /tmp/cookies
+[http "https://example.com/org"]
+	sslVerify = false
+[http "https://user@example.com"]
+	extraHeader = X-User: yes
+`))
+	cs.local = ParseConfig(strings.NewReader(`[http]
+	proxy = http://local-proxy
+[http "https://example.com/org/repo.git"]
+	postBuffer = 1024
+`))
+
+	vals, err := cs.GetURLMatch("http", "https://user@example.com/org/repo.git")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"http.proxy":       "http://example-proxy",
+		"http.sslverify":   "false",
+		"http.extraheader": "X-User: yes",
+		"http.postbuffer":  "1024",
+	}, vals)
+
+	vals, err = cs.GetURLMatch("http", "https://git.example.com/repo.git")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"http.proxy":      "http://local-proxy",
+		"http.sslverify":  "true",
+		"http.cookiefile": "/tmp/cookies",
+	}, vals)
+
+	vals, err = cs.GetURLMatch("http.sslVerify", "https://example.com/org/other.git")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"http.sslverify": "false"}, vals)
+
+	vals, err = cs.local.GetURLMatch("http", "https://example.com/")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"http.proxy": "http://local-proxy"}, vals)
+
+	_, err = cs.GetURLMatch("http", "not a url")
+	require.ErrorIs(t, err, ErrInvalidValue)
+	_, err = cs.GetURLMatch("", "https://example.com")
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
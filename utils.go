@@ -1,17 +1,16 @@
 package gitconfig
 
 import (
+	"slices"
 	"strings"
-
-	"github.com/gobwas/glob"
 )
 
 // globMatch matches a string against a glob pattern.
-// It uses the gobwas/glob package and supports:
+// The pattern follows git's wildmatch (see matchPattern) and supports:
 // - single-asterisk (*) patterns for matching within a path component
 // - double-asterisk (**) patterns for matching across path components
 // - question mark (?) for single character matching
-// - character classes [abc] and ranges [a-z]
+// - character classes [abc], ranges [a-z] and named classes [[:alpha:]]
 //
 // The pattern uses '/' as a path separator.
 //
@@ -25,7 +24,7 @@ import (
 // - (false, nil) if the string does not match.
 // - (false, error) if the pattern is invalid.
 func globMatch(pattern, s string) (bool, error) {
-	g, err := glob.Compile(pattern, '/')
+	g, err := compilePattern(pattern)
 	if err != nil {
 		return false, err
 	}
@@ -33,6 +32,13 @@ func globMatch(pattern, s string) (bool, error) {
 	return g.Match(s), nil
 }
 
+// sortedSet sorts s and removes duplicates. It modifies s.
+func sortedSet(s []string) []string {
+	slices.Sort(s)
+
+	return slices.Compact(s)
+}
+
 // splitKey splits a fully qualified gitconfig key into two or three parts.
 // A valid key consists of either a section and a key separated by a dot
 // or section, subsection and key, all separated by a dot. Note that
@@ -59,7 +65,8 @@ func splitKey(key string) (section, subsection, skey string) { //nolint:nonamedr
 	return
 }
 
-// canonicalizeKey normalizes a gitconfig key according to git rules.
+// canonicalizeKey normalizes a gitconfig key according to git rules, see
+// KeyRules for other rules.
 //
 // Canonicalization rules (per git-config):
 // - Section names are converted to lowercase
@@ -75,28 +82,7 @@ func splitKey(key string) (section, subsection, skey string) { //nolint:nonamedr
 //	canonicalizeKey("valid.key") returns "valid.key"
 //	canonicalizeKey("invalid") returns "" // missing key part
 func canonicalizeKey(key string) string {
-	if key == "" {
-		// invalid key, return empty string
-		return ""
-	}
-
-	section, subsection, skey := splitKey(key)
-	// "Section names are case-insensitive.""
-	section = strings.ToLower(section)
-	// "Subsection names are case sensitive."
-	// "The variable names are case-insensitive."
-	skey = strings.ToLower(skey)
-
-	if section == "" || skey == "" {
-		// invalid key, return empty string
-		return ""
-	}
-
-	if subsection == "" {
-		return section + "." + skey
-	}
-
-	return section + "." + subsection + "." + skey
+	return KeyRules{}.Canonical(key)
 }
 
 // trim removes leading and trailing whitespace from all strings in the slice.
@@ -109,6 +95,22 @@ func trim(s []string) {
 	}
 }
 
+// unquotedIndexAny returns the index of the first character of chars in s
+// that is not enclosed in double quotes, or -1.
+func unquotedIndexAny(s, chars string) int {
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && strings.IndexByte(chars, s[i]) >= 0:
+			return i
+		}
+	}
+
+	return -1
+}
+
 // parseLineForComment separates a line into content and comment parts.
 //
 // Parsing rules:
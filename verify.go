@@ -0,0 +1,123 @@
+package gitconfig
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+)
+
+// Divergence describes a key whose values differ between git and this package.
+//
+// Fields:
+// - Key: The canonical key
+// - Git: All values reported by git, from lowest to highest priority
+// - Ours: All values known to this package, in the same order
+// - Origins: Where git found the values (e.g. "file:/home/user/.gitconfig")
+type Divergence struct {
+	Key     string   `json:"key"`
+	Git     []string `json:"git"`
+	Ours    []string `json:"ours"`
+	Origins []string `json:"origins,omitempty"`
+}
+
+// VerifyAgainstGit compares the loaded configuration against the output of
+// `git config --list --show-origin` and returns all keys where the values
+// differ. It is meant as a developer aid and as a sanity check for embedders,
+// e.g. as part of a "doctor" command.
+//
+// workdir should be the same directory that was passed to LoadAll. It is
+// used as GIT_DIR for git, so the comparison only makes sense with the
+// default settings (see New), i.e. when reading git's own config files.
+// Presets are not considered since git doesn't know about them.
+//
+// Returns ErrGitNotFound if no git binary is available.
+func (cs *Configs) VerifyAgainstGit(workdir string) ([]Divergence, error) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrGitNotFound, err)
+	}
+
+	cmd := exec.Command(gitPath, "config", "--list", "--show-origin", "-z")
+	if workdir != "" {
+		cmd.Dir = workdir
+		cmd.Env = append(os.Environ(), "GIT_DIR="+workdir)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s: %w", cmd, err)
+	}
+
+	gitVals, origins := parseGitList(out)
+	ours := cs.allValues()
+
+	keys := slices.Sorted(maps.Keys(gitVals))
+	for k := range ours {
+		if _, found := gitVals[k]; !found {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	diffs := make([]Divergence, 0, 8)
+	for _, k := range keys {
+		if slices.Equal(gitVals[k], ours[k]) {
+			continue
+		}
+		diffs = append(diffs, Divergence{
+			Key:     k,
+			Git:     gitVals[k],
+			Ours:    ours[k],
+			Origins: origins[k],
+		})
+	}
+
+	debug.V(1).Log("[%s] found %d divergences from git", cs.Name, len(diffs))
+
+	return diffs, nil
+}
+
+// parseGitList parses the output of `git config --list --show-origin -z`.
+// Every entry consists of the origin and the key, optionally followed by a
+// newline and the value, each terminated by a NUL byte.
+func parseGitList(out []byte) (map[string][]string, map[string][]string) {
+	vals := make(map[string][]string, 64)
+	origins := make(map[string][]string, 64)
+
+	fields := bytes.Split(out, []byte{0})
+	for i := 0; i+1 < len(fields); i += 2 {
+		origin := string(fields[i])
+		key, value, _ := bytes.Cut(fields[i+1], []byte{'\n'})
+		k := canonicalizeKey(string(key))
+		if k == "" {
+			continue
+		}
+		vals[k] = append(vals[k], string(value))
+		origins[k] = append(origins[k], origin)
+	}
+
+	return vals, origins
+}
+
+// allValues returns all values of all keys in the order git would list
+// them, i.e. from the lowest to the highest priority scope.
+func (cs *Configs) allValues() map[string][]string {
+	out := make(map[string][]string, 64)
+
+	scopes := cs.namedScopes()
+	slices.Reverse(scopes)
+	for _, sc := range scopes {
+		// git knows neither the presets nor the overlay
+		if sc.cfg == nil || sc.name == ScopePreset || sc.name == ScopeOverlay {
+			continue
+		}
+		for k, vs := range sc.cfg.vars {
+			out[k] = append(out[k], vs...)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,65 @@
+package gitconfig
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyAgainstGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	td := t.TempDir()
+	home := filepath.Join(td, "home")
+	gitDir := filepath.Join(td, "repo", ".git")
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".config", "git"), 0o700))
+
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("GOPASS_HOMEDIR", home)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_COUNT", "")
+
+	require.NoError(t, exec.Command("git", "init", "-q", filepath.Dir(gitDir)).Run())
+
+	// git reads both the XDG config and ~/.gitconfig, we only use the first one found
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".config", "git", "config"), []byte("[user]\n\tname = XDG\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".gitconfig"), []byte("[user]\n\temail = home@example.com\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "config"), []byte(`[core]
+	bare = false
+[remote "origin"]
+	url = https://example.com/repo.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+`), 0o600))
+
+	c := New()
+	c.LoadAll(gitDir)
+	c.Preset = NewFromMap(map[string]string{"preset.only": "ignored"})
+
+	diffs, err := c.VerifyAgainstGit(gitDir)
+	require.NoError(t, err)
+	require.Len(t, diffs, 1)
+	assert.Equal(t, "user.email", diffs[0].Key)
+	assert.Equal(t, []string{"home@example.com"}, diffs[0].Git)
+	assert.Empty(t, diffs[0].Ours)
+	require.Len(t, diffs[0].Origins, 1)
+	assert.Contains(t, diffs[0].Origins[0], ".gitconfig")
+}
+
+func TestParseGitList(t *testing.T) {
+	t.Parallel()
+
+	vals, origins := parseGitList([]byte("file:a\x00core.bare\nfalse\x00file:a\x00Remote.Origin.URL\nx\x00command line:\x00core.flag\x00"))
+	assert.Equal(t, map[string][]string{
+		"core.bare":         {"false"},
+		"remote.Origin.url": {"x"},
+		"core.flag":         {""},
+	}, vals)
+	assert.Equal(t, []string{"command line:"}, origins["core.flag"])
+}
@@ -0,0 +1,42 @@
+package gitconfig
+
+// Warning describes a problem found while loading a config, e.g. a line
+// that was ignored by the lenient parser.
+//
+// Fields:
+// - Path: The file the problem was found in, empty if parsed from a reader
+// - Line: The line number, starting at 1, or 0 if not related to a line
+// - Reason: What is wrong
+type Warning struct {
+	Path   string
+	Line   int
+	Reason string
+}
+
+// String implements fmt.Stringer.
+func (w Warning) String() string {
+	return parseIssue{path: w.Path, line: w.Line, msg: w.Reason}.String()
+}
+
+// Warnings returns the problems found while loading the config and its
+// includes, in the order they were found. ParseConfig and LoadConfig never
+// fail, so this is the place to find out about lines that were ignored.
+//
+// Example:
+//
+//	c, _ := gitconfig.LoadConfig("~/.gitconfig")
+//	for _, w := range c.Warnings() {
+//		fmt.Fprintln(os.Stderr, "warning:", w)
+//	}
+func (c *Config) Warnings() []Warning {
+	if c == nil || len(c.issues) == 0 {
+		return nil
+	}
+
+	ws := make([]Warning, 0, len(c.issues))
+	for _, pi := range c.issues {
+		ws = append(ws, Warning{Path: pi.path, Line: pi.line, Reason: pi.msg})
+	}
+
+	return ws
+}
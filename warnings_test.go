@@ -0,0 +1,39 @@
+package gitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarnings(t *testing.T) {
+	t.Parallel()
+
+	c := ParseConfig(strings.NewReader("[core]\n\t1nvalid = x\n\tok = 1\n[]\n"))
+	assert.Equal(t, []Warning{
+		{Line: 2, Reason: `invalid key "1nvalid"`},
+		{Line: 4, Reason: "empty section header"},
+	}, c.Warnings())
+	assert.Equal(t, "line 4: empty section header", c.Warnings()[1].String())
+
+	assert.Nil(t, ParseConfig(strings.NewReader("[core]\n\tok = 1\n")).Warnings())
+	assert.Nil(t, (*Config)(nil).Warnings())
+}
+
+func TestWarningsFromIncludes(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	fn := filepath.Join(td, "config")
+	inc := filepath.Join(td, "inc")
+	require.NoError(t, os.WriteFile(fn, []byte("[include]\n\tpath = inc\n"), 0o600))
+	require.NoError(t, os.WriteFile(inc, []byte("[core]\n\tmsg = \"open\n"), 0o600))
+
+	c, err := LoadConfig(fn)
+	require.NoError(t, err)
+	assert.Equal(t, []Warning{{Path: inc, Line: 2, Reason: "unterminated quoted value"}}, c.Warnings())
+}